@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/api/handlers"
 	"github.com/yourusername/auth-api/internal/api/middlewares"
 	"github.com/yourusername/auth-api/internal/config"
+	"github.com/yourusername/auth-api/internal/extauthz"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/policy"
+	"github.com/yourusername/auth-api/internal/rebac"
+	"github.com/yourusername/auth-api/internal/routeauth"
 	"github.com/yourusername/auth-api/internal/service"
 	"github.com/yourusername/auth-api/pkg/database"
 	"github.com/yourusername/auth-api/pkg/jwt"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -20,6 +34,10 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// ยกเลิกเมื่อได้รับ SIGINT/SIGTERM เพื่อปิดเซิร์ฟเวอร์และงานเบื้องหลังอย่างเรียบร้อย
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// เชื่อมต่อฐานข้อมูล
 	dbConfig := &database.Config{
 		Host:     cfg.Database.Host,
@@ -45,64 +63,320 @@ func main() {
 		log.Printf("Warning: Failed to seed initial data: %v", err)
 	}
 
+	// apply ไฟล์ policy (ถ้ากำหนดไว้) ให้สิทธิ์และบทบาทตรงกับไฟล์ทุกครั้งที่เริ่มระบบ
+	if cfg.Policy.File != "" {
+		data, err := os.ReadFile(cfg.Policy.File)
+		if err != nil {
+			log.Fatalf("Failed to read policy file: %v", err)
+		}
+		desired, err := policy.Parse(data)
+		if err != nil {
+			log.Fatalf("Failed to parse policy file: %v", err)
+		}
+		plan, err := policy.Apply(db, desired, policy.ApplyOptions{})
+		if err != nil {
+			log.Fatalf("Failed to apply policy file: %v", err)
+		}
+		log.Printf("Applied policy file %s (%d changes)", cfg.Policy.File, len(plan.Changes))
+	}
+
 	// สร้าง JWT service
 	jwtService := jwt.NewJWTService(
 		cfg.JWT.SecretKey,
 		cfg.JWT.Issuer,
 		cfg.JWT.TokenDuration,
 	)
+	if cfg.JWT.PrivateKeyFile != "" {
+		pemData, err := os.ReadFile(cfg.JWT.PrivateKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to read JWT private key: %v", err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKey(pemData)
+		if err != nil {
+			log.Fatalf("Invalid JWT private key: %v", err)
+		}
+		jwtService.UseRSAKey(privateKey, cfg.JWT.KeyID)
+	}
+	jwtService.SetValidation(jwt.ValidationOptions{
+		StrictIssuer:     cfg.JWT.StrictIssuer,
+		RequireNotBefore: cfg.JWT.RequireNotBefore,
+		Leeway:           cfg.JWT.Leeway,
+	})
+
+	// audience ของ token: ค่าเริ่มต้นคือ API นี้เอง ส่วนแอปอื่นได้ audience ตาม client_id ที่ตั้งค่าไว้
+	var defaultAudiences []string
+	var authOptions []middlewares.AuthOption
+	if cfg.JWT.Audience != "" {
+		defaultAudiences = []string{cfg.JWT.Audience}
+		authOptions = append(authOptions, middlewares.AcceptAudiences(cfg.JWT.Audience))
+	}
+	clientAudiences := make(map[string][]string, len(cfg.JWT.Clients))
+	for _, client := range cfg.JWT.Clients {
+		clientAudiences[client.ID] = client.Audiences
+	}
 
 	// สร้าง services
 	authService := service.NewAuthService(db, jwtService)
+	authService.EmbedPermissions(cfg.JWT.EmbedPermissions)
+	authService.SetAudiences(defaultAudiences, clientAudiences)
+	assignmentService := service.NewAssignmentService(db)
+	accessRequestService := service.NewAccessRequestService(db, assignmentService, cfg.AccessRequests.MaxDuration)
+	accessReviewService := service.NewAccessReviewService(db, assignmentService)
+	serviceAccountService := service.NewServiceAccountService(db)
+	usageService := service.NewUsageService(db)
+	relationEngine, err := rebac.NewEngine(rebac.NewDBStore(db), cfg.ReBAC.Namespaces)
+	if err != nil {
+		log.Fatalf("Invalid relation namespace configuration: %v", err)
+	}
+
+	// แคชบทบาทและสิทธิ์ของผู้ใช้ (cacheTTL 0 = ปิดแคช) การกำหนด/ถอนบทบาททุกช่องทาง invalidate ผ่าน change hook
+	var permissionCache *service.PermissionCache
+	if cfg.Authz.CacheTTL > 0 {
+		permissionCache = service.NewPermissionCache(cfg.Authz.CacheTTL)
+		authService.UseCache(permissionCache)
+		assignmentService.OnChanged(permissionCache.InvalidateUser)
+
+		// หลาย instance: แจ้งการ invalidate ให้ instance อื่นและรับการแจ้งจาก instance อื่น
+		if cfg.Authz.CacheSync {
+			cacheSync, err := service.NewCacheSync(db, dbConfig.DSN(), permissionCache)
+			if err != nil {
+				log.Fatalf("Failed to create permission cache sync: %v", err)
+			}
+			permissionCache.OnInvalidate(cacheSync.Publish)
+			go cacheSync.StartListener(ctx, time.Second)
+		}
+	} else if cfg.JWT.EmbedPermissions {
+		log.Println("Warning: permission cache is disabled, token permission version checks will query the database on every request")
+	}
+
+	approverResource, approverAction, ok := models.ParsePermissionKey(cfg.AccessRequests.ApproverPermission)
+	if !ok {
+		log.Fatalf("Invalid access request approver permission: %q", cfg.AccessRequests.ApproverPermission)
+	}
+
+	forwardAuthRules, err := routeauth.NewTable(cfg.ForwardAuth.Rules, cfg.ForwardAuth.DefaultPolicy)
+	if err != nil {
+		log.Fatalf("Invalid forward-auth rules: %v", err)
+	}
+
+	// Envoy ext_authz gRPC server บน port แยก ใช้การตรวจ token และสิทธิ์ชุดเดียวกับ HTTP API
+	if cfg.ExtAuthz.Port != "" {
+		extAuthzRules, err := routeauth.NewTable(cfg.ExtAuthz.Rules, cfg.ExtAuthz.DefaultPolicy)
+		if err != nil {
+			log.Fatalf("Invalid ext_authz rules: %v", err)
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.ExtAuthz.Port))
+		if err != nil {
+			log.Fatalf("Failed to listen for ext_authz: %v", err)
+		}
+		grpcServer := grpc.NewServer()
+		extauthz.NewServer(jwtService, authService, extAuthzRules, defaultAudiences...).Register(grpcServer)
+		go func() {
+			log.Printf("ext_authz gRPC server starting on %s", listener.Addr())
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("Failed to start ext_authz server: %v", err)
+			}
+		}()
+	}
+
+	// ลบการกำหนดบทบาทที่หมดอายุเป็นระยะ
+	go assignmentService.StartExpirySweeper(ctx, cfg.RoleExpiry.SweepInterval)
+
+	// ปิดแคมเปญทบทวนสิทธิ์ที่เลยกำหนด (ถอนบทบาทที่ไม่มีผู้ทบทวนถ้าแคมเปญตั้งไว้)
+	go accessReviewService.StartDeadlineSweeper(ctx, cfg.AccessReviews.SweepInterval)
+
+	// flusher หยุดหลังเซิร์ฟเวอร์หยุดรับ request แล้วเท่านั้น เพื่อให้ Flush ครั้งสุดท้ายได้ยอดครบ
+	flusherCtx, stopFlusher := context.WithCancel(context.Background())
+	flusherDone := make(chan struct{})
+	go func() {
+		defer close(flusherDone)
+		usageService.StartFlusher(flusherCtx, cfg.Usage.FlushInterval, cfg.Usage.Retention)
+	}()
+
+	// สรุปผลการตัดสินสิทธิ์ของ RequirePermission และบันทึกเป็นระยะ
+	// แนบคำอธิบายในคำตอบ 403 เฉพาะเมื่อเปิดไว้และไม่ใช่ production
+	permissionOptions := []middlewares.PermissionOption{
+		middlewares.RecordUsage(usageService),
+		middlewares.ExplainDenials(cfg.Authz.ExplainDenials && cfg.Server.Environment != "production"),
+	}
+	requirePermission := func(resource string, action string) gin.HandlerFunc {
+		return middlewares.RequirePermission(authService, resource, action, permissionOptions...)
+	}
 
 	// สร้าง handlers
 	authHandler := handlers.NewAuthHandler(authService)
-	userHandler := handlers.NewUserHandler(db)
-	roleHandler := handlers.NewRoleHandler(db)
-	permissionHandler := handlers.NewPermissionHandler(db)
+	userHandler := handlers.NewUserHandler(db, assignmentService, permissionCache)
+	roleHandler := handlers.NewRoleHandler(db, permissionCache)
+	permissionHandler := handlers.NewPermissionHandler(db, permissionCache)
+	organizationHandler := handlers.NewOrganizationHandler(db)
+	groupHandler := handlers.NewGroupHandler(db, permissionCache)
+	accessRequestHandler := handlers.NewAccessRequestHandler(accessRequestService, authService, approverResource, approverAction)
+	sodRuleHandler := handlers.NewSoDRuleHandler(db)
+	authzHandler := handlers.NewAuthzHandler(db, authService, permissionCache)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(db, serviceAccountService)
+	relationHandler := handlers.NewRelationHandler(relationEngine)
+	policyHandler := handlers.NewPolicyHandler(db, permissionCache)
+	accessReviewHandler := handlers.NewAccessReviewHandler(db, accessReviewService, authService)
+	usageHandler := handlers.NewUsageHandler(usageService)
+	forwardAuthHandler := handlers.NewForwardAuthHandler(forwardAuthRules, authService)
 
 	// สร้าง middlewares
-	authMiddleware := middlewares.AuthMiddleware(jwtService, authService)
+	authMiddleware := middlewares.AuthMiddleware(jwtService, authService, authOptions...)
+	// request ที่ผ่าน proxy มาจาก browser จึงอ่าน token จาก session cookie ได้ด้วย
+	forwardAuthOptions := append([]middlewares.AuthOption{middlewares.TokenCookie(cfg.ForwardAuth.CookieName)}, authOptions...)
 
 	// สร้าง Gin router
 	r := gin.Default()
 
 	// API routes
 	r.POST("/api/login", authHandler.Login)
+	r.GET("/.well-known/jwks.json", authHandler.GetJWKS)
 
 	// กลุ่ม routes ที่ต้องการการยืนยันตัวตน
 	authorized := r.Group("/api")
 	authorized.Use(authMiddleware)
 
+	// Current user routes
+	authorized.GET("/me/permissions", authHandler.GetMyPermissions)
+	authorized.GET("/me/access-reviews", accessReviewHandler.GetMyReviewItems)
+
 	// User routes
-	authorized.GET("/users", middlewares.RequirePermission(authService, "users", "read"), userHandler.GetUsers)
-	authorized.GET("/users/:id", middlewares.RequirePermission(authService, "users", "read"), userHandler.GetUser)
-	authorized.POST("/users", middlewares.RequirePermission(authService, "users", "write"), userHandler.CreateUser)
-	authorized.PUT("/users/:id", middlewares.RequirePermission(authService, "users", "write"), userHandler.UpdateUser)
-	authorized.DELETE("/users/:id", middlewares.RequirePermission(authService, "users", "write"), userHandler.DeleteUser)
-	authorized.POST("/users/:id/roles", middlewares.RequirePermission(authService, "users", "write"), userHandler.AddRoleToUser)
-	authorized.DELETE("/users/:id/roles/:roleId", middlewares.RequirePermission(authService, "users", "write"), userHandler.RemoveRoleFromUser)
+	authorized.GET("/users", requirePermission("users", "read"), userHandler.GetUsers)
+	authorized.GET("/users/:id", requirePermission("users", "read"), userHandler.GetUser)
+	authorized.POST("/users", requirePermission("users", "write"), userHandler.CreateUser)
+	authorized.PUT("/users/:id", requirePermission("users", "write"), userHandler.UpdateUser)
+	authorized.DELETE("/users/:id", requirePermission("users", "write"), userHandler.DeleteUser)
+	authorized.GET("/users/:id/roles", requirePermission("users", "read"), userHandler.GetUserRoleAssignments)
+	authorized.POST("/users/:id/roles", requirePermission("users", "write"), userHandler.AddRoleToUser)
+	authorized.DELETE("/users/:id/roles/:roleId", requirePermission("users", "write"), userHandler.RemoveRoleFromUser)
 
 	// Role routes
-	authorized.GET("/roles", middlewares.RequirePermission(authService, "roles", "read"), roleHandler.GetRoles)
-	authorized.GET("/roles/:id", middlewares.RequirePermission(authService, "roles", "read"), roleHandler.GetRole)
-	authorized.POST("/roles", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.CreateRole)
-	authorized.PUT("/roles/:id", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.UpdateRole)
-	authorized.DELETE("/roles/:id", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.DeleteRole)
-	authorized.POST("/roles/:id/permissions", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.AddPermissionToRole)
-	authorized.DELETE("/roles/:id/permissions/:permissionId", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.RemovePermissionFromRole)
+	authorized.GET("/roles", requirePermission("roles", "read"), roleHandler.GetRoles)
+	authorized.GET("/roles/:id", requirePermission("roles", "read"), roleHandler.GetRole)
+	authorized.POST("/roles", requirePermission("roles", "write"), roleHandler.CreateRole)
+	authorized.PUT("/roles/:id", requirePermission("roles", "write"), roleHandler.UpdateRole)
+	authorized.DELETE("/roles/:id", requirePermission("roles", "write"), roleHandler.DeleteRole)
+	authorized.POST("/roles/:id/permissions", requirePermission("roles", "write"), roleHandler.AddPermissionToRole)
+	authorized.DELETE("/roles/:id/permissions/:permissionId", requirePermission("roles", "write"), roleHandler.RemovePermissionFromRole)
+	authorized.GET("/roles/:id/delegations", requirePermission("roles", "read"), roleHandler.GetRoleDelegations)
+	authorized.POST("/roles/:id/delegations", requirePermission("roles", "write"), roleHandler.AddRoleDelegation)
+	authorized.DELETE("/roles/:id/delegations/:grantorRoleId", requirePermission("roles", "write"), roleHandler.RemoveRoleDelegation)
 
 	// Permission routes
-	authorized.GET("/permissions", middlewares.RequirePermission(authService, "permissions", "read"), permissionHandler.GetPermissions)
-	authorized.GET("/permissions/:id", middlewares.RequirePermission(authService, "permissions", "read"), permissionHandler.GetPermission)
-	authorized.POST("/permissions", middlewares.RequirePermission(authService, "permissions", "write"), permissionHandler.CreatePermission)
-	authorized.PUT("/permissions/:id", middlewares.RequirePermission(authService, "permissions", "write"), permissionHandler.UpdatePermission)
-	authorized.DELETE("/permissions/:id", middlewares.RequirePermission(authService, "permissions", "write"), permissionHandler.DeletePermission)
+	// เมื่อ token แนบสิทธิ์มา การอ่านรายการสิทธิ์ตรวจจาก token โดยไม่โหลดผู้ใช้ และเทียบ pv กับแคชสิทธิ์เพื่อปฏิเสธ token ที่ล้าสมัย
+	if cfg.JWT.EmbedPermissions {
+		tokenAuthorized := r.Group("/api")
+		tokenAuthorized.Use(middlewares.TokenAuthMiddleware(jwtService, authOptions...))
+		tokenAuthorized.GET("/permissions", middlewares.RequireTokenPermission(authService, "permissions", "read", permissionOptions...), permissionHandler.GetPermissions)
+		tokenAuthorized.GET("/permissions/:id", middlewares.RequireTokenPermission(authService, "permissions", "read", permissionOptions...), permissionHandler.GetPermission)
+	} else {
+		authorized.GET("/permissions", requirePermission("permissions", "read"), permissionHandler.GetPermissions)
+		authorized.GET("/permissions/:id", requirePermission("permissions", "read"), permissionHandler.GetPermission)
+	}
+	authorized.POST("/permissions", requirePermission("permissions", "write"), permissionHandler.CreatePermission)
+	authorized.PUT("/permissions/:id", requirePermission("permissions", "write"), permissionHandler.UpdatePermission)
+	authorized.DELETE("/permissions/:id", requirePermission("permissions", "write"), permissionHandler.DeletePermission)
+
+	// Organization (tenant) routes
+	authorized.GET("/organizations", requirePermission("organizations", "read"), organizationHandler.GetOrganizations)
+	authorized.GET("/organizations/:id", requirePermission("organizations", "read"), organizationHandler.GetOrganization)
+	authorized.GET("/organizations/:id/users", requirePermission("organizations", "read"), organizationHandler.GetOrganizationUsers)
+	authorized.POST("/organizations", requirePermission("organizations", "write"), organizationHandler.CreateOrganization)
+	authorized.PUT("/organizations/:id", requirePermission("organizations", "write"), organizationHandler.UpdateOrganization)
+	authorized.DELETE("/organizations/:id", requirePermission("organizations", "write"), organizationHandler.DeleteOrganization)
+
+	// Group routes
+	authorized.GET("/groups", requirePermission("groups", "read"), groupHandler.GetGroups)
+	authorized.GET("/groups/:id", requirePermission("groups", "read"), groupHandler.GetGroup)
+	authorized.POST("/groups", requirePermission("groups", "write"), groupHandler.CreateGroup)
+	authorized.PUT("/groups/:id", requirePermission("groups", "write"), groupHandler.UpdateGroup)
+	authorized.DELETE("/groups/:id", requirePermission("groups", "write"), groupHandler.DeleteGroup)
+	authorized.POST("/groups/:id/members", requirePermission("groups", "write"), groupHandler.AddMemberToGroup)
+	authorized.DELETE("/groups/:id/members/:userId", requirePermission("groups", "write"), groupHandler.RemoveMemberFromGroup)
+	authorized.POST("/groups/:id/roles", requirePermission("groups", "write"), groupHandler.AddRoleToGroup)
+	authorized.DELETE("/groups/:id/roles/:roleId", requirePermission("groups", "write"), groupHandler.RemoveRoleFromGroup)
+
+	// Access request routes (ทุกคนขอสิทธิ์ให้ตนเองได้ การตัดสินใจต้องใช้สิทธิ์ผู้อนุมัติ)
+	authorized.GET("/access-requests", accessRequestHandler.GetAccessRequests)
+	authorized.GET("/access-requests/:id", accessRequestHandler.GetAccessRequest)
+	authorized.POST("/access-requests", accessRequestHandler.CreateAccessRequest)
+	authorized.POST("/access-requests/:id/approve", requirePermission(approverResource, approverAction), accessRequestHandler.ApproveAccessRequest)
+	authorized.POST("/access-requests/:id/deny", requirePermission(approverResource, approverAction), accessRequestHandler.DenyAccessRequest)
+	authorized.POST("/access-requests/:id/revoke", requirePermission(approverResource, approverAction), accessRequestHandler.RevokeAccessRequest)
+
+	// Access review routes (ผู้ทบทวนตัดสินรายการของตนได้โดยไม่ต้องมีสิทธิ์ access_reviews)
+	authorized.GET("/access-reviews", requirePermission("access_reviews", "read"), accessReviewHandler.GetAccessReviews)
+	authorized.GET("/access-reviews/:id", requirePermission("access_reviews", "read"), accessReviewHandler.GetAccessReview)
+	authorized.GET("/access-reviews/:id/report", requirePermission("access_reviews", "read"), accessReviewHandler.GetAccessReviewReport)
+	authorized.POST("/access-reviews", requirePermission("access_reviews", "write"), accessReviewHandler.CreateAccessReview)
+	authorized.POST("/access-reviews/:id/close", requirePermission("access_reviews", "write"), accessReviewHandler.CloseAccessReview)
+	authorized.POST("/access-reviews/:id/sign-off", requirePermission("access_reviews", "write"), accessReviewHandler.SignOffAccessReview)
+	authorized.POST("/access-reviews/:id/items/:itemId/decision", accessReviewHandler.DecideAccessReviewItem)
+
+	// Separation of duties rule routes
+	authorized.GET("/sod-rules", requirePermission("roles", "read"), sodRuleHandler.GetSoDRules)
+	authorized.GET("/sod-rules/:id", requirePermission("roles", "read"), sodRuleHandler.GetSoDRule)
+	authorized.GET("/sod-rules/:id/violations", requirePermission("roles", "read"), sodRuleHandler.GetSoDRuleViolations)
+	authorized.POST("/sod-rules", requirePermission("roles", "write"), sodRuleHandler.CreateSoDRule)
+	authorized.PUT("/sod-rules/:id", requirePermission("roles", "write"), sodRuleHandler.UpdateSoDRule)
+	authorized.DELETE("/sod-rules/:id", requirePermission("roles", "write"), sodRuleHandler.DeleteSoDRule)
+
+	// Service account routes
+	authorized.GET("/service-accounts", requirePermission("service_accounts", "read"), serviceAccountHandler.GetServiceAccounts)
+	authorized.POST("/service-accounts", requirePermission("service_accounts", "write"), serviceAccountHandler.CreateServiceAccount)
+	authorized.DELETE("/service-accounts/:id", requirePermission("service_accounts", "write"), serviceAccountHandler.DeleteServiceAccount)
+
+	// Policy as code routes
+	authorized.GET("/policy/export", requirePermission("policy", "read"), policyHandler.ExportPolicy)
+	authorized.POST("/policy/apply", requirePermission("policy", "write"), policyHandler.ApplyPolicy)
+
+	// Permission usage report routes
+	authorized.GET("/usage/unused-permissions", requirePermission("roles", "read"), usageHandler.GetUnusedPermissions)
+	authorized.GET("/usage/over-privileged", requirePermission("roles", "read"), usageHandler.GetOverPrivilegedUsers)
+	authorized.GET("/usage/denials", requirePermission("roles", "read"), usageHandler.GetDenialTrends)
+
+	// Relation tuple routes (ReBAC)
+	authorized.GET("/relations", requirePermission("relations", "read"), relationHandler.GetRelations)
+	authorized.POST("/relations/write", requirePermission("relations", "write"), relationHandler.WriteRelations)
+	authorized.POST("/relations/check", requirePermission("relations", "read"), relationHandler.CheckRelation)
+	authorized.POST("/relations/expand", requirePermission("relations", "read"), relationHandler.ExpandRelation)
+	authorized.POST("/relations/list-objects", requirePermission("relations", "read"), relationHandler.ListRelationObjects)
+
+	// ผู้ที่มีสิทธิ์และคำอธิบายผลการตัดสินสิทธิ์ (ใช้ token ของผู้ใช้)
+	authorized.GET("/authz/subjects", requirePermission("users", "read"), authzHandler.GetSubjects)
+	authorized.POST("/authz/explain", requirePermission("authz", "debug"), authzHandler.Explain)
+	authorized.POST("/authz/simulate", requirePermission("roles", "write"), authzHandler.Simulate)
+	authorized.GET("/authz/cache", requirePermission("authz", "debug"), authzHandler.GetCacheStats)
+
+	// Authorization decision API สำหรับบริการอื่น (ยืนยันตัวตนด้วยบัญชีบริการ ไม่ใช่ token ของผู้ใช้)
+	authz := r.Group("/api/authz")
+	authz.Use(middlewares.ServiceAuthMiddleware(serviceAccountService))
+	authz.POST("/check", authzHandler.Check)
+	authz.POST("/check-batch", authzHandler.CheckBatch)
+	authz.POST("/relations/check", relationHandler.CheckRelation)
+
+	// Forward-auth สำหรับ reverse proxy (nginx auth_request / Traefik ForwardAuth)
+	r.GET("/api/auth/verify", forwardAuthHandler.MatchRoute, middlewares.AuthMiddleware(jwtService, authService, forwardAuthOptions...), forwardAuthHandler.Verify)
 
 	// เริ่มต้นเซิร์ฟเวอร์
 	serverAddr := fmt.Sprintf(":%s", cfg.Server.Port)
-	log.Printf("Server starting on %s", serverAddr)
-	if err := r.Run(serverAddr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{Addr: serverAddr, Handler: r}
+	go func() {
+		log.Printf("Server starting on %s", serverAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.Timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
 	}
+
+	// บันทึกยอดการใช้สิทธิ์ที่ค้างอยู่ก่อนออกจากโปรแกรม
+	stopFlusher()
+	<-flusherDone
 }
@@ -4,12 +4,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,7 +21,11 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/yourusername/auth-api/internal/api/handlers"
 	"github.com/yourusername/auth-api/internal/api/middlewares"
+	"github.com/yourusername/auth-api/internal/rebac"
+	"github.com/yourusername/auth-api/internal/routeauth"
 	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+	"github.com/yourusername/auth-api/pkg/adminclient"
 	"github.com/yourusername/auth-api/pkg/database"
 	"github.com/yourusername/auth-api/pkg/jwt"
 	"gorm.io/gorm"
@@ -92,12 +98,45 @@ func (s *APIIntegrationTestSuite) SetupSuite() {
 
 	// สร้าง services
 	authService := service.NewAuthService(s.DB, s.JWTService)
+	assignmentService := service.NewAssignmentService(s.DB)
+	accessRequestService := service.NewAccessRequestService(s.DB, assignmentService, 8*time.Hour)
+	accessReviewService := service.NewAccessReviewService(s.DB, assignmentService)
+	serviceAccountService := service.NewServiceAccountService(s.DB)
+	usageService := service.NewUsageService(s.DB)
+	relationEngine, err := rebac.NewEngine(rebac.NewDBStore(s.DB), nil)
+	if err != nil {
+		s.T().Fatalf("Failed to create relation engine: %v", err)
+	}
+
+	// ใช้แคชสิทธิ์เหมือน production เพื่อให้ทุกการทดสอบตรวจสอบการ invalidate ไปด้วย
+	permissionCache := service.NewPermissionCache(time.Minute)
+	authService.UseCache(permissionCache)
+	assignmentService.OnChanged(permissionCache.InvalidateUser)
 
 	// สร้าง handlers
 	authHandler := handlers.NewAuthHandler(authService)
-	userHandler := handlers.NewUserHandler(s.DB)
-	roleHandler := handlers.NewRoleHandler(s.DB)
-	permissionHandler := handlers.NewPermissionHandler(s.DB)
+	userHandler := handlers.NewUserHandler(s.DB, assignmentService, permissionCache)
+	roleHandler := handlers.NewRoleHandler(s.DB, permissionCache)
+	permissionHandler := handlers.NewPermissionHandler(s.DB, permissionCache)
+	organizationHandler := handlers.NewOrganizationHandler(s.DB)
+	groupHandler := handlers.NewGroupHandler(s.DB, permissionCache)
+	accessRequestHandler := handlers.NewAccessRequestHandler(accessRequestService, authService, "access_requests", "approve")
+	sodRuleHandler := handlers.NewSoDRuleHandler(s.DB)
+	authzHandler := handlers.NewAuthzHandler(s.DB, authService, permissionCache)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(s.DB, serviceAccountService)
+	relationHandler := handlers.NewRelationHandler(relationEngine)
+	policyHandler := handlers.NewPolicyHandler(s.DB, permissionCache)
+	accessReviewHandler := handlers.NewAccessReviewHandler(s.DB, accessReviewService, authService)
+	usageHandler := handlers.NewUsageHandler(usageService)
+	forwardAuthRules, err := routeauth.NewTable([]routeauth.Rule{
+		{Path: "/static/**", Public: true},
+		{Path: "/reports/**", Methods: []string{"GET"}, Permission: "users:read"},
+		{Path: "/billing/**", Permission: "billing:write"},
+	}, routeauth.PolicyDeny)
+	if err != nil {
+		s.T().Fatalf("Failed to create forward-auth rules: %v", err)
+	}
+	forwardAuthHandler := handlers.NewForwardAuthHandler(forwardAuthRules, authService)
 
 	// สร้าง middlewares
 	authMiddleware := middlewares.AuthMiddleware(s.JWTService, authService)
@@ -107,17 +146,23 @@ func (s *APIIntegrationTestSuite) SetupSuite() {
 
 	// ตั้งค่า API routes
 	s.Router.POST("/api/login", authHandler.Login)
+	s.Router.GET("/.well-known/jwks.json", authHandler.GetJWKS)
 
 	// กลุ่ม routes ที่ต้องการการยืนยันตัวตน
 	authorized := s.Router.Group("/api")
 	authorized.Use(authMiddleware)
 
+	// Current user routes
+	authorized.GET("/me/permissions", authHandler.GetMyPermissions)
+	authorized.GET("/me/access-reviews", accessReviewHandler.GetMyReviewItems)
+
 	// User routes
 	authorized.GET("/users", middlewares.RequirePermission(authService, "users", "read"), userHandler.GetUsers)
 	authorized.GET("/users/:id", middlewares.RequirePermission(authService, "users", "read"), userHandler.GetUser)
 	authorized.POST("/users", middlewares.RequirePermission(authService, "users", "write"), userHandler.CreateUser)
 	authorized.PUT("/users/:id", middlewares.RequirePermission(authService, "users", "write"), userHandler.UpdateUser)
 	authorized.DELETE("/users/:id", middlewares.RequirePermission(authService, "users", "write"), userHandler.DeleteUser)
+	authorized.GET("/users/:id/roles", middlewares.RequirePermission(authService, "users", "read"), userHandler.GetUserRoleAssignments)
 	authorized.POST("/users/:id/roles", middlewares.RequirePermission(authService, "users", "write"), userHandler.AddRoleToUser)
 	authorized.DELETE("/users/:id/roles/:roleId", middlewares.RequirePermission(authService, "users", "write"), userHandler.RemoveRoleFromUser)
 
@@ -129,6 +174,9 @@ func (s *APIIntegrationTestSuite) SetupSuite() {
 	authorized.DELETE("/roles/:id", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.DeleteRole)
 	authorized.POST("/roles/:id/permissions", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.AddPermissionToRole)
 	authorized.DELETE("/roles/:id/permissions/:permissionId", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.RemovePermissionFromRole)
+	authorized.GET("/roles/:id/delegations", middlewares.RequirePermission(authService, "roles", "read"), roleHandler.GetRoleDelegations)
+	authorized.POST("/roles/:id/delegations", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.AddRoleDelegation)
+	authorized.DELETE("/roles/:id/delegations/:grantorRoleId", middlewares.RequirePermission(authService, "roles", "write"), roleHandler.RemoveRoleDelegation)
 
 	// Permission routes
 	authorized.GET("/permissions", middlewares.RequirePermission(authService, "permissions", "read"), permissionHandler.GetPermissions)
@@ -137,6 +185,88 @@ func (s *APIIntegrationTestSuite) SetupSuite() {
 	authorized.PUT("/permissions/:id", middlewares.RequirePermission(authService, "permissions", "write"), permissionHandler.UpdatePermission)
 	authorized.DELETE("/permissions/:id", middlewares.RequirePermission(authService, "permissions", "write"), permissionHandler.DeletePermission)
 
+	// Organization routes
+	authorized.GET("/organizations", middlewares.RequirePermission(authService, "organizations", "read"), organizationHandler.GetOrganizations)
+	authorized.GET("/organizations/:id", middlewares.RequirePermission(authService, "organizations", "read"), organizationHandler.GetOrganization)
+	authorized.GET("/organizations/:id/users", middlewares.RequirePermission(authService, "organizations", "read"), organizationHandler.GetOrganizationUsers)
+	authorized.POST("/organizations", middlewares.RequirePermission(authService, "organizations", "write"), organizationHandler.CreateOrganization)
+	authorized.PUT("/organizations/:id", middlewares.RequirePermission(authService, "organizations", "write"), organizationHandler.UpdateOrganization)
+	authorized.DELETE("/organizations/:id", middlewares.RequirePermission(authService, "organizations", "write"), organizationHandler.DeleteOrganization)
+
+	// Group routes
+	authorized.GET("/groups", middlewares.RequirePermission(authService, "groups", "read"), groupHandler.GetGroups)
+	authorized.GET("/groups/:id", middlewares.RequirePermission(authService, "groups", "read"), groupHandler.GetGroup)
+	authorized.POST("/groups", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.CreateGroup)
+	authorized.PUT("/groups/:id", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.UpdateGroup)
+	authorized.DELETE("/groups/:id", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.DeleteGroup)
+	authorized.POST("/groups/:id/members", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.AddMemberToGroup)
+	authorized.DELETE("/groups/:id/members/:userId", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.RemoveMemberFromGroup)
+	authorized.POST("/groups/:id/roles", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.AddRoleToGroup)
+	authorized.DELETE("/groups/:id/roles/:roleId", middlewares.RequirePermission(authService, "groups", "write"), groupHandler.RemoveRoleFromGroup)
+
+	// Access request routes
+	authorized.GET("/access-requests", accessRequestHandler.GetAccessRequests)
+	authorized.GET("/access-requests/:id", accessRequestHandler.GetAccessRequest)
+	authorized.POST("/access-requests", accessRequestHandler.CreateAccessRequest)
+	authorized.POST("/access-requests/:id/approve", middlewares.RequirePermission(authService, "access_requests", "approve"), accessRequestHandler.ApproveAccessRequest)
+	authorized.POST("/access-requests/:id/deny", middlewares.RequirePermission(authService, "access_requests", "approve"), accessRequestHandler.DenyAccessRequest)
+	authorized.POST("/access-requests/:id/revoke", middlewares.RequirePermission(authService, "access_requests", "approve"), accessRequestHandler.RevokeAccessRequest)
+
+	// Access review routes (ผู้ทบทวนตัดสินรายการของตนได้โดยไม่ต้องมีสิทธิ์ access_reviews)
+	authorized.GET("/access-reviews", middlewares.RequirePermission(authService, "access_reviews", "read"), accessReviewHandler.GetAccessReviews)
+	authorized.GET("/access-reviews/:id", middlewares.RequirePermission(authService, "access_reviews", "read"), accessReviewHandler.GetAccessReview)
+	authorized.GET("/access-reviews/:id/report", middlewares.RequirePermission(authService, "access_reviews", "read"), accessReviewHandler.GetAccessReviewReport)
+	authorized.POST("/access-reviews", middlewares.RequirePermission(authService, "access_reviews", "write"), accessReviewHandler.CreateAccessReview)
+	authorized.POST("/access-reviews/:id/close", middlewares.RequirePermission(authService, "access_reviews", "write"), accessReviewHandler.CloseAccessReview)
+	authorized.POST("/access-reviews/:id/sign-off", middlewares.RequirePermission(authService, "access_reviews", "write"), accessReviewHandler.SignOffAccessReview)
+	authorized.POST("/access-reviews/:id/items/:itemId/decision", accessReviewHandler.DecideAccessReviewItem)
+
+	// Separation of duties rule routes
+	authorized.GET("/sod-rules", middlewares.RequirePermission(authService, "roles", "read"), sodRuleHandler.GetSoDRules)
+	authorized.GET("/sod-rules/:id", middlewares.RequirePermission(authService, "roles", "read"), sodRuleHandler.GetSoDRule)
+	authorized.GET("/sod-rules/:id/violations", middlewares.RequirePermission(authService, "roles", "read"), sodRuleHandler.GetSoDRuleViolations)
+	authorized.POST("/sod-rules", middlewares.RequirePermission(authService, "roles", "write"), sodRuleHandler.CreateSoDRule)
+	authorized.PUT("/sod-rules/:id", middlewares.RequirePermission(authService, "roles", "write"), sodRuleHandler.UpdateSoDRule)
+	authorized.DELETE("/sod-rules/:id", middlewares.RequirePermission(authService, "roles", "write"), sodRuleHandler.DeleteSoDRule)
+
+	// Service account routes
+	authorized.GET("/service-accounts", middlewares.RequirePermission(authService, "service_accounts", "read"), serviceAccountHandler.GetServiceAccounts)
+	authorized.POST("/service-accounts", middlewares.RequirePermission(authService, "service_accounts", "write"), serviceAccountHandler.CreateServiceAccount)
+	authorized.DELETE("/service-accounts/:id", middlewares.RequirePermission(authService, "service_accounts", "write"), serviceAccountHandler.DeleteServiceAccount)
+
+	// Policy as code routes
+	authorized.GET("/policy/export", middlewares.RequirePermission(authService, "policy", "read"), policyHandler.ExportPolicy)
+	authorized.POST("/policy/apply", middlewares.RequirePermission(authService, "policy", "write"), policyHandler.ApplyPolicy)
+
+	// Permission usage report routes
+	authorized.GET("/usage/unused-permissions", middlewares.RequirePermission(authService, "roles", "read"), usageHandler.GetUnusedPermissions)
+	authorized.GET("/usage/over-privileged", middlewares.RequirePermission(authService, "roles", "read"), usageHandler.GetOverPrivilegedUsers)
+	authorized.GET("/usage/denials", middlewares.RequirePermission(authService, "roles", "read"), usageHandler.GetDenialTrends)
+
+	// Relation tuple routes (ReBAC)
+	authorized.GET("/relations", middlewares.RequirePermission(authService, "relations", "read"), relationHandler.GetRelations)
+	authorized.POST("/relations/write", middlewares.RequirePermission(authService, "relations", "write"), relationHandler.WriteRelations)
+	authorized.POST("/relations/check", middlewares.RequirePermission(authService, "relations", "read"), relationHandler.CheckRelation)
+	authorized.POST("/relations/expand", middlewares.RequirePermission(authService, "relations", "read"), relationHandler.ExpandRelation)
+	authorized.POST("/relations/list-objects", middlewares.RequirePermission(authService, "relations", "read"), relationHandler.ListRelationObjects)
+
+	// ผู้ที่มีสิทธิ์และคำอธิบายผลการตัดสินสิทธิ์ (ใช้ token ของผู้ใช้)
+	authorized.GET("/authz/subjects", middlewares.RequirePermission(authService, "users", "read"), authzHandler.GetSubjects)
+	authorized.POST("/authz/explain", middlewares.RequirePermission(authService, "authz", "debug"), authzHandler.Explain)
+	authorized.POST("/authz/simulate", middlewares.RequirePermission(authService, "roles", "write"), authzHandler.Simulate)
+	authorized.GET("/authz/cache", middlewares.RequirePermission(authService, "authz", "debug"), authzHandler.GetCacheStats)
+
+	// Authorization decision API สำหรับบริการอื่น (ยืนยันตัวตนด้วยบัญชีบริการ ไม่ใช่ token ของผู้ใช้)
+	authz := s.Router.Group("/api/authz")
+	authz.Use(middlewares.ServiceAuthMiddleware(serviceAccountService))
+	authz.POST("/check", authzHandler.Check)
+	authz.POST("/check-batch", authzHandler.CheckBatch)
+	authz.POST("/relations/check", relationHandler.CheckRelation)
+
+	// Forward-auth สำหรับ reverse proxy
+	s.Router.GET("/api/auth/verify", forwardAuthHandler.MatchRoute,
+		middlewares.AuthMiddleware(s.JWTService, authService, middlewares.TokenCookie("access_token")), forwardAuthHandler.Verify)
+
 	// เข้าสู่ระบบด้วยผู้ใช้ admin เพื่อให้ได้ token สำหรับการทดสอบ
 	loginReq := service.LoginRequest{
 		Username: "admin",
@@ -457,6 +587,185 @@ func (s *APIIntegrationTestSuite) TestPermissionsEndpoints() {
 		End()
 }
 
+func (s *APIIntegrationTestSuite) TestOrganizationsEndpoints() {
+	// สร้างองค์กรใหม่
+	newOrgResp := apitest.New().
+		Handler(s.Router).
+		Post("/api/organizations").
+		Header("Authorization", "Bearer "+s.AdminToken).
+		JSON(map[string]interface{}{
+			"name":        "test-tenant",
+			"description": "Test Tenant",
+		}).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Equal("$.name", "test-tenant")).
+		End().Response.Body
+
+	var newOrg map[string]interface{}
+	respBody, _ := io.ReadAll(newOrgResp)
+	json.Unmarshal(respBody, &newOrg)
+	newOrgID := fmt.Sprintf("%.0f", newOrg["id"].(float64))
+
+	// สร้างผู้ใช้ใน tenant
+	newUserResp := apitest.New().
+		Handler(s.Router).
+		Post("/api/users").
+		Header("Authorization", "Bearer "+s.AdminToken).
+		JSON(map[string]interface{}{
+			"username":        "tenantuser",
+			"email":           "tenantuser@example.com",
+			"password":        "password123",
+			"organization_id": newOrg["id"],
+		}).
+		Expect(s.T()).
+		Status(http.StatusCreated).
+		Assert(jsonpath.Equal("$.organization_id", newOrg["id"])).
+		End().Response.Body
+
+	var newUser map[string]interface{}
+	respBody, _ = io.ReadAll(newUserResp)
+	json.Unmarshal(respBody, &newUser)
+	newUserID := fmt.Sprintf("%.0f", newUser["id"].(float64))
+
+	// สมาชิกของ tenant
+	apitest.New().
+		Handler(s.Router).
+		Get("/api/organizations/"+newOrgID+"/users").
+		Header("Authorization", "Bearer "+s.AdminToken).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		Assert(jsonpath.Equal("$[0].username", "tenantuser")).
+		End()
+
+	// ลบองค์กรที่ยังมีสมาชิกไม่ได้
+	apitest.New().
+		Handler(s.Router).
+		Delete("/api/organizations/"+newOrgID).
+		Header("Authorization", "Bearer "+s.AdminToken).
+		Expect(s.T()).
+		Status(http.StatusBadRequest).
+		End()
+
+	apitest.New().
+		Handler(s.Router).
+		Delete("/api/users/"+newUserID).
+		Header("Authorization", "Bearer "+s.AdminToken).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		End()
+
+	apitest.New().
+		Handler(s.Router).
+		Delete("/api/organizations/"+newOrgID).
+		Header("Authorization", "Bearer "+s.AdminToken).
+		Expect(s.T()).
+		Status(http.StatusOK).
+		End()
+}
+
+func (s *APIIntegrationTestSuite) TestAdminClient() {
+	// ทดสอบ pkg/adminclient กับ router จริงผ่าน HTTP
+	server := httptest.NewServer(s.Router)
+	defer server.Close()
+	ctx := context.Background()
+
+	admin, err := adminclient.NewClient(adminclient.Config{BaseURL: server.URL, Username: "admin", Password: "adminpassword"})
+	s.Require().NoError(err)
+
+	// แบ่งหน้ารายการสิทธิ์ และรวมทุกหน้าได้ครบ
+	page, err := admin.ListPermissions(ctx, adminclient.ListOptions{PerPage: 2})
+	s.Require().NoError(err)
+	s.Len(page.Items, 2)
+	permissions, err := adminclient.All(ctx, admin.ListPermissions, adminclient.ListOptions{PerPage: 2})
+	s.Require().NoError(err)
+	s.Len(permissions, page.Total)
+
+	var readUsers adminapi.Permission
+	for _, permission := range permissions {
+		if permission.Resource == "users" && permission.Action == "read" {
+			readUsers = permission
+		}
+	}
+	s.Require().NotZero(readUsers.ID)
+
+	_, err = admin.CreatePermission(ctx, adminapi.CreatePermissionRequest{Resource: "users", Action: "read"})
+	s.ErrorIs(err, adminclient.ErrAlreadyExists)
+
+	// สร้างบทบาทและผู้ใช้ แล้วกำหนดบทบาทให้ผู้ใช้
+	role, err := admin.CreateRole(ctx, adminapi.CreateRoleRequest{
+		Name:        "sdk_user_reader",
+		Permissions: []adminapi.PermissionRef{{ID: readUsers.ID}},
+	})
+	s.Require().NoError(err)
+	s.Len(role.Permissions, 1)
+
+	user, err := admin.CreateUser(ctx, adminapi.CreateUserRequest{
+		Username: "sdkuser",
+		Email:    "sdkuser@example.com",
+		Password: "sdkpassword",
+	})
+	s.Require().NoError(err)
+	_, err = admin.AssignRole(ctx, user.ID, adminapi.AssignRoleRequest{RoleID: role.ID, Reason: "sdk test"})
+	s.Require().NoError(err)
+	assignments, err := admin.ListUserRoles(ctx, user.ID)
+	s.Require().NoError(err)
+	s.Require().Len(assignments, 1)
+	s.Equal("sdk_user_reader", assignments[0].Role.Name)
+
+	// ผู้ใช้ใหม่เข้าสู่ระบบด้วยรหัสผ่านที่ตั้งตอนสร้าง และทำได้เฉพาะสิทธิ์ของบทบาทที่ได้รับ
+	reader, err := adminclient.NewClient(adminclient.Config{BaseURL: server.URL, Username: "sdkuser", Password: "sdkpassword"})
+	s.Require().NoError(err)
+	fetched, err := reader.GetUser(ctx, user.ID)
+	s.Require().NoError(err)
+	s.Equal("sdkuser", fetched.Username)
+	_, err = reader.CreatePermission(ctx, adminapi.CreatePermissionRequest{Resource: "sdk", Action: "write"})
+	s.ErrorIs(err, adminclient.ErrForbidden)
+
+	// ลบข้อมูลที่สร้างขึ้น
+	s.NoError(admin.DeleteUser(ctx, user.ID))
+	s.NoError(admin.DeleteRole(ctx, role.ID))
+	_, err = admin.GetUser(ctx, user.ID)
+	s.ErrorIs(err, adminclient.ErrNotFound)
+}
+
+func (s *APIIntegrationTestSuite) TestForwardAuth() {
+	verify := func(method, uri, token, cookie string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/auth/verify", nil)
+		req.Header.Set("X-Forwarded-Method", method)
+		req.Header.Set("X-Forwarded-Uri", uri)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: cookie})
+		}
+		s.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// route สาธารณะไม่ต้องยืนยันตัวตน
+	s.Equal(http.StatusOK, verify("GET", "/static/app.js", "", "").Code)
+
+	// ผู้ใช้ที่มีสิทธิ์ได้ 200 พร้อม header ระบุตัวตน ทั้งจาก header Authorization และ session cookie
+	w := verify("GET", "/reports/monthly?year=2024", s.AdminToken, "")
+	s.Equal(http.StatusOK, w.Code)
+	s.NotEmpty(w.Header().Get("X-User-Id"))
+	s.Contains(strings.Split(w.Header().Get("X-User-Roles"), ","), "admin")
+	s.Equal(http.StatusOK, verify("GET", "/reports/monthly", "", s.AdminToken).Code)
+
+	// ไม่มี token ได้ 401 ส่วนสิทธิ์ไม่พอ method ไม่ตรงกฎ หรือไม่มีกฎที่ตรงกันได้ 403
+	s.Equal(http.StatusUnauthorized, verify("GET", "/reports/monthly", "", "").Code)
+	s.Equal(http.StatusUnauthorized, verify("GET", "/static/../reports/monthly", "", "").Code)
+	s.Equal(http.StatusForbidden, verify("POST", "/reports/monthly", s.AdminToken, "").Code)
+	s.Equal(http.StatusForbidden, verify("GET", "/billing/invoices", s.AdminToken, "").Code)
+	s.Equal(http.StatusForbidden, verify("GET", "/unknown", s.AdminToken, "").Code)
+
+	// proxy ต้องส่ง method และ URI เดิมมาด้วย
+	s.Equal(http.StatusBadRequest, verify("", "", s.AdminToken, "").Code)
+}
+
 func (s *APIIntegrationTestSuite) TestUnauthorizedAccess() {
 	// ทดสอบเข้าถึง API โดยไม่มี token
 	apitest.New().
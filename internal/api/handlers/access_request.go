@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type AccessRequestHandler struct {
+	accessRequests   *service.AccessRequestService
+	authService      service.AuthServiceInterface
+	approverResource string
+	approverAction   string
+}
+
+func NewAccessRequestHandler(accessRequests *service.AccessRequestService, authService service.AuthServiceInterface, approverResource, approverAction string) *AccessRequestHandler {
+	return &AccessRequestHandler{
+		accessRequests:   accessRequests,
+		authService:      authService,
+		approverResource: approverResource,
+		approverAction:   approverAction,
+	}
+}
+
+// CreateAccessRequest ผู้ใช้ขอบทบาทชั่วคราวให้ตนเองพร้อมเหตุผล
+func (h *AccessRequestHandler) CreateAccessRequest(c *gin.Context) {
+	var requestData struct {
+		RoleID        uint   `json:"role_id" binding:"required"`
+		Duration      string `json:"duration" binding:"required"`
+		Justification string `json:"justification" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	duration, err := time.ParseDuration(requestData.Duration)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+		return
+	}
+
+	request, err := h.accessRequests.Create(service.CreateAccessRequestInput{
+		RequesterID:   c.GetUint("userID"),
+		RoleID:        requestData.RoleID,
+		Duration:      duration,
+		Justification: requestData.Justification,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAccessDuration):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrRoleNotAvailable), errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access request"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetAccessRequests ผู้อนุมัติเห็นคำขอทั้งหมดใน tenant ของตน ผู้ใช้ทั่วไปเห็นเฉพาะคำขอของตนเอง
+func (h *AccessRequestHandler) GetAccessRequests(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	isApprover, err := callerHasPermission(c, h.authService, h.approverResource, h.approverAction)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	filter := service.AccessRequestFilter{
+		OrganizationID: currentTenantID(c),
+		Status:         c.Query("status"),
+	}
+	if !isApprover {
+		filter.RequesterID = &userID
+	}
+
+	requests, err := h.accessRequests.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetAccessRequest รับคำขอตาม ID (ผู้ขอหรือผู้อนุมัติเท่านั้น)
+func (h *AccessRequestHandler) GetAccessRequest(c *gin.Context) {
+	request, ok := h.findAccessRequest(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if request.RequesterID != userID {
+		isApprover, err := callerHasPermission(c, h.authService, h.approverResource, h.approverAction)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !isApprover {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Access request not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// ApproveAccessRequest อนุมัติคำขอและกำหนดบทบาทให้ผู้ขอตามระยะเวลาที่ขอ
+func (h *AccessRequestHandler) ApproveAccessRequest(c *gin.Context) {
+	h.decide(c, h.accessRequests.Approve)
+}
+
+// DenyAccessRequest ปฏิเสธคำขอ
+func (h *AccessRequestHandler) DenyAccessRequest(c *gin.Context) {
+	h.decide(c, h.accessRequests.Deny)
+}
+
+// RevokeAccessRequest ถอนสิทธิ์ที่อนุมัติแล้วก่อนหมดเวลา
+func (h *AccessRequestHandler) RevokeAccessRequest(c *gin.Context) {
+	h.decide(c, h.accessRequests.Revoke)
+}
+
+// decide ใช้ร่วมกันระหว่างการอนุมัติ ปฏิเสธ และถอนสิทธิ์
+func (h *AccessRequestHandler) decide(c *gin.Context, action func(id uint, actorID uint, note string) (*models.AccessRequest, error)) {
+	request, ok := h.findAccessRequest(c)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		Note string `json:"note"`
+	}
+	// note ไม่บังคับ จึงยอมรับ body ว่าง
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&requestData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	updated, err := action(request.ID, c.GetUint("userID"), requestData.Note)
+	if err != nil {
+		if respondSoDViolation(c, err) || respondLastRoleManager(c, err) {
+			return
+		}
+		var delegationErr *service.DelegationError
+		switch {
+		case errors.As(err, &delegationErr):
+			respondGrantError(c, err, "Failed to update access request")
+		case errors.Is(err, service.ErrAccessRequestNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSelfApproval):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAccessRequestNotPending), errors.Is(err, service.ErrAccessRequestNotActive),
+			errors.Is(err, service.ErrConflictingRoleBinding):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update access request"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// findAccessRequest ดึงคำขอตาม :id ภายใน tenant ของผู้เรียก และตอบ error ให้เองถ้าไม่พบ
+func (h *AccessRequestHandler) findAccessRequest(c *gin.Context) (*models.AccessRequest, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access request ID"})
+		return nil, false
+	}
+
+	request, err := h.accessRequests.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrAccessRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Access request not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access request"})
+		}
+		return nil, false
+	}
+
+	// ผู้ดูแล tenant จัดการได้เฉพาะคำขอใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil && !models.SameOrganization(tenantID, request.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access request not found"})
+		return nil, false
+	}
+
+	return request, true
+}
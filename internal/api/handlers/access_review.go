@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type AccessReviewHandler struct {
+	db            *gorm.DB
+	accessReviews *service.AccessReviewService
+	authService   service.AuthServiceInterface
+}
+
+func NewAccessReviewHandler(db *gorm.DB, accessReviews *service.AccessReviewService, authService service.AuthServiceInterface) *AccessReviewHandler {
+	return &AccessReviewHandler{
+		db:            db,
+		accessReviews: accessReviews,
+		authService:   authService,
+	}
+}
+
+// CreateAccessReview สร้างแคมเปญทบทวนสิทธิ์ของบทบาทที่เลือก พร้อมผู้ทบทวนของแต่ละบทบาท
+func (h *AccessReviewHandler) CreateAccessReview(c *gin.Context) {
+	var requestData struct {
+		Name              string    `json:"name" binding:"required"`
+		Description       string    `json:"description"`
+		RoleIDs           []uint    `json:"role_ids" binding:"required,min=1"`
+		Deadline          time.Time `json:"deadline" binding:"required"`
+		AutoRevoke        bool      `json:"auto_revoke"`
+		DefaultReviewerID *uint     `json:"default_reviewer_id"`
+		Reviewers         []struct {
+			RoleID     uint `json:"role_id" binding:"required"`
+			ReviewerID uint `json:"reviewer_id" binding:"required"`
+		} `json:"reviewers" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// บทบาทต้องมองเห็นได้ และผู้ทบทวนต้องอยู่ใน tenant ของผู้เรียก
+	var roleCount int64
+	if err := h.db.Model(&models.Role{}).Scopes(tenantRoles(c)).Where("id IN ?", requestData.RoleIDs).Count(&roleCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access review"})
+		return
+	}
+	if int(roleCount) != len(uniqueIDs(requestData.RoleIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	reviewers := make(map[uint]uint, len(requestData.Reviewers))
+	reviewerIDs := []uint{}
+	for _, reviewer := range requestData.Reviewers {
+		reviewers[reviewer.RoleID] = reviewer.ReviewerID
+		reviewerIDs = append(reviewerIDs, reviewer.ReviewerID)
+	}
+	if requestData.DefaultReviewerID != nil {
+		reviewerIDs = append(reviewerIDs, *requestData.DefaultReviewerID)
+	}
+	if len(reviewerIDs) > 0 {
+		var reviewerCount int64
+		if err := h.db.Model(&models.User{}).Scopes(tenantUsers(c)).Where("id IN ?", reviewerIDs).Count(&reviewerCount).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access review"})
+			return
+		}
+		if int(reviewerCount) != len(uniqueIDs(reviewerIDs)) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reviewer not found"})
+			return
+		}
+	}
+
+	campaign, err := h.accessReviews.Create(service.CreateAccessReviewInput{
+		Name:              requestData.Name,
+		Description:       requestData.Description,
+		RoleIDs:           requestData.RoleIDs,
+		Deadline:          requestData.Deadline,
+		AutoRevoke:        requestData.AutoRevoke,
+		Reviewers:         reviewers,
+		DefaultReviewerID: requestData.DefaultReviewerID,
+		CreatedBy:         c.GetUint("userID"),
+		OrganizationID:    currentTenantID(c),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAccessReview) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create access review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// GetAccessReviews รับรายการแคมเปญใน tenant ของผู้เรียก (?status= กรองตามสถานะ)
+func (h *AccessReviewHandler) GetAccessReviews(c *gin.Context) {
+	campaigns, err := h.accessReviews.List(service.AccessReviewFilter{
+		OrganizationID: currentTenantID(c),
+		Status:         c.Query("status"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, campaigns)
+}
+
+// GetAccessReview รับแคมเปญพร้อมรายการทั้งหมด
+func (h *AccessReviewHandler) GetAccessReview(c *gin.Context) {
+	campaign, ok := h.findAccessReview(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, campaign)
+}
+
+// GetMyReviewItems รับรายการที่ผู้เรียกต้องทบทวนในแคมเปญที่ยังเปิดอยู่ (?decision=pending เฉพาะที่ยังไม่ตัดสิน)
+func (h *AccessReviewHandler) GetMyReviewItems(c *gin.Context) {
+	items, err := h.accessReviews.ListReviewerItems(c.GetUint("userID"), c.Query("decision"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access review items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// DecideAccessReviewItem ผู้ทบทวน (หรือผู้มีสิทธิ์ access_reviews:write) อนุมัติหรือถอนบทบาทของรายการหนึ่ง
+// ผู้ทบทวนอาจอยู่คนละ tenant กับแคมเปญ (เช่นแคมเปญระดับ global) จึงไม่จำกัดด้วย tenant แต่ service ตรวจสอบว่าเป็นผู้ทบทวนของรายการ
+func (h *AccessReviewHandler) DecideAccessReviewItem(c *gin.Context) {
+	campaignID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access review ID"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var requestData struct {
+		Decision string `json:"decision" binding:"required"`
+		Note     string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	isAdmin, err := callerHasPermission(c, h.authService, "access_reviews", "write")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	// ผู้ดูแล tenant ตัดสินแทนผู้ทบทวนได้เฉพาะแคมเปญใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); isAdmin && tenantID != nil {
+		campaign, err := h.accessReviews.Get(uint(campaignID))
+		isAdmin = err == nil && models.SameOrganization(tenantID, campaign.OrganizationID)
+	}
+
+	item, err := h.accessReviews.Decide(uint(campaignID), uint(itemID), userID, isAdmin, requestData.Decision, requestData.Note)
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrAccessReviewNotFound), errors.Is(err, service.ErrAccessReviewItemNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidReviewDecision):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotReviewer), errors.Is(err, service.ErrSelfReview):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAccessReviewNotOpen), errors.Is(err, service.ErrAccessReviewItemDecided):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decide on access review item"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// CloseAccessReview ปิดแคมเปญก่อนกำหนด รายการที่ยังไม่ตัดสินจะถูกถอนหรือคงไว้ตาม auto_revoke
+func (h *AccessReviewHandler) CloseAccessReview(c *gin.Context) {
+	campaign, ok := h.findAccessReview(c)
+	if !ok {
+		return
+	}
+
+	actorID := c.GetUint("userID")
+	closed, err := h.accessReviews.Close(campaign.ID, &actorID)
+	if err != nil {
+		if errors.Is(err, service.ErrAccessReviewNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close access review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, closed)
+}
+
+// SignOffAccessReview รับรองผลแคมเปญที่ปิดแล้ว และคืนรายงานที่รับรอง
+func (h *AccessReviewHandler) SignOffAccessReview(c *gin.Context) {
+	campaign, ok := h.findAccessReview(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.accessReviews.SignOff(campaign.ID, c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, service.ErrAccessReviewNotClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign off access review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAccessReviewReport รับรายงานสรุปผลของแคมเปญ
+func (h *AccessReviewHandler) GetAccessReviewReport(c *gin.Context) {
+	campaign, ok := h.findAccessReview(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.accessReviews.Report(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build access review report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// findAccessReview ดึงแคมเปญตาม :id ภายใน tenant ของผู้เรียก และตอบ error ให้เองถ้าไม่พบ
+func (h *AccessReviewHandler) findAccessReview(c *gin.Context) (*models.AccessReviewCampaign, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid access review ID"})
+		return nil, false
+	}
+
+	campaign, err := h.accessReviews.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, service.ErrAccessReviewNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Access review not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch access review"})
+		}
+		return nil, false
+	}
+
+	// ผู้ดูแล tenant จัดการได้เฉพาะแคมเปญใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil && !models.SameOrganization(tenantID, campaign.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Access review not found"})
+		return nil, false
+	}
+
+	return campaign, true
+}
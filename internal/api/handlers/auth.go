@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/jwt"
 )
 
 type AuthHandler struct {
@@ -27,6 +30,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	resp, err := h.authService.Login(&loginReq)
+	if errors.Is(err, service.ErrInvalidScope) || errors.Is(err, service.ErrUnknownClient) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -34,3 +41,51 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+// GetJWKS เผยแพร่ public key สำหรับตรวจ token (ว่างเมื่อเซ็นด้วย secret key)
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
+
+// GetMyPermissions คืนบทบาทและสิทธิ์ที่มีผลจริงของผู้ใช้ปัจจุบัน (รวมบทบาทที่ได้รับผ่านกลุ่ม)
+func (h *AuthHandler) GetMyPermissions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	roles, err := h.authService.GetEffectiveRoles(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	roleNames := make([]string, 0, len(roles))
+	permissionSet := make(map[string]bool)
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+		for _, perm := range role.Permissions {
+			permissionSet[perm.Key()] = true
+		}
+	}
+
+	permissions := make([]string, 0, len(permissionSet))
+	for key := range permissionSet {
+		permissions = append(permissions, key)
+	}
+	sort.Strings(roleNames)
+	sort.Strings(permissions)
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":     userID,
+		"roles":       roleNames,
+		"permissions": permissions,
+	})
+}
+
+// callerHasPermission ตรวจสิทธิ์ของผู้ใช้ปัจจุบันสำหรับการตัดสินใจภายใน handler
+// token ที่จำกัด scope จะได้เฉพาะสิทธิ์ใน scope เช่นเดียวกับ RequirePermission
+func callerHasPermission(c *gin.Context, authService service.AuthServiceInterface, resource string, action string) (bool, error) {
+	if claimsValue, exists := c.Get("claims"); exists && !claimsValue.(*jwt.Claims).ScopeCovers(resource, action) {
+		return false, nil
+	}
+	return authService.HasPermission(c.GetUint("userID"), resource, action)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// maxBatchChecks จำนวนคำถามสูงสุดต่อการเรียก check-batch หนึ่งครั้ง
+const maxBatchChecks = 100
+
+// maxSimulatedChanges จำนวนการเปลี่ยนแปลงสูงสุดต่อการจำลองหนึ่งครั้ง
+// แต่ละรายการอาจโหลดบทบาทของผู้ถือบทบาททุกคน จึงจำกัดแยกจาก check-batch
+const maxSimulatedChanges = 50
+
+// AuthzSubject ผู้ที่ถูกตรวจสอบสิทธิ์ รองรับเฉพาะ type "user" (หรือว่าง) บัญชีบริการไม่มีบทบาทจึงตรวจสิทธิ์ไม่ได้
+type AuthzSubject struct {
+	Type string `json:"type"`
+	ID   uint   `json:"id" binding:"required"`
+}
+
+// AuthzCheckRequest คำถามการตัดสินสิทธิ์หนึ่งรายการ
+type AuthzCheckRequest struct {
+	Subject  AuthzSubject `json:"subject" binding:"required"`
+	Resource string       `json:"resource" binding:"required"`
+	Action   string       `json:"action" binding:"required"`
+	// ResourceID และ Attributes สงวนไว้สำหรับนโยบายระดับ instance ในอนาคต ตอนนี้ถูกปฏิเสธ (400)
+	// เพื่อไม่ให้ผู้เรียกเข้าใจผิดว่าผล allow ตรวจถึงระดับ instance แล้ว
+	ResourceID string                 `json:"resource_id,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// AuthzDecision ผลการตัดสินสิทธิ์
+type AuthzDecision struct {
+	Allowed  bool   `json:"allowed"`
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type AuthzHandler struct {
+	db          *gorm.DB
+	authService service.AuthServiceInterface
+	cache       *service.PermissionCache
+}
+
+func NewAuthzHandler(db *gorm.DB, authService service.AuthServiceInterface, cache *service.PermissionCache) *AuthzHandler {
+	return &AuthzHandler{
+		db:          db,
+		authService: authService,
+		cache:       cache,
+	}
+}
+
+// GetCacheStats รับสถิติ hit/miss ของแคชสิทธิ์ใน instance นี้
+func (h *AuthzHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cache.Stats())
+}
+
+// Check ตัดสินว่า subject มีสิทธิ์ resource:action หรือไม่ (ใช้ตรรกะเดียวกับ RequirePermission)
+func (h *AuthzHandler) Check(c *gin.Context) {
+	var request AuthzCheckRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCheck(request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	decision, err := h.evaluate(request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// CheckBatch ตัดสินสิทธิ์หลายรายการในการเรียกครั้งเดียว ผลลัพธ์เรียงตามลำดับคำถาม
+func (h *AuthzHandler) CheckBatch(c *gin.Context) {
+	var requestData struct {
+		Checks []AuthzCheckRequest `json:"checks" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(requestData.Checks) > maxBatchChecks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many checks in one batch"})
+		return
+	}
+	for i, request := range requestData.Checks {
+		if err := validateCheck(request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("checks[%d]: %v", i, err)})
+			return
+		}
+	}
+
+	results := make([]AuthzDecision, 0, len(requestData.Checks))
+	for _, request := range requestData.Checks {
+		decision, err := h.evaluate(request)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		results = append(results, decision)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Explain อธิบายผลการประเมินสิทธิ์ของผู้ใช้แบบละเอียด (บทบาท สิทธิ์แต่ละข้อ และเหตุผล) สำหรับผู้ดูแลระบบ
+func (h *AuthzHandler) Explain(c *gin.Context) {
+	explainer, ok := h.authService.(service.PermissionExplainer)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Permission explanations are not supported"})
+		return
+	}
+
+	var request AuthzCheckRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !isUserSubject(request.Subject) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errUnsupportedSubject.Error()})
+		return
+	}
+
+	explanation, err := explainer.ExplainPermission(request.Subject.ID, request.Resource, request.Action)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to explain permission"})
+		}
+		return
+	}
+
+	// ผู้ดูแล tenant ดูได้เฉพาะผู้ใช้ใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil && !models.SameOrganization(tenantID, explanation.OrganizationID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// GetSubjects ตอบว่าใครบ้างที่มีสิทธิ์ resource:action ที่มีผลอยู่ พร้อมเส้นทางบทบาทที่ให้สิทธิ์
+// ?type= รองรับเฉพาะ user (หรือไม่ระบุ) ผู้ดูแล tenant เห็นเฉพาะผู้ใช้ใน tenant ของตนเอง
+func (h *AuthzHandler) GetSubjects(c *gin.Context) {
+	resource := c.Query("resource")
+	action := c.Query("action")
+	if resource == "" || action == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource and action are required"})
+		return
+	}
+	// บัญชีบริการไม่มีบทบาท การตอบรายการว่างจะทำให้เข้าใจผิดว่าตรวจแล้วไม่พบ จึงปฏิเสธแทน
+	if !isUserSubject(AuthzSubject{Type: c.Query("type")}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errUnsupportedSubject.Error()})
+		return
+	}
+
+	holders, err := service.FindPermissionHolders(h.db, resource, action, currentTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find permission holders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"resource": resource, "action": action, "subjects": holders})
+}
+
+// Simulate จำลองการเปลี่ยนแปลงบทบาทและสิทธิ์ที่เสนอ แล้วตอบว่าผู้ใช้คนใดจะได้รับหรือสูญเสียสิทธิ์ใด โดยไม่บันทึกสิ่งใด
+// ผู้ดูแล tenant จำลองได้เฉพาะบทบาทและผู้ใช้ของ tenant ตนเอง
+func (h *AuthzHandler) Simulate(c *gin.Context) {
+	var requestData struct {
+		Changes []service.SimulatedChange `json:"changes" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(requestData.Changes) > maxSimulatedChanges {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many changes in one simulation"})
+		return
+	}
+
+	for _, change := range requestData.Changes {
+		var role models.Role
+		if result := h.db.Scopes(tenantRoles(c)).First(&role, change.RoleID); result.Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+			return
+		}
+
+		switch change.Type {
+		case service.SimulateAssignRole, service.SimulateRevokeRole:
+			var user models.User
+			if result := h.db.Scopes(tenantUsers(c)).First(&user, change.UserID); result.Error != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+		default:
+			if !canManageRole(c, role.OrganizationID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+				return
+			}
+		}
+	}
+
+	result, err := service.SimulateChanges(h.db, requestData.Changes, currentTenantID(c))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSimulation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to simulate changes"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+var (
+	errUnsupportedSubject    = errors.New("unsupported subject type")
+	errUnsupportedConstraint = errors.New("resource_id and attributes are not supported")
+)
+
+// isUserSubject ตรวจว่า subject เป็นผู้ใช้ (type ว่างถือเป็น "user")
+func isUserSubject(subject AuthzSubject) bool {
+	return subject.Type == "" || subject.Type == "user"
+}
+
+// validateCheck ปฏิเสธคำถามที่ RBAC ตอบไม่ได้ แทนที่จะตอบ deny หรือ allow แบบไม่ตรงกับสิ่งที่ถาม
+func validateCheck(request AuthzCheckRequest) error {
+	if !isUserSubject(request.Subject) {
+		return errUnsupportedSubject
+	}
+	if request.ResourceID != "" || len(request.Attributes) > 0 {
+		return errUnsupportedConstraint
+	}
+	return nil
+}
+
+// evaluate ตัดสินสิทธิ์หนึ่งรายการที่ผ่าน validateCheck แล้ว ผู้ใช้ที่ไม่พบถือว่าไม่มีสิทธิ์
+func (h *AuthzHandler) evaluate(request AuthzCheckRequest) (AuthzDecision, error) {
+	allowed, err := h.authService.HasPermission(request.Subject.ID, request.Resource, request.Action)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return deny("subject not found"), nil
+		}
+		return AuthzDecision{}, err
+	}
+
+	if !allowed {
+		return deny("no role grants " + request.Resource + ":" + request.Action), nil
+	}
+	return AuthzDecision{Allowed: true, Decision: "allow"}, nil
+}
+
+func deny(reason string) AuthzDecision {
+	return AuthzDecision{Allowed: false, Decision: "deny", Reason: reason}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// mockAuthService เป็น mock ของ AuthServiceInterface ผู้ใช้ 1 มีสิทธิ์ reports:read เท่านั้น ผู้ใช้อื่นไม่มีอยู่
+type mockAuthService struct {
+	permissionErr error
+}
+
+func (m *mockAuthService) GetUserByID(userID uint) (*models.User, error) {
+	if userID != 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.User{ID: 1, Username: "alice"}, nil
+}
+
+func (m *mockAuthService) GetGroupRoles(userID uint) ([]models.Role, error) {
+	return nil, nil
+}
+
+func (m *mockAuthService) HasPermission(userID uint, resource string, action string) (bool, error) {
+	if m.permissionErr != nil {
+		return false, m.permissionErr
+	}
+	if userID != 1 {
+		return false, gorm.ErrRecordNotFound
+	}
+	return resource == "reports" && action == "read", nil
+}
+
+func (m *mockAuthService) Login(_ *service.LoginRequest) (*service.LoginResponse, error) {
+	return nil, nil
+}
+
+func setupAuthzTest(authService service.AuthServiceInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthzHandler(nil, authService, nil)
+	r := gin.New()
+	r.POST("/authz/check", h.Check)
+	r.POST("/authz/check-batch", h.CheckBatch)
+	r.GET("/authz/subjects", h.GetSubjects)
+	return r
+}
+
+func checkBody(userID uint, resource, action string) gin.H {
+	return gin.H{"subject": gin.H{"type": "user", "id": userID}, "resource": resource, "action": action}
+}
+
+func TestAuthzCheck(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{})
+
+	cases := []struct {
+		name     string
+		body     gin.H
+		decision AuthzDecision
+	}{
+		{"allowed", checkBody(1, "reports", "read"), AuthzDecision{Allowed: true, Decision: "allow"}},
+		{"no role grants", checkBody(1, "reports", "write"), AuthzDecision{Decision: "deny", Reason: "no role grants reports:write"}},
+		{"unknown subject", checkBody(2, "reports", "read"), AuthzDecision{Decision: "deny", Reason: "subject not found"}},
+		{"subject type omitted", gin.H{"subject": gin.H{"id": 1}, "resource": "reports", "action": "read"}, AuthzDecision{Allowed: true, Decision: "allow"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := performJSON(r, http.MethodPost, "/authz/check", tc.body)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var decision AuthzDecision
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decision))
+			assert.Equal(t, tc.decision, decision)
+		})
+	}
+}
+
+func TestAuthzCheck_RejectsUnsupportedRequests(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{})
+
+	serviceAccount := checkBody(1, "reports", "read")
+	serviceAccount["subject"] = gin.H{"type": "service_account", "id": 1}
+	withResourceID := checkBody(1, "reports", "read")
+	withResourceID["resource_id"] = "42"
+	withAttributes := checkBody(1, "reports", "read")
+	withAttributes["attributes"] = gin.H{"owner": "alice"}
+
+	cases := []struct {
+		name string
+		body gin.H
+	}{
+		{"missing action", gin.H{"subject": gin.H{"id": 1}, "resource": "reports"}},
+		{"service account subject", serviceAccount},
+		{"resource id", withResourceID},
+		{"attributes", withAttributes},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := performJSON(r, http.MethodPost, "/authz/check", tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestAuthzCheck_PermissionError(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{permissionErr: errors.New("database unavailable")})
+
+	w := performJSON(r, http.MethodPost, "/authz/check", checkBody(1, "reports", "read"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAuthzCheckBatch(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{})
+
+	// ผลลัพธ์เรียงตามลำดับคำถาม
+	w := performJSON(r, http.MethodPost, "/authz/check-batch", gin.H{"checks": []gin.H{
+		checkBody(1, "reports", "write"),
+		checkBody(1, "reports", "read"),
+		checkBody(2, "reports", "read"),
+	}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Results []AuthzDecision `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Results, 3)
+	assert.False(t, response.Results[0].Allowed)
+	assert.True(t, response.Results[1].Allowed)
+	assert.Equal(t, "subject not found", response.Results[2].Reason)
+}
+
+func TestAuthzCheckBatch_Validation(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{})
+
+	// ไม่มีคำถาม
+	w := performJSON(r, http.MethodPost, "/authz/check-batch", gin.H{"checks": []gin.H{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// เกินจำนวนสูงสุด
+	checks := make([]gin.H, maxBatchChecks+1)
+	for i := range checks {
+		checks[i] = checkBody(1, "reports", "read")
+	}
+	w = performJSON(r, http.MethodPost, "/authz/check-batch", gin.H{"checks": checks})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// คำถามที่ไม่รองรับรายการใดรายการหนึ่งทำให้ทั้ง batch ถูกปฏิเสธ พร้อมระบุตำแหน่ง
+	serviceAccount := checkBody(1, "reports", "read")
+	serviceAccount["subject"] = gin.H{"type": "service_account", "id": 1}
+	w = performJSON(r, http.MethodPost, "/authz/check-batch", gin.H{"checks": []gin.H{checkBody(1, "reports", "read"), serviceAccount}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "checks[1]")
+}
+
+func TestAuthzSubjects_Validation(t *testing.T) {
+	r := setupAuthzTest(&mockAuthService{})
+
+	// ทั้งสองกรณีถูกปฏิเสธก่อนถึงฐานข้อมูล
+	for _, path := range []string{"/authz/subjects?resource=reports", "/authz/subjects?resource=reports&action=read&type=service_account"} {
+		w := performJSON(r, http.MethodGet, path, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+// mockExplainingAuthService เพิ่ม PermissionExplainer ให้ mockAuthService ผู้ใช้ 1 อยู่ใน tenant 3
+type mockExplainingAuthService struct {
+	mockAuthService
+}
+
+func (m *mockExplainingAuthService) ExplainPermission(userID uint, resource string, action string) (*service.PermissionExplanation, error) {
+	if userID != 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	allowed, _ := m.HasPermission(userID, resource, action)
+	organizationID := uint(3)
+	return &service.PermissionExplanation{
+		UserID:         userID,
+		OrganizationID: &organizationID,
+		Resource:       resource,
+		Action:         action,
+		Allowed:        allowed,
+		Reason:         "role reporter grants reports:read",
+		Roles:          []service.RoleTrace{{RoleID: 4, RoleName: "reporter", Source: service.RoleSourceDirect, Active: true}},
+	}, nil
+}
+
+// setupExplainTest tenantID ไม่เป็น nil จำลองผู้ดูแลระดับ tenant
+func setupExplainTest(authService service.AuthServiceInterface, tenantID *uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthzHandler(nil, authService, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenantID", tenantID)
+		c.Next()
+	})
+	r.POST("/authz/explain", h.Explain)
+	return r
+}
+
+func TestAuthzExplain(t *testing.T) {
+	r := setupExplainTest(&mockExplainingAuthService{}, nil)
+
+	w := performJSON(r, http.MethodPost, "/authz/explain", checkBody(1, "reports", "read"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var explanation service.PermissionExplanation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &explanation))
+	assert.True(t, explanation.Allowed)
+	assert.Equal(t, "role reporter grants reports:read", explanation.Reason)
+	require.Len(t, explanation.Roles, 1)
+	assert.Equal(t, "reporter", explanation.Roles[0].RoleName)
+}
+
+func TestAuthzExplain_Errors(t *testing.T) {
+	ownTenant, otherTenant := uint(3), uint(4)
+
+	cases := []struct {
+		name        string
+		authService service.AuthServiceInterface
+		tenantID    *uint
+		body        gin.H
+		status      int
+	}{
+		{"explainer not available", &mockAuthService{}, nil, checkBody(1, "reports", "read"), http.StatusNotImplemented},
+		{"missing action", &mockExplainingAuthService{}, nil, gin.H{"subject": gin.H{"id": 1}, "resource": "reports"}, http.StatusBadRequest},
+		{"service account subject", &mockExplainingAuthService{}, nil, gin.H{"subject": gin.H{"type": "service_account", "id": 1}, "resource": "reports", "action": "read"}, http.StatusBadRequest},
+		{"unknown user", &mockExplainingAuthService{}, nil, checkBody(2, "reports", "read"), http.StatusNotFound},
+		{"user in own tenant", &mockExplainingAuthService{}, &ownTenant, checkBody(1, "reports", "read"), http.StatusOK},
+		{"user in another tenant", &mockExplainingAuthService{}, &otherTenant, checkBody(1, "reports", "read"), http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := setupExplainTest(tc.authService, tc.tenantID)
+			w := performJSON(r, http.MethodPost, "/authz/explain", tc.body)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// respondAlreadyExists ตอบ 400 สำหรับข้อมูลที่ซ้ำกับที่มีอยู่ พร้อม code ที่ client ตรวจได้โดยไม่ต้องอ่านข้อความ
+func respondAlreadyExists(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": message, "code": adminapi.ErrorCodeAlreadyExists})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/routeauth"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+// ForwardAuthHandler ตัดสินสิทธิ์ request ของแอปที่อยู่หลัง reverse proxy (nginx auth_request / Traefik ForwardAuth)
+// ใช้เป็นลำดับ MatchRoute -> AuthMiddleware -> Verify
+type ForwardAuthHandler struct {
+	rules       *routeauth.Table
+	authService service.AuthServiceInterface
+}
+
+func NewForwardAuthHandler(rules *routeauth.Table, authService service.AuthServiceInterface) *ForwardAuthHandler {
+	return &ForwardAuthHandler{
+		rules:       rules,
+		authService: authService,
+	}
+}
+
+// MatchRoute อ่าน method, host และ URI เดิมจาก header ที่ proxy ส่งมา แล้วหากฎที่ตรงกัน
+// route สาธารณะตอบ 200 ทันทีโดยไม่ต้องยืนยันตัวตน ส่วน route อื่นส่งต่อให้ AuthMiddleware และ Verify
+func (h *ForwardAuthHandler) MatchRoute(c *gin.Context) {
+	// Traefik ส่ง X-Forwarded-* ส่วน nginx ตั้งเองใน location ของ auth_request (นิยมใช้ X-Original-*)
+	method := firstHeader(c, "X-Forwarded-Method", "X-Original-Method")
+	uri := firstHeader(c, "X-Forwarded-Uri", "X-Original-URI")
+	if method == "" || uri == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Forwarded-Method and X-Forwarded-Uri headers are required"})
+		c.Abort()
+		return
+	}
+	requestURL, err := url.ParseRequestURI(uri)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid forwarded URI"})
+		c.Abort()
+		return
+	}
+
+	requirement := h.rules.Match(method, firstHeader(c, "X-Forwarded-Host", "X-Original-Host"), requestURL.Path)
+	if requirement.Public {
+		c.Status(http.StatusOK)
+		c.Abort()
+		return
+	}
+
+	c.Set("routeRequirement", requirement)
+	c.Next()
+}
+
+// Verify ตรวจสิทธิ์ตามกฎที่ MatchRoute หาได้ แล้วตอบ 200 พร้อม header ระบุตัวตนให้ proxy ส่งต่อไปยังแอป
+func (h *ForwardAuthHandler) Verify(c *gin.Context) {
+	requirement := c.MustGet("routeRequirement").(routeauth.Requirement)
+	if requirement.Denied {
+		c.JSON(http.StatusForbidden, gin.H{"error": "No rule allows this route"})
+		return
+	}
+
+	if requirement.NeedsPermission() {
+		allowed, err := callerHasPermission(c, h.authService, requirement.Resource, requirement.Action)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return
+		}
+	}
+
+	c.Header("X-User-Id", strconv.FormatUint(uint64(c.GetUint("userID")), 10))
+	c.Header("X-User-Roles", strings.Join(contextRoleNames(c), ","))
+	c.Status(http.StatusOK)
+}
+
+// firstHeader คืนค่าของ header แรกในรายการที่มีค่า
+func firstHeader(c *gin.Context, names ...string) string {
+	for _, name := range names {
+		if value := c.GetHeader(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// contextRoleNames คืนชื่อบทบาทที่มีผลซึ่ง AuthMiddleware เก็บไว้ เรียงและไม่ซ้ำ
+func contextRoleNames(c *gin.Context) []string {
+	value, _ := c.Get("roles")
+	roles, _ := value.([]models.Role)
+	return service.RoleNames(roles)
+}
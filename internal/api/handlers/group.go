@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupHandler struct {
+	db    *gorm.DB
+	cache *service.PermissionCache
+}
+
+func NewGroupHandler(db *gorm.DB, cache *service.PermissionCache) *GroupHandler {
+	return &GroupHandler{
+		db:    db,
+		cache: cache,
+	}
+}
+
+// GetGroups รับรายการกลุ่มทั้งหมด
+func (h *GroupHandler) GetGroups(c *gin.Context) {
+	var groups []models.Group
+	result := h.db.Scopes(tenantGroups(c)).Preload("Roles").Find(&groups)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetGroup รับข้อมูลกลุ่มตาม ID พร้อมสมาชิกและบทบาท
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	var group models.Group
+	result := h.db.Scopes(tenantGroups(c)).Preload("Members").Preload("Roles").First(&group, groupID)
+	if result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// CreateGroup สร้างกลุ่มใหม่
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var group models.Group
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// สมาชิกและบทบาทต้องเพิ่มผ่าน endpoint ของตัวเองเพื่อให้ผ่านการตรวจสอบ tenant
+	group.Members = nil
+	group.Roles = nil
+
+	// ผู้ดูแล tenant สร้างได้เฉพาะกลุ่มของ tenant ตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil {
+		group.OrganizationID = tenantID
+	} else if group.OrganizationID != nil {
+		var organization models.Organization
+		if result := h.db.First(&organization, *group.OrganizationID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return
+		}
+	}
+
+	if group.ParentID != nil {
+		var parent models.Group
+		if result := h.db.Scopes(tenantGroups(c)).First(&parent, *group.ParentID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent group not found"})
+			return
+		}
+		if !models.SameOrganization(parent.OrganizationID, group.OrganizationID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent group belongs to another organization"})
+			return
+		}
+	}
+
+	// ตรวจสอบว่ามีชื่อกลุ่มซ้ำภายใน tenant เดียวกันหรือไม่
+	var existingGroup models.Group
+	if result := h.db.Scopes(inOrganization(group.OrganizationID)).Where("name = ?", group.Name).First(&existingGroup); result.RowsAffected > 0 {
+		respondAlreadyExists(c, "Group name already exists")
+		return
+	}
+
+	if result := h.db.Create(&group); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateGroup อัปเดตข้อมูลกลุ่ม รวมถึงการย้ายไปอยู่ใต้กลุ่มแม่อื่น
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ParentID    *uint  `json:"parent_id"`
+		// DetachParent ย้ายกลุ่มออกมาเป็นกลุ่มระดับบนสุด
+		DetachParent bool `json:"detach_parent"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// อัปเดตข้อมูลที่ไม่ใช่ค่าว่าง
+	updates := make(map[string]interface{})
+	if updateData.Name != "" {
+		if updateData.Name != group.Name {
+			var existingGroup models.Group
+			if result := h.db.Scopes(inOrganization(group.OrganizationID)).Where("name = ?", updateData.Name).First(&existingGroup); result.RowsAffected > 0 {
+				respondAlreadyExists(c, "Group name already exists")
+				return
+			}
+		}
+		updates["name"] = updateData.Name
+	}
+
+	if updateData.Description != "" {
+		updates["description"] = updateData.Description
+	}
+
+	if updateData.DetachParent {
+		updates["parent_id"] = nil
+	} else if updateData.ParentID != nil {
+		var parent models.Group
+		if result := h.db.Scopes(tenantGroups(c)).First(&parent, *updateData.ParentID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent group not found"})
+			return
+		}
+		if !models.SameOrganization(parent.OrganizationID, group.OrganizationID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parent group belongs to another organization"})
+			return
+		}
+
+		createsCycle, err := h.isDescendantOrSelf(parent.ID, group.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+			return
+		}
+		if createsCycle {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Group hierarchy cannot contain cycles"})
+			return
+		}
+		updates["parent_id"] = parent.ID
+	}
+
+	grantorID := c.GetUint("userID")
+	var memberIDs []uint
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		// การย้ายกลุ่มแม่เปลี่ยนบทบาทที่สมาชิกของกลุ่มและกลุ่มย่อยได้รับ
+		if _, moved := updates["parent_id"]; moved {
+			var err error
+			if memberIDs, err = service.GroupMemberIDs(tx, group.ID); err != nil {
+				return err
+			}
+		}
+
+		// การย้ายไปอยู่ใต้กลุ่มแม่ใหม่ทำให้สมาชิกได้รับบทบาทของกลุ่มแม่เพิ่ม
+		if parentID, ok := updates["parent_id"].(uint); ok {
+			roleIDs, err := service.GroupRoleIDs(tx, parentID)
+			if err != nil {
+				return err
+			}
+			for _, roleID := range roleIDs {
+				if err := service.CheckCanGrantRole(tx, grantorID, roleID); err != nil {
+					return err
+				}
+			}
+			if err := service.CheckSoD(tx, service.GrantToAll(memberIDs, roleIDs)); err != nil {
+				return err
+			}
+		}
+		return tx.Model(group).Updates(updates).Error
+	})
+	if err != nil {
+		if respondSoDViolation(c, err) {
+			return
+		}
+		respondGrantError(c, err, "Failed to update group")
+		return
+	}
+	h.cache.InvalidateUser(memberIDs...)
+
+	// ดึงข้อมูลกลุ่มที่อัปเดตแล้ว
+	h.db.Preload("Roles").First(group, group.ID)
+
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup ลบกลุ่ม กลุ่มย่อยจะถูกย้ายไปอยู่ใต้กลุ่มแม่ของกลุ่มที่ถูกลบ
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	var memberIDs []uint
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if memberIDs, err = service.GroupMemberIDs(tx, group.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Group{}).Where("parent_id = ?", group.ID).Update("parent_id", group.ParentID).Error; err != nil {
+			return err
+		}
+		// ลบความสัมพันธ์กับสมาชิกและบทบาทไปพร้อมกับกลุ่ม
+		if err := tx.Select(clause.Associations).Delete(group).Error; err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+	h.cache.InvalidateUser(memberIDs...)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+// AddMemberToGroup เพิ่มผู้ใช้เป็นสมาชิกของกลุ่ม
+func (h *GroupHandler) AddMemberToGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, requestData.UserID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// กลุ่มของ tenant รับได้เฉพาะสมาชิกจาก tenant เดียวกัน
+	if group.OrganizationID != nil && !models.SameOrganization(group.OrganizationID, user.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User belongs to another organization"})
+		return
+	}
+
+	// สมาชิกใหม่จะได้รับบทบาทของกลุ่มและกลุ่มแม่ทุกระดับ ผู้เรียกจึงต้องมอบบทบาทเหล่านั้นได้
+	// และต้องไม่ละเมิดกฎแบ่งแยกหน้าที่
+	grantorID := c.GetUint("userID")
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		roleIDs, err := service.GroupRoleIDs(tx, group.ID)
+		if err != nil {
+			return err
+		}
+		for _, roleID := range roleIDs {
+			if err := service.CheckCanGrantRole(tx, grantorID, roleID); err != nil {
+				return err
+			}
+		}
+		if err := service.CheckSoD(tx, map[uint][]uint{user.ID: roleIDs}); err != nil {
+			return err
+		}
+		return tx.Model(group).Association("Members").Append(&user)
+	})
+	if err != nil {
+		if respondSoDViolation(c, err) {
+			return
+		}
+		respondGrantError(c, err, "Failed to add member to group")
+		return
+	}
+	h.cache.InvalidateUser(user.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member added to group successfully"})
+}
+
+// RemoveMemberFromGroup ลบสมาชิกออกจากกลุ่ม
+func (h *GroupHandler) RemoveMemberFromGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้หลังลบสมาชิก
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Association("Members").Delete(&user); err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member from group"})
+		return
+	}
+	h.cache.InvalidateUser(user.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed from group successfully"})
+}
+
+// AddRoleToGroup กำหนดบทบาทให้กับกลุ่ม สมาชิกทุกคนจะได้รับบทบาทนี้
+func (h *GroupHandler) AddRoleToGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		RoleID uint `json:"role_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, requestData.RoleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	// บทบาทของ tenant กำหนดให้ได้เฉพาะกลุ่มใน tenant เดียวกัน
+	if !role.IsGlobal() && !models.SameOrganization(role.OrganizationID, group.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role belongs to another organization"})
+		return
+	}
+
+	// ผู้เรียกกำหนดได้เฉพาะบทบาทที่สิทธิ์ไม่เกินสิทธิ์ของตนเอง หรือบทบาทที่มอบต่อได้
+	if err := service.CheckCanGrantRole(h.db, c.GetUint("userID"), role.ID); err != nil {
+		respondGrantError(c, err, "Failed to add role to group")
+		return
+	}
+
+	// สมาชิกของกลุ่มและกลุ่มย่อยทุกคนจะได้รับบทบาทนี้ จึงต้องไม่ละเมิดกฎแบ่งแยกหน้าที่
+	var memberIDs []uint
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if memberIDs, err = service.GroupMemberIDs(tx, group.ID); err != nil {
+			return err
+		}
+		if err := service.CheckSoD(tx, service.GrantToAll(memberIDs, []uint{role.ID})); err != nil {
+			return err
+		}
+		return tx.Model(group).Association("Roles").Append(&role)
+	})
+	if err != nil {
+		if respondSoDViolation(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add role to group"})
+		return
+	}
+	h.cache.InvalidateUser(memberIDs...)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role added to group successfully"})
+}
+
+// RemoveRoleFromGroup ลบบทบาทออกจากกลุ่ม
+func (h *GroupHandler) RemoveRoleFromGroup(c *gin.Context) {
+	group, ok := h.findGroup(c)
+	if !ok {
+		return
+	}
+
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	// ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้หลังลบบทบาทออกจากกลุ่ม
+	var memberIDs []uint
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if memberIDs, err = service.GroupMemberIDs(tx, group.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Roles").Delete(&role); err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role from group"})
+		return
+	}
+	h.cache.InvalidateUser(memberIDs...)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role removed from group successfully"})
+}
+
+// findGroup ค้นหากลุ่มจาก path parameter ภายใน tenant ของผู้เรียก
+func (h *GroupHandler) findGroup(c *gin.Context) (*models.Group, bool) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return nil, false
+	}
+
+	var group models.Group
+	if result := h.db.Scopes(tenantGroups(c)).First(&group, groupID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return nil, false
+	}
+
+	return &group, true
+}
+
+// isDescendantOrSelf ตรวจสอบว่า candidateID คือ groupID เองหรือเป็นกลุ่มย่อยของ groupID
+// ใช้ป้องกันการตั้งกลุ่มแม่ที่ทำให้ลำดับชั้นเป็นวงจร
+func (h *GroupHandler) isDescendantOrSelf(candidateID uint, groupID uint) (bool, error) {
+	visited := make(map[uint]bool)
+	current := &candidateID
+	for current != nil {
+		if *current == groupID {
+			return true, nil
+		}
+		if visited[*current] {
+			return false, nil
+		}
+		visited[*current] = true
+
+		var group models.Group
+		if err := h.db.Select("id", "parent_id").First(&group, *current).Error; err != nil {
+			return false, err
+		}
+		current = group.ParentID
+	}
+	return false, nil
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type OrganizationHandler struct {
+	db *gorm.DB
+}
+
+func NewOrganizationHandler(db *gorm.DB) *OrganizationHandler {
+	return &OrganizationHandler{
+		db: db,
+	}
+}
+
+// GetOrganizations รับรายการ tenant ทั้งหมด (ผู้ดูแล tenant จะเห็นเฉพาะ tenant ของตนเอง)
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	query := h.db
+	if tenantID := currentTenantID(c); tenantID != nil {
+		query = query.Where("id = ?", *tenantID)
+	}
+
+	var organizations []models.Organization
+	if result := query.Find(&organizations); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, organizations)
+}
+
+// GetOrganization รับข้อมูล tenant ตาม ID
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// GetOrganizationUsers รับรายการสมาชิกของ tenant
+func (h *OrganizationHandler) GetOrganizationUsers(c *gin.Context) {
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	var users []models.User
+	if result := h.db.Preload("Roles").Where("organization_id = ?", organization.ID).Find(&users); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	usersResponse := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		usersResponse = append(usersResponse, user.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, usersResponse)
+}
+
+// CreateOrganization สร้าง tenant ใหม่ (เฉพาะผู้ดูแลระดับ global)
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage organizations"})
+		return
+	}
+
+	var organization models.Organization
+	if err := c.ShouldBindJSON(&organization); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ตรวจสอบว่ามีชื่อ tenant ซ้ำหรือไม่
+	var existingOrganization models.Organization
+	if result := h.db.Where("name = ?", organization.Name).First(&existingOrganization); result.RowsAffected > 0 {
+		respondAlreadyExists(c, "Organization name already exists")
+		return
+	}
+
+	if result := h.db.Create(&organization); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+// UpdateOrganization อัปเดตข้อมูล tenant (เฉพาะผู้ดูแลระดับ global)
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage organizations"})
+		return
+	}
+
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// อัปเดตข้อมูลที่ไม่ใช่ค่าว่าง
+	updates := make(map[string]interface{})
+	if updateData.Name != "" {
+		if updateData.Name != organization.Name {
+			var existingOrganization models.Organization
+			if result := h.db.Where("name = ?", updateData.Name).First(&existingOrganization); result.RowsAffected > 0 {
+				respondAlreadyExists(c, "Organization name already exists")
+				return
+			}
+		}
+		updates["name"] = updateData.Name
+	}
+
+	if updateData.Description != "" {
+		updates["description"] = updateData.Description
+	}
+
+	if result := h.db.Model(organization).Updates(updates); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
+
+	h.db.First(organization, organization.ID)
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// DeleteOrganization ลบ tenant ที่ไม่มีผู้ใช้และบทบาทเหลืออยู่ (เฉพาะผู้ดูแลระดับ global)
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage organizations"})
+		return
+	}
+
+	organization, ok := h.findOrganization(c)
+	if !ok {
+		return
+	}
+
+	var userCount, roleCount int64
+	h.db.Model(&models.User{}).Where("organization_id = ?", organization.ID).Count(&userCount)
+	h.db.Model(&models.Role{}).Where("organization_id = ?", organization.ID).Count(&roleCount)
+	if userCount > 0 || roleCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization still has users or roles"})
+		return
+	}
+
+	if result := h.db.Delete(organization); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+}
+
+// findOrganization ค้นหา tenant จาก path parameter และตรวจสอบว่าผู้เรียกมองเห็นได้
+func (h *OrganizationHandler) findOrganization(c *gin.Context) (*models.Organization, bool) {
+	organizationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, false
+	}
+
+	if tenantID := currentTenantID(c); tenantID != nil && uint(organizationID) != *tenantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+
+	var organization models.Organization
+	if result := h.db.First(&organization, organizationID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+
+	return &organization, true
+}
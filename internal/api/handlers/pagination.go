@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+	"gorm.io/gorm"
+)
+
+// paginate แบ่งหน้ารายการตาม query page และ per_page และตั้ง header X-Total-Count จากจำนวนที่ count นับได้
+// ถ้าไม่ได้ขอแบ่งหน้าจะคืน scope ที่ไม่จำกัดผลลัพธ์ (คืนทั้งหมดเหมือนเดิม)
+// คืนค่า false เมื่อตอบข้อผิดพลาดไปแล้ว
+func paginate(c *gin.Context, count *gorm.DB, orderBy string) (func(*gorm.DB) *gorm.DB, bool) {
+	pageParam, perPageParam := c.Query(adminapi.PageParam), c.Query(adminapi.PerPageParam)
+	if pageParam == "" && perPageParam == "" {
+		return func(db *gorm.DB) *gorm.DB { return db }, true
+	}
+
+	page, perPage := 1, adminapi.DefaultPerPage
+	var err error
+	if pageParam != "" {
+		if page, err = strconv.Atoi(pageParam); err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+			return nil, false
+		}
+	}
+	if perPageParam != "" {
+		if perPage, err = strconv.Atoi(perPageParam); err != nil || perPage < 1 || perPage > adminapi.MaxPerPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page"})
+			return nil, false
+		}
+	}
+
+	var total int64
+	if err := count.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count records"})
+		return nil, false
+	}
+	c.Header(adminapi.TotalCountHeader, strconv.FormatInt(total, 10))
+
+	// เรียงตาม ID เพื่อให้แต่ละหน้าไม่ซ้อนทับกัน
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(orderBy).Offset((page - 1) * perPage).Limit(perPage)
+	}, true
+}
@@ -1,28 +1,38 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/adminapi"
 	"gorm.io/gorm"
 )
 
 type PermissionHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache *service.PermissionCache
 }
 
-func NewPermissionHandler(db *gorm.DB) *PermissionHandler {
+func NewPermissionHandler(db *gorm.DB, cache *service.PermissionCache) *PermissionHandler {
 	return &PermissionHandler{
-		db: db,
+		db:    db,
+		cache: cache,
 	}
 }
 
-// GetPermissions รับรายการสิทธิ์ทั้งหมด
+// GetPermissions รับรายการสิทธิ์ทั้งหมด (แบ่งหน้าได้ด้วย page และ per_page)
 func (h *PermissionHandler) GetPermissions(c *gin.Context) {
+	page, ok := paginate(c, h.db.Model(&models.Permission{}), "permissions.id")
+	if !ok {
+		return
+	}
+
 	var permissions []models.Permission
-	result := h.db.Find(&permissions)
+	result := h.db.Scopes(page).Find(&permissions)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
 		return
@@ -52,22 +62,24 @@ func (h *PermissionHandler) GetPermission(c *gin.Context) {
 
 // CreatePermission สร้างสิทธิ์ใหม่
 func (h *PermissionHandler) CreatePermission(c *gin.Context) {
-	var permission models.Permission
-	if err := c.ShouldBindJSON(&permission); err != nil {
+	var request adminapi.CreatePermissionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// ตรวจสอบว่ามีสิทธิ์ซ้ำหรือไม่
-	var existingPermission models.Permission
-	if result := h.db.Where("resource = ? AND action = ?", permission.Resource, permission.Action).First(&existingPermission); result.RowsAffected > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Permission already exists"})
-		return
+	permission := models.Permission{
+		Resource:    request.Resource,
+		Action:      request.Action,
+		Description: request.Description,
 	}
 
-	// บันทึกสิทธิ์ใหม่
-	result := h.db.Create(&permission)
-	if result.Error != nil {
+	// บันทึกสิทธิ์ใหม่ สิทธิ์ซ้ำถูกป้องกันด้วย unique index ของ (resource, action)
+	if err := h.db.Create(&permission).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondAlreadyExists(c, "Permission already exists")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create permission"})
 		return
 	}
@@ -90,11 +102,7 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 		return
 	}
 
-	var updateData struct {
-		Resource    string `json:"resource"`
-		Action      string `json:"action"`
-		Description string `json:"description"`
-	}
+	var updateData adminapi.UpdatePermissionRequest
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -111,26 +119,12 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 		updates["action"] = updateData.Action
 	}
 
-	// ตรวจสอบว่ามีสิทธิ์ซ้ำหรือไม่หากมีการเปลี่ยนแปลง resource หรือ action
-	if (updateData.Resource != "" && updateData.Resource != permission.Resource) ||
-		(updateData.Action != "" && updateData.Action != permission.Action) {
-
-		resourceToCheck := permission.Resource
-		if updateData.Resource != "" {
-			resourceToCheck = updateData.Resource
-		}
-
-		actionToCheck := permission.Action
-		if updateData.Action != "" {
-			actionToCheck = updateData.Action
-		}
-
-		var existingPermission models.Permission
-		if result := h.db.Where("resource = ? AND action = ? AND id != ?", resourceToCheck, actionToCheck, permission.ID).
-			First(&existingPermission); result.RowsAffected > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Permission already exists"})
-			return
-		}
+	// สิทธิ์ system เปลี่ยน resource หรือ action ไม่ได้ ส่วนสิทธิ์ซ้ำถูกป้องกันด้วย unique index
+	if permission.System &&
+		((updateData.Resource != "" && updateData.Resource != permission.Resource) ||
+			(updateData.Action != "" && updateData.Action != permission.Action)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot rename system permission"})
+		return
 	}
 
 	if updateData.Description != "" {
@@ -138,10 +132,15 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 	}
 
 	// อัปเดตข้อมูล
-	if result := h.db.Model(&permission).Updates(updates); result.Error != nil {
+	if err := h.db.Model(&permission).Updates(updates).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			respondAlreadyExists(c, "Permission already exists")
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update permission"})
 		return
 	}
+	h.cache.InvalidatePermission(permission.ID)
 
 	// ดึงข้อมูลสิทธิ์ที่อัปเดตแล้ว
 	h.db.First(&permission, permissionID)
@@ -158,17 +157,35 @@ func (h *PermissionHandler) DeletePermission(c *gin.Context) {
 		return
 	}
 
-	// ลบสิทธิ์
-	result := h.db.Delete(&models.Permission{}, permissionID)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete permission"})
+	var permission models.Permission
+	if result := h.db.First(&permission, permissionID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
+	if permission.System {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete system permission"})
+		return
+	}
+
+	// ลบสิทธิ์ออกจากทุกบทบาทพร้อมกัน ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_permissions WHERE permission_id = ?", permission.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&permission).Error; err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete permission"})
 		return
 	}
+	h.cache.InvalidatePermission(permission.ID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Permission deleted successfully"})
 }
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/policy"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// maxPolicySize ขนาดสูงสุดของไฟล์ policy ที่รับ
+const maxPolicySize = 1 << 20
+
+type PolicyHandler struct {
+	db    *gorm.DB
+	cache *service.PermissionCache
+}
+
+func NewPolicyHandler(db *gorm.DB, cache *service.PermissionCache) *PolicyHandler {
+	return &PolicyHandler{
+		db:    db,
+		cache: cache,
+	}
+}
+
+// ExportPolicy ส่งออกสิทธิ์และบทบาทระดับ global ปัจจุบันในรูปแบบไฟล์ policy (YAML เป็นค่าเริ่มต้น หรือ ?format=json)
+func (h *PolicyHandler) ExportPolicy(c *gin.Context) {
+	current, err := policy.Export(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export policy"})
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, current)
+		return
+	}
+
+	data, err := current.YAML()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export policy"})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// ApplyPolicy ปรับสิทธิ์และบทบาทระดับ global ให้ตรงกับไฟล์ policy ใน body (YAML หรือ JSON)
+// ?dry_run=true คืนแผนการเปลี่ยนแปลงโดยไม่บันทึก (เฉพาะผู้ดูแลระดับ global)
+func (h *PolicyHandler) ApplyPolicy(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can apply policies"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPolicySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read policy"})
+		return
+	}
+	if len(data) > maxPolicySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Policy is too large"})
+		return
+	}
+
+	desired, err := policy.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID := c.GetUint("userID")
+	plan, err := policy.Apply(h.db, desired, policy.ApplyOptions{
+		ActorID: &actorID,
+		DryRun:  c.Query("dry_run") == "true",
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		var systemErr *policy.SystemRecordError
+		switch {
+		case errors.As(err, &systemErr):
+			c.JSON(http.StatusConflict, gin.H{"error": systemErr.Error(), "system_records": systemErr.Records})
+		case errors.Is(err, policy.ErrInvalidPolicy):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			respondGrantError(c, err, "Failed to apply policy")
+		}
+		return
+	}
+
+	// policy อาจแก้สิทธิ์ของบทบาท global ได้หลายบทบาทพร้อมกัน จึงล้างแคชทั้งหมด
+	if !plan.DryRun && len(plan.Changes) > 0 {
+		h.cache.InvalidateAll()
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
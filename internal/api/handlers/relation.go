@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/rebac"
+)
+
+// consistencyRequest ส่วนของคำขอที่ระบุ revision ที่จะอ่าน
+type consistencyRequest struct {
+	ConsistencyToken string `json:"consistency_token"`
+	Exact            bool   `json:"exact"` // true = อ่าน ณ revision ของ token พอดี แทนที่จะเป็น revision ล่าสุด
+}
+
+func (r consistencyRequest) consistency() rebac.Consistency {
+	return rebac.Consistency{Token: r.ConsistencyToken, Exact: r.Exact}
+}
+
+type RelationHandler struct {
+	engine *rebac.Engine
+}
+
+func NewRelationHandler(engine *rebac.Engine) *RelationHandler {
+	return &RelationHandler{
+		engine: engine,
+	}
+}
+
+// GetRelations อ่าน tuple ตามเงื่อนไข namespace, object_id และ relation
+func (h *RelationHandler) GetRelations(c *gin.Context) {
+	filter := rebac.Filter{
+		Namespace: c.Query("namespace"),
+		ObjectID:  c.Query("object_id"),
+		Relation:  c.Query("relation"),
+	}
+	consistency := rebac.Consistency{Token: c.Query("consistency_token"), Exact: c.Query("exact") == "true"}
+
+	tuples, revision, err := h.engine.Read(filter, consistency)
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	values := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		values = append(values, tuple.String())
+	}
+	c.JSON(http.StatusOK, gin.H{"tuples": values, "consistency_token": revision.Token()})
+}
+
+// WriteRelations เพิ่มและลบ tuple ในครั้งเดียว (เฉพาะผู้ดูแลระดับ global) คืน consistency token ของการเขียน
+func (h *RelationHandler) WriteRelations(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can write relation tuples"})
+		return
+	}
+
+	var requestData struct {
+		Writes  []string `json:"writes"`
+		Deletes []string `json:"deletes"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(requestData.Writes) == 0 && len(requestData.Deletes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "writes or deletes is required"})
+		return
+	}
+
+	writes, err := parseTuples(requestData.Writes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deletes, err := parseTuples(requestData.Deletes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revision, err := h.engine.Write(writes, deletes)
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consistency_token": revision.Token()})
+}
+
+// CheckRelation ตรวจสอบว่า subject มี relation กับ object หรือไม่
+func (h *RelationHandler) CheckRelation(c *gin.Context) {
+	var requestData struct {
+		Object   string `json:"object" binding:"required"`
+		Relation string `json:"relation" binding:"required"`
+		Subject  string `json:"subject" binding:"required"`
+		consistencyRequest
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	object, err := rebac.ParseObject(requestData.Object)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subject, err := rebac.ParseSubject(requestData.Subject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowed, revision, err := h.engine.Check(object, requestData.Relation, subject, requestData.consistency())
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"allowed": allowed, "consistency_token": revision.Token()})
+}
+
+// ExpandRelation คืนต้นไม้ของ userset ที่มี relation กับ object
+func (h *RelationHandler) ExpandRelation(c *gin.Context) {
+	var requestData struct {
+		Object   string `json:"object" binding:"required"`
+		Relation string `json:"relation" binding:"required"`
+		consistencyRequest
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	object, err := rebac.ParseObject(requestData.Object)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tree, revision, err := h.engine.Expand(object, requestData.Relation, requestData.consistency())
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tree": tree, "consistency_token": revision.Token()})
+}
+
+// ListRelationObjects คืนวัตถุใน namespace ที่ subject มี relation ด้วย
+func (h *RelationHandler) ListRelationObjects(c *gin.Context) {
+	var requestData struct {
+		Namespace string `json:"namespace" binding:"required"`
+		Relation  string `json:"relation" binding:"required"`
+		Subject   string `json:"subject" binding:"required"`
+		consistencyRequest
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subject, err := rebac.ParseSubject(requestData.Subject)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objects, revision, err := h.engine.ListObjects(requestData.Namespace, requestData.Relation, subject, requestData.consistency())
+	if err != nil {
+		respondRelationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"objects": objects, "consistency_token": revision.Token()})
+}
+
+func parseTuples(values []string) ([]rebac.Tuple, error) {
+	tuples := make([]rebac.Tuple, 0, len(values))
+	for _, value := range values {
+		tuple, err := rebac.ParseTuple(value)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, nil
+}
+
+// respondRelationError ตอบ 400 สำหรับ tuple, namespace, relation หรือ token ที่ไม่ถูกต้อง และ 500 สำหรับกรณีอื่น
+func respondRelationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, rebac.ErrInvalidTuple),
+		errors.Is(err, rebac.ErrUnknownNamespace),
+		errors.Is(err, rebac.ErrUnknownRelation),
+		errors.Is(err, rebac.ErrInvalidConsistencyToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate relations"})
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/rebac"
+)
+
+// setupRelationTest สร้าง handler บน MemoryStore: folder ที่ owner เป็น viewer ด้วย และสมาชิก team
+// tenantID ไม่เป็น nil จำลองผู้ดูแลระดับ tenant
+func setupRelationTest(t *testing.T, tenantID *uint) *gin.Engine {
+	engine, err := rebac.NewEngine(rebac.NewMemoryStore(), []rebac.Namespace{
+		{Name: "team", Relations: []rebac.Relation{{Name: "member"}}},
+		{Name: "folder", Relations: []rebac.Relation{
+			{Name: "owner"},
+			{Name: "viewer", Rewrite: &rebac.Rewrite{Union: []rebac.Rewrite{
+				{This: true},
+				{ComputedUserset: "owner"},
+			}}},
+		}},
+	})
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	h := NewRelationHandler(engine)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenantID", tenantID)
+		c.Next()
+	})
+	r.GET("/relations", h.GetRelations)
+	r.POST("/relations/write", h.WriteRelations)
+	r.POST("/relations/check", h.CheckRelation)
+	r.POST("/relations/expand", h.ExpandRelation)
+	r.POST("/relations/list-objects", h.ListRelationObjects)
+	return r
+}
+
+func decodeJSON(t *testing.T, body []byte) map[string]interface{} {
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+func TestRelations_WriteCheckAndRead(t *testing.T) {
+	r := setupRelationTest(t, nil)
+
+	w := performJSON(r, http.MethodPost, "/relations/write", gin.H{
+		"writes": []string{"folder:4#owner@user:1", "folder:4#viewer@team:9#member", "team:9#member@user:2"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	firstToken := decodeJSON(t, w.Body.Bytes())["consistency_token"]
+	assert.Equal(t, "1", firstToken)
+
+	// viewer ได้ทั้งจาก owner และจากสมาชิกของ team ที่เป็น viewer
+	for _, subject := range []string{"user:1", "user:2"} {
+		w = performJSON(r, http.MethodPost, "/relations/check", gin.H{"object": "folder:4", "relation": "viewer", "subject": subject})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, decodeJSON(t, w.Body.Bytes())["allowed"], subject)
+	}
+
+	w = performJSON(r, http.MethodPost, "/relations/write", gin.H{"deletes": []string{"team:9#member@user:2"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", decodeJSON(t, w.Body.Bytes())["consistency_token"])
+
+	w = performJSON(r, http.MethodPost, "/relations/check", gin.H{"object": "folder:4", "relation": "viewer", "subject": "user:2"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, false, decodeJSON(t, w.Body.Bytes())["allowed"])
+
+	// อ่าน ณ token ของการเขียนครั้งแรกพอดี ยังเห็นสิทธิ์ก่อนถูกลบ
+	w = performJSON(r, http.MethodPost, "/relations/check", gin.H{"object": "folder:4", "relation": "viewer", "subject": "user:2", "consistency_token": firstToken, "exact": true})
+	require.Equal(t, http.StatusOK, w.Code)
+	response := decodeJSON(t, w.Body.Bytes())
+	assert.Equal(t, true, response["allowed"])
+	assert.Equal(t, firstToken, response["consistency_token"])
+
+	w = performJSON(r, http.MethodGet, "/relations?namespace=team&consistency_token=1&exact=true", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{"team:9#member@user:2"}, decodeJSON(t, w.Body.Bytes())["tuples"])
+
+	w = performJSON(r, http.MethodGet, "/relations?namespace=team", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{}, decodeJSON(t, w.Body.Bytes())["tuples"])
+}
+
+func TestRelations_ExpandAndListObjects(t *testing.T) {
+	r := setupRelationTest(t, nil)
+
+	w := performJSON(r, http.MethodPost, "/relations/write", gin.H{
+		"writes": []string{"folder:4#owner@user:1", "folder:5#viewer@user:1", "folder:6#owner@user:2"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = performJSON(r, http.MethodPost, "/relations/list-objects", gin.H{"namespace": "folder", "relation": "viewer", "subject": "user:1"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []interface{}{"4", "5"}, decodeJSON(t, w.Body.Bytes())["objects"])
+
+	w = performJSON(r, http.MethodPost, "/relations/expand", gin.H{"object": "folder:4", "relation": "viewer"})
+	require.Equal(t, http.StatusOK, w.Code)
+	tree := decodeJSON(t, w.Body.Bytes())["tree"].(map[string]interface{})
+	assert.Equal(t, "union", tree["operation"])
+	assert.Len(t, tree["children"], 2)
+}
+
+func TestRelations_Validation(t *testing.T) {
+	r := setupRelationTest(t, nil)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"empty write", http.MethodPost, "/relations/write", gin.H{}},
+		{"malformed tuple", http.MethodPost, "/relations/write", gin.H{"writes": []string{"folder:4#owner"}}},
+		{"unknown namespace", http.MethodPost, "/relations/write", gin.H{"writes": []string{"project:1#owner@user:1"}}},
+		{"unknown relation", http.MethodPost, "/relations/write", gin.H{"writes": []string{"folder:1#editor@user:1"}}},
+		{"missing subject", http.MethodPost, "/relations/check", gin.H{"object": "folder:4", "relation": "viewer"}},
+		{"malformed object", http.MethodPost, "/relations/check", gin.H{"object": "folder", "relation": "viewer", "subject": "user:1"}},
+		{"malformed token", http.MethodPost, "/relations/check", gin.H{"object": "folder:4", "relation": "viewer", "subject": "user:1", "consistency_token": "abc"}},
+		{"token from the future", http.MethodGet, "/relations?namespace=folder&consistency_token=99", nil},
+		{"expand unknown relation", http.MethodPost, "/relations/expand", gin.H{"object": "folder:4", "relation": "editor"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := performJSON(r, tc.method, tc.path, tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestRelations_TenantAdminCannotWrite(t *testing.T) {
+	tenantID := uint(3)
+	r := setupRelationTest(t, &tenantID)
+
+	w := performJSON(r, http.MethodPost, "/relations/write", gin.H{"writes": []string{"folder:4#owner@user:1"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -1,28 +1,38 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/adminapi"
 	"gorm.io/gorm"
 )
 
 type RoleHandler struct {
-	db *gorm.DB
+	db    *gorm.DB
+	cache *service.PermissionCache
 }
 
-func NewRoleHandler(db *gorm.DB) *RoleHandler {
+func NewRoleHandler(db *gorm.DB, cache *service.PermissionCache) *RoleHandler {
 	return &RoleHandler{
-		db: db,
+		db:    db,
+		cache: cache,
 	}
 }
 
-// GetRoles รับรายการบทบาททั้งหมด
+// GetRoles รับรายการบทบาททั้งหมด (แบ่งหน้าได้ด้วย page และ per_page)
 func (h *RoleHandler) GetRoles(c *gin.Context) {
+	page, ok := paginate(c, h.db.Model(&models.Role{}).Scopes(tenantRoles(c)), "roles.id")
+	if !ok {
+		return
+	}
+
 	var roles []models.Role
-	result := h.db.Preload("Permissions").Find(&roles)
+	result := h.db.Scopes(tenantRoles(c), page).Preload("Permissions").Find(&roles)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
 		return
@@ -41,7 +51,7 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	result := h.db.Preload("Permissions").First(&role, roleID)
+	result := h.db.Scopes(tenantRoles(c)).Preload("Permissions").First(&role, roleID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
@@ -52,21 +62,59 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 
 // CreateRole สร้างบทบาทใหม่
 func (h *RoleHandler) CreateRole(c *gin.Context) {
-	var role models.Role
-	if err := c.ShouldBindJSON(&role); err != nil {
+	var request adminapi.CreateRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// ตรวจสอบว่ามีชื่อบทบาทซ้ำหรือไม่
+	// delegable_by กำหนดผ่าน /roles/:id/delegations เท่านั้น และบทบาทระบบสร้างผ่าน API ไม่ได้
+	role := models.Role{
+		Name:           request.Name,
+		Description:    request.Description,
+		OrganizationID: request.OrganizationID,
+	}
+
+	// ผู้ดูแล tenant สร้างได้เฉพาะบทบาทของ tenant ตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil {
+		role.OrganizationID = tenantID
+	} else if role.OrganizationID != nil {
+		var organization models.Organization
+		if result := h.db.First(&organization, *role.OrganizationID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return
+		}
+	}
+
+	// ตรวจสอบว่ามีชื่อบทบาทซ้ำหรือไม่ (ชื่อต้องไม่ซ้ำภายใน tenant เดียวกัน)
 	var existingRole models.Role
-	if result := h.db.Where("name = ?", role.Name).First(&existingRole); result.RowsAffected > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name already exists"})
+	if result := h.db.Scopes(inOrganization(role.OrganizationID)).Where("name = ?", role.Name).First(&existingRole); result.RowsAffected > 0 {
+		respondAlreadyExists(c, "Role name already exists")
 		return
 	}
 
+	// สิทธิ์ที่แนบมากับบทบาทใหม่ต้องเป็นสิทธิ์ที่ผู้สร้างมีอยู่แล้ว
+	if len(request.Permissions) > 0 {
+		permissionIDs := make([]uint, 0, len(request.Permissions))
+		for _, perm := range request.Permissions {
+			permissionIDs = append(permissionIDs, perm.ID)
+		}
+
+		var permissions []models.Permission
+		if result := h.db.Where("id IN ?", permissionIDs).Find(&permissions); result.Error != nil || len(permissions) != len(uniqueIDs(permissionIDs)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more permissions not found"})
+			return
+		}
+
+		if err := service.CheckCanGrantPermissions(h.db, c.GetUint("userID"), permissions); err != nil {
+			respondGrantError(c, err, "Failed to create role")
+			return
+		}
+		role.Permissions = permissions
+	}
+
 	// บันทึกบทบาทใหม่
-	result := h.db.Create(&role)
+	result := h.db.Omit("Permissions.*").Create(&role)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
 		return
@@ -85,16 +133,18 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	if result := h.db.First(&role, roleID); result.Error != nil {
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
-	var updateData struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
 	}
 
+	var updateData adminapi.UpdateRoleRequest
+
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -105,9 +155,14 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	if updateData.Name != "" {
 		// ตรวจสอบว่ามีชื่อบทบาทซ้ำหรือไม่
 		if updateData.Name != role.Name {
+			if role.System {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Cannot rename system role"})
+				return
+			}
+
 			var existingRole models.Role
-			if result := h.db.Where("name = ?", updateData.Name).First(&existingRole); result.RowsAffected > 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Role name already exists"})
+			if result := h.db.Scopes(inOrganization(role.OrganizationID)).Where("name = ?", updateData.Name).First(&existingRole); result.RowsAffected > 0 {
+				respondAlreadyExists(c, "Role name already exists")
 				return
 			}
 		}
@@ -123,6 +178,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
 		return
 	}
+	h.cache.InvalidateRole(role.ID)
 
 	// ดึงข้อมูลบทบาทที่อัปเดตแล้ว
 	h.db.Preload("Permissions").First(&role, roleID)
@@ -139,17 +195,40 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	// ลบบทบาท
-	result := h.db.Delete(&models.Role{}, roleID)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
+	}
+
+	if role.System {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete system role"})
+		return
+	}
+
+	// ลบบทบาทพร้อมข้อมูลที่อ้างถึง ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้
+	var holderIDs []uint
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if holderIDs, err = service.DeleteRole(tx, &role); err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
 		return
 	}
+	h.cache.InvalidateRole(role.ID)
+	h.cache.InvalidateUser(holderIDs...)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
 }
@@ -162,9 +241,7 @@ func (h *RoleHandler) AddPermissionToRole(c *gin.Context) {
 		return
 	}
 
-	var requestData struct {
-		PermissionID uint `json:"permission_id" binding:"required"`
-	}
+	var requestData adminapi.AddRolePermissionRequest
 
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -172,22 +249,34 @@ func (h *RoleHandler) AddPermissionToRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	if result := h.db.First(&role, roleID); result.Error != nil {
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
+	}
+
 	var permission models.Permission
 	if result := h.db.First(&permission, requestData.PermissionID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
 		return
 	}
 
+	// ผู้เรียกเพิ่มได้เฉพาะสิทธิ์ที่ตนเองมีอยู่
+	if err := service.CheckCanGrantPermissions(h.db, c.GetUint("userID"), []models.Permission{permission}); err != nil {
+		respondGrantError(c, err, "Failed to add permission to role")
+		return
+	}
+
 	// เพิ่มสิทธิ์ให้กับบทบาท
 	if err := h.db.Model(&role).Association("Permissions").Append(&permission); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add permission to role"})
 		return
 	}
+	h.cache.InvalidateRole(role.ID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Permission added to role successfully"})
 }
@@ -207,22 +296,149 @@ func (h *RoleHandler) RemovePermissionFromRole(c *gin.Context) {
 	}
 
 	var role models.Role
-	if result := h.db.First(&role, roleID); result.Error != nil {
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
+	}
+
 	var permission models.Permission
 	if result := h.db.First(&permission, permissionID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
 		return
 	}
 
-	// ลบสิทธิ์ออกจากบทบาท
-	if err := h.db.Model(&role).Association("Permissions").Delete(&permission); err != nil {
+	// ลบสิทธิ์ออกจากบทบาท ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Association("Permissions").Delete(&permission); err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove permission from role"})
 		return
 	}
+	h.cache.InvalidateRole(role.ID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Permission removed from role successfully"})
 }
+
+// GetRoleDelegations รับรายการบทบาทที่ผู้ถือสามารถมอบบทบาทนี้ต่อได้
+func (h *RoleHandler) GetRoleDelegations(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).Preload("DelegableBy").First(&role, roleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, role.DelegableBy)
+}
+
+// AddRoleDelegation อนุญาตให้ผู้ถือบทบาท grantor_role_id มอบบทบาทนี้ต่อได้
+// ผู้เรียกต้องมอบบทบาทนี้ได้เองก่อน มิฉะนั้นจะใช้การมอบหมายเป็นช่องทางยกระดับสิทธิ์
+func (h *RoleHandler) AddRoleDelegation(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	var requestData struct {
+		GrantorRoleID uint `json:"grantor_role_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
+	}
+
+	var grantorRole models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&grantorRole, requestData.GrantorRoleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Grantor role not found"})
+		return
+	}
+
+	if err := service.CheckCanGrantRole(h.db, c.GetUint("userID"), role.ID); err != nil {
+		respondGrantError(c, err, "Failed to add role delegation")
+		return
+	}
+
+	if err := h.db.Model(&role).Association("DelegableBy").Append(&grantorRole); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add role delegation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role delegation added successfully"})
+}
+
+// RemoveRoleDelegation ยกเลิกการอนุญาตให้ผู้ถือบทบาท grantorRoleId มอบบทบาทนี้ต่อ
+func (h *RoleHandler) RemoveRoleDelegation(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return
+	}
+
+	grantorRoleID, err := strconv.ParseUint(c.Param("grantorRoleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid grantor role ID"})
+		return
+	}
+
+	var role models.Role
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if !canManageRole(c, role.OrganizationID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot modify global role"})
+		return
+	}
+
+	if err := h.db.Model(&role).Association("DelegableBy").Delete(&models.Role{ID: uint(grantorRoleID)}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role delegation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role delegation removed successfully"})
+}
+
+// respondGrantError ตอบ 403 พร้อมรายการสิทธิ์ที่ขาด ถ้าผู้เรียกให้สิทธิ์เกินกว่าที่ตนเองมี
+func respondGrantError(c *gin.Context, err error, fallback string) {
+	var delegationErr *service.DelegationError
+	if errors.As(err, &delegationErr) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":               delegationErr.Error(),
+			"missing_permissions": delegationErr.MissingPermissions,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
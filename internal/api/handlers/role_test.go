@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+func TestDeleteRole_ClearsGroupBindingsAndInvalidatesMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock := setupMockDB(t)
+
+	var invalidatedUsers []uint
+	cache := service.NewPermissionCache(0)
+	cache.OnInvalidate(func(scope string, ids []uint) {
+		if scope == service.InvalidateScopeUser {
+			invalidatedUsers = append(invalidatedUsers, ids...)
+		}
+	})
+	h := NewRoleHandler(db, cache)
+	r := gin.New()
+	r.DELETE("/roles/:id", h.DeleteRole)
+
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+		WithArgs(4, 1).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(4, "auditor", "", nil, false))
+
+	// บทบาทถูกผูกกับกลุ่ม 10 (มีกลุ่มย่อย 11) และผู้ใช้ 5 โดยตรง
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT "user_id" FROM "user_roles" WHERE role_id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT "group_id" FROM "group_roles" WHERE role_id = \$1`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(10))
+	mock.ExpectQuery(`SELECT "id" FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	mock.ExpectQuery(`SELECT "id" FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT "user_id" FROM "group_members" WHERE group_id IN \(\$1,\$2\)`).
+		WithArgs(10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(6).AddRow(7))
+	mock.ExpectExec(`DELETE FROM "user_roles" WHERE role_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "group_roles" WHERE role_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "sod_rule_roles" WHERE role_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE "access_requests" SET "decided_at"=\$1,"decision_note"=\$2,"status"=\$3,"updated_at"=\$4 WHERE role_id = \$5 AND status = \$6`).
+		WithArgs(sqlmock.AnyArg(), "role deleted", models.AccessRequestDenied, sqlmock.AnyArg(), 4, models.AccessRequestPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM role_delegations WHERE role_id = \$1 OR grantor_role_id = \$2`).WithArgs(4, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM "roles" WHERE "roles"\."id" = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	w := performJSON(r, http.MethodDelete, "/roles/4", nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []uint{5, 6, 7}, invalidatedUsers)
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+// respondLastRoleManager ตอบ 409 ถ้าการดำเนินการจะทำให้ไม่เหลือผู้ใช้ที่จัดการบทบาทได้
+func respondLastRoleManager(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrLastRoleManager) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	return true
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type ServiceAccountHandler struct {
+	db                    *gorm.DB
+	serviceAccountService *service.ServiceAccountService
+}
+
+func NewServiceAccountHandler(db *gorm.DB, serviceAccountService *service.ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		db:                    db,
+		serviceAccountService: serviceAccountService,
+	}
+}
+
+// GetServiceAccounts รับรายการบัญชีบริการทั้งหมด
+func (h *ServiceAccountHandler) GetServiceAccounts(c *gin.Context) {
+	var accounts []models.ServiceAccount
+	if result := h.db.Find(&accounts); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// CreateServiceAccount สร้างบัญชีบริการ (เฉพาะผู้ดูแลระดับ global) client_secret จะแสดงในคำตอบนี้ครั้งเดียวเท่านั้น
+func (h *ServiceAccountHandler) CreateServiceAccount(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage service accounts"})
+		return
+	}
+
+	var requestData struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existingAccount models.ServiceAccount
+	if result := h.db.Where("name = ?", requestData.Name).Limit(1).Find(&existingAccount); result.RowsAffected > 0 {
+		respondAlreadyExists(c, "Service account name already exists")
+		return
+	}
+
+	account, secret, err := h.serviceAccountService.Create(requestData.Name, requestData.Description)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"service_account": account, "client_secret": secret})
+}
+
+// DeleteServiceAccount ลบบัญชีบริการ (เฉพาะผู้ดูแลระดับ global)
+func (h *ServiceAccountHandler) DeleteServiceAccount(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage service accounts"})
+		return
+	}
+
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service account ID"})
+		return
+	}
+
+	result := h.db.Delete(&models.ServiceAccount{}, accountID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service account"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service account deleted successfully"})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// setupSimulateTest tenantID ไม่เป็น nil จำลองผู้ดูแลระดับ tenant
+func setupSimulateTest(db *gorm.DB, tenantID *uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthzHandler(db, &mockAuthService{}, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenantID", tenantID)
+		c.Next()
+	})
+	r.POST("/authz/simulate", h.Simulate)
+	return r
+}
+
+var roleColumns = []string{"id", "name", "description", "organization_id", "system"}
+
+func TestAuthzSimulate_Validation(t *testing.T) {
+	db, _ := setupMockDB(t)
+	r := setupSimulateTest(db, nil)
+
+	tooMany := make([]gin.H, maxSimulatedChanges+1)
+	for i := range tooMany {
+		tooMany[i] = gin.H{"type": service.SimulateDeleteRole, "role_id": 1}
+	}
+
+	// ทุกกรณีถูกปฏิเสธก่อนถึงฐานข้อมูล
+	cases := []struct {
+		name string
+		body gin.H
+	}{
+		{"no changes", gin.H{"changes": []gin.H{}}},
+		{"missing type", gin.H{"changes": []gin.H{{"role_id": 1}}}},
+		{"missing role", gin.H{"changes": []gin.H{{"type": service.SimulateDeleteRole}}}},
+		{"too many changes", gin.H{"changes": tooMany}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := performJSON(r, http.MethodPost, "/authz/simulate", tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestAuthzSimulate_Scope(t *testing.T) {
+	tenantID := uint(3)
+
+	t.Run("unknown role", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		r := setupSimulateTest(db, nil)
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+			WithArgs(9, 1).
+			WillReturnRows(sqlmock.NewRows(roleColumns))
+
+		w := performJSON(r, http.MethodPost, "/authz/simulate", gin.H{"changes": []gin.H{{"type": service.SimulateDeleteRole, "role_id": 9}}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("tenant administrator changes global role", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		r := setupSimulateTest(db, &tenantID)
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1 AND \(roles.organization_id IS NULL OR roles.organization_id = \$2\)`).
+			WithArgs(2, tenantID, 1).
+			WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(2, "editor", "", nil, false))
+
+		w := performJSON(r, http.MethodPost, "/authz/simulate", gin.H{"changes": []gin.H{{"type": service.SimulateAddPermission, "role_id": 2, "permission": "users:write"}}})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("user outside tenant", func(t *testing.T) {
+		db, mock := setupMockDB(t)
+		r := setupSimulateTest(db, &tenantID)
+		mock.ExpectQuery(`SELECT \* FROM "roles"`).
+			WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(2, "editor", "", nil, false))
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1 AND users.organization_id = \$2`).
+			WithArgs(5, tenantID, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		w := performJSON(r, http.MethodPost, "/authz/simulate", gin.H{"changes": []gin.H{{"type": service.SimulateAssignRole, "role_id": 2, "user_id": 5}}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAuthzSimulate_Diff(t *testing.T) {
+	db, mock := setupMockDB(t)
+	r := setupSimulateTest(db, nil)
+
+	// ผู้ใช้ 5 ถือ viewer (2) ซึ่งมี reports:read อยู่แล้ว จำลองการกำหนด analyst (3) ที่มี reports:read และ reports:export
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(3, "analyst", "", nil, false))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(5, "bob"))
+	mock.ExpectQuery(`SELECT "id" FROM "roles" WHERE "roles"."id" = \$1`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(`SELECT "id" FROM "users" WHERE "users"."id" = \$1`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectQuery(`SELECT id, username, organization_id FROM "users" WHERE id IN \(\$1\) ORDER BY id`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "organization_id"}).AddRow(5, "bob", nil))
+	mock.ExpectQuery(`SELECT "role_id" FROM "user_roles" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(2))
+	mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE id IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows(roleColumns).
+			AddRow(2, "viewer", "", nil, false).
+			AddRow(3, "analyst", "", nil, false))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"."role_id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).
+			AddRow(2, 10).
+			AddRow(3, 10).
+			AddRow(3, 11))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"."id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).
+			AddRow(10, "reports", "read").
+			AddRow(11, "reports", "export"))
+
+	w := performJSON(r, http.MethodPost, "/authz/simulate", gin.H{"changes": []gin.H{{"type": service.SimulateAssignRole, "role_id": 3, "user_id": 5}}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result service.SimulationResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, []service.AccessDelta{{
+		UserID:   5,
+		Username: "bob",
+		Gained:   []string{"reports:export"},
+		Lost:     []string{},
+	}}, result.Affected)
+}
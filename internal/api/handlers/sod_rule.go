@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+type SoDRuleHandler struct {
+	db *gorm.DB
+}
+
+func NewSoDRuleHandler(db *gorm.DB) *SoDRuleHandler {
+	return &SoDRuleHandler{
+		db: db,
+	}
+}
+
+// GetSoDRules รับรายการกฎแบ่งแยกหน้าที่ทั้งหมด
+func (h *SoDRuleHandler) GetSoDRules(c *gin.Context) {
+	var rules []models.SoDRule
+	if result := h.db.Preload("Roles").Find(&rules); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch separation of duties rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetSoDRule รับกฎแบ่งแยกหน้าที่ตาม ID
+func (h *SoDRuleHandler) GetSoDRule(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateSoDRule สร้างกฎแบ่งแยกหน้าที่ใหม่ (เฉพาะผู้ดูแลระดับ global)
+// กฎจะมีผลกับการกำหนดบทบาทครั้งต่อไป ผู้ที่ละเมิดอยู่แล้วดูได้จาก GetSoDRuleViolations
+func (h *SoDRuleHandler) CreateSoDRule(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage separation of duties rules"})
+		return
+	}
+
+	var requestData struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		Kind        string `json:"kind" binding:"required"`
+		MaxAllowed  *int   `json:"max_allowed"`
+		RoleIDs     []uint `json:"role_ids" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.SoDRule{
+		Name:        requestData.Name,
+		Description: requestData.Description,
+		Kind:        requestData.Kind,
+		MaxAllowed:  1,
+	}
+	if requestData.MaxAllowed != nil {
+		rule.MaxAllowed = *requestData.MaxAllowed
+	}
+
+	roles, ok := h.validateRule(c, &rule, requestData.RoleIDs)
+	if !ok {
+		return
+	}
+
+	var existingRule models.SoDRule
+	if result := h.db.Where("name = ?", rule.Name).Limit(1).Find(&existingRule); result.RowsAffected > 0 {
+		respondAlreadyExists(c, "Rule name already exists")
+		return
+	}
+
+	rule.Roles = roles
+	if result := h.db.Create(&rule); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create separation of duties rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateSoDRule อัปเดตกฎแบ่งแยกหน้าที่ (เฉพาะผู้ดูแลระดับ global)
+func (h *SoDRuleHandler) UpdateSoDRule(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage separation of duties rules"})
+		return
+	}
+
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		MaxAllowed  *int   `json:"max_allowed"`
+		RoleIDs     []uint `json:"role_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updateData.Name != "" && updateData.Name != rule.Name {
+		var existingRule models.SoDRule
+		if result := h.db.Where("name = ?", updateData.Name).Limit(1).Find(&existingRule); result.RowsAffected > 0 {
+			respondAlreadyExists(c, "Rule name already exists")
+			return
+		}
+		rule.Name = updateData.Name
+	}
+	if updateData.Description != "" {
+		rule.Description = updateData.Description
+	}
+	if updateData.MaxAllowed != nil {
+		rule.MaxAllowed = *updateData.MaxAllowed
+	}
+
+	roleIDs := rule.RoleIDs()
+	if updateData.RoleIDs != nil {
+		roleIDs = updateData.RoleIDs
+	}
+
+	roles, ok := h.validateRule(c, rule, roleIDs)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(rule).Updates(map[string]interface{}{
+			"name":        rule.Name,
+			"description": rule.Description,
+			"max_allowed": rule.MaxAllowed,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(rule).Association("Roles").Replace(roles)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update separation of duties rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteSoDRule ลบกฎแบ่งแยกหน้าที่ (เฉพาะผู้ดูแลระดับ global)
+func (h *SoDRuleHandler) DeleteSoDRule(c *gin.Context) {
+	if currentTenantID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only global administrators can manage separation of duties rules"})
+		return
+	}
+
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Select("Roles").Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete separation of duties rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Separation of duties rule deleted successfully"})
+}
+
+// GetSoDRuleViolations รายงานผู้ใช้หรือบทบาทที่ละเมิดกฎนี้อยู่แล้ว
+// ใช้ตรวจสอบหลังเพิ่มกฎใหม่ เพราะกฎไม่ถอนบทบาทที่กำหนดไปก่อนหน้าโดยอัตโนมัติ
+func (h *SoDRuleHandler) GetSoDRuleViolations(c *gin.Context) {
+	rule, ok := h.findRule(c)
+	if !ok {
+		return
+	}
+
+	violations, err := service.FindSoDViolations(h.db, *rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate separation of duties rule"})
+		return
+	}
+
+	// ผู้ดูแล tenant เห็นเฉพาะผู้ใช้ใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil {
+		violations, err = h.tenantViolations(c, violations)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evaluate separation of duties rule"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule, "violations": violations})
+}
+
+// validateRule ตรวจสอบชนิดของกฎ ค่าจำกัด และบทบาทในกฎ แล้วคืนบทบาทที่โหลดมา
+func (h *SoDRuleHandler) validateRule(c *gin.Context, rule *models.SoDRule, roleIDs []uint) ([]models.Role, bool) {
+	switch rule.Kind {
+	case models.SoDMutuallyExclusive:
+		if len(roleIDs) < 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mutually exclusive rules need at least two roles"})
+			return nil, false
+		}
+		if rule.MaxAllowed < 1 || rule.MaxAllowed >= len(roleIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_allowed must be at least 1 and less than the number of roles"})
+			return nil, false
+		}
+	case models.SoDMaxCardinality:
+		if len(roleIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cardinality rules need at least one role"})
+			return nil, false
+		}
+		if rule.MaxAllowed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_allowed must be at least 1"})
+			return nil, false
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be mutually_exclusive or max_cardinality"})
+		return nil, false
+	}
+
+	var roles []models.Role
+	if result := h.db.Where("id IN ?", roleIDs).Find(&roles); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return nil, false
+	}
+	if len(roles) != len(uniqueIDs(roleIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more roles not found"})
+		return nil, false
+	}
+
+	return roles, true
+}
+
+// tenantViolations ตัดรายการละเมิดให้เหลือเฉพาะผู้ใช้ใน tenant ของผู้เรียก
+func (h *SoDRuleHandler) tenantViolations(c *gin.Context, violations []service.SoDViolation) ([]service.SoDViolation, error) {
+	var tenantUserIDs []uint
+	if err := h.db.Model(&models.User{}).Scopes(tenantUsers(c)).Pluck("id", &tenantUserIDs).Error; err != nil {
+		return nil, err
+	}
+	visible := make(map[uint]bool, len(tenantUserIDs))
+	for _, id := range tenantUserIDs {
+		visible[id] = true
+	}
+
+	filtered := []service.SoDViolation{}
+	for _, violation := range violations {
+		if violation.Kind == models.SoDMaxCardinality {
+			var userIDs []uint
+			for _, id := range violation.UserIDs {
+				if visible[id] {
+					userIDs = append(userIDs, id)
+				}
+			}
+			if len(userIDs) == 0 {
+				continue
+			}
+			violation.UserIDs = userIDs
+			filtered = append(filtered, violation)
+		} else if visible[violation.UserID] {
+			filtered = append(filtered, violation)
+		}
+	}
+	return filtered, nil
+}
+
+// findRule ค้นหากฎจาก path parameter
+func (h *SoDRuleHandler) findRule(c *gin.Context) (*models.SoDRule, bool) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return nil, false
+	}
+
+	var rule models.SoDRule
+	if result := h.db.Preload("Roles").First(&rule, ruleID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Separation of duties rule not found"})
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// respondSoDViolation ตอบ 409 พร้อมรายละเอียดถ้า err เป็นการละเมิดกฎแบ่งแยกหน้าที่
+func respondSoDViolation(c *gin.Context, err error) bool {
+	var violation *service.SoDViolationError
+	if !errors.As(err, &violation) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, gin.H{"error": violation.Error(), "violation": violation.Violation})
+	return true
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentTenantID คืนค่า tenant ของผู้เรียกที่ AuthMiddleware ตั้งไว้ (nil = ผู้ดูแลระดับ global)
+func currentTenantID(c *gin.Context) *uint {
+	value, exists := c.Get("tenantID")
+	if !exists {
+		return nil
+	}
+	tenantID, _ := value.(*uint)
+	return tenantID
+}
+
+// tenantUsers จำกัดการค้นหาผู้ใช้ให้อยู่ใน tenant ของผู้เรียก
+func tenantUsers(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	tenantID := currentTenantID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return db
+		}
+		return db.Where("users.organization_id = ?", *tenantID)
+	}
+}
+
+// tenantRoles จำกัดการค้นหาบทบาทให้เหลือเฉพาะบทบาท global และบทบาทของ tenant ผู้เรียก
+func tenantRoles(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	tenantID := currentTenantID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return db
+		}
+		return db.Where("roles.organization_id IS NULL OR roles.organization_id = ?", *tenantID)
+	}
+}
+
+// tenantGroups จำกัดการค้นหากลุ่มให้อยู่ใน tenant ของผู้เรียก (ผู้ดูแล tenant มองเห็นเฉพาะกลุ่มของตนเอง)
+func tenantGroups(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	tenantID := currentTenantID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if tenantID == nil {
+			return db
+		}
+		return db.Where("groups.organization_id = ?", *tenantID)
+	}
+}
+
+// inOrganization จำกัดการค้นหาให้อยู่ใน tenant ที่ระบุพอดี (nil = ข้อมูลระดับ global)
+// ใช้ตรวจสอบชื่อซ้ำของบทบาทและกลุ่มซึ่งต้องไม่ซ้ำภายใน tenant เดียวกัน
+func inOrganization(organizationID *uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if organizationID == nil {
+			return db.Where("organization_id IS NULL")
+		}
+		return db.Where("organization_id = ?", *organizationID)
+	}
+}
+
+// canManageRole ตรวจสอบว่าผู้เรียกแก้ไขบทบาทนี้ได้หรือไม่ (ผู้ดูแล tenant แก้ไขบทบาท global ไม่ได้)
+func canManageRole(c *gin.Context, roleOrgID *uint) bool {
+	tenantID := currentTenantID(c)
+	if tenantID == nil {
+		return true
+	}
+	return roleOrgID != nil && *roleOrgID == *tenantID
+}
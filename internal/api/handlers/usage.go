@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetUnusedPermissions รายงานสิทธิ์ของแต่ละบทบาทที่ไม่มีผู้ถือคนใดใช้ใน ?days= วันล่าสุด (ค่าเริ่มต้น 90)
+func (h *UsageHandler) GetUnusedPermissions(c *gin.Context) {
+	since, ok := reportSince(c, 90)
+	if !ok {
+		return
+	}
+
+	report, err := h.usageService.UnusedRolePermissions(since, currentTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build usage report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"since": since, "roles": report})
+}
+
+// GetOverPrivilegedUsers รายงานผู้ใช้ที่ไม่ได้ใช้สิทธิ์ที่มีผลอยู่อย่างน้อย ?min_unused_ratio= (ค่าเริ่มต้น 0.5) ใน ?days= วันล่าสุด
+func (h *UsageHandler) GetOverPrivilegedUsers(c *gin.Context) {
+	since, ok := reportSince(c, 90)
+	if !ok {
+		return
+	}
+
+	ratio, err := strconv.ParseFloat(c.DefaultQuery("min_unused_ratio", "0.5"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_unused_ratio must be between 0 and 1"})
+		return
+	}
+
+	report, err := h.usageService.OverPrivilegedUsers(since, ratio, currentTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build usage report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"since": since, "users": report})
+}
+
+// GetDenialTrends รายงานจำนวนการปฏิเสธรายวันของแต่ละสิทธิ์ใน ?days= วันล่าสุด (ค่าเริ่มต้น 30)
+func (h *UsageHandler) GetDenialTrends(c *gin.Context) {
+	since, ok := reportSince(c, 30)
+	if !ok {
+		return
+	}
+
+	report, err := h.usageService.DenialTrends(since, currentTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build usage report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"since": since, "permissions": report})
+}
+
+// reportSince อ่าน ?days= แล้วคืนวันเริ่มต้นของรายงาน (ตอบ 400 เองถ้าค่าไม่ถูกต้อง)
+func reportSince(c *gin.Context, defaultDays int) (time.Time, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultDays)))
+	if err != nil || days < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+		return time.Time{}, false
+	}
+	return time.Now().UTC().AddDate(0, 0, -days).Truncate(24 * time.Hour), true
+}
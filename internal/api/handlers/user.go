@@ -1,35 +1,49 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	db *gorm.DB
+	db                *gorm.DB
+	assignmentService *service.AssignmentService
+	cache             *service.PermissionCache
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
+// NewUserHandler การเพิ่ม/ถอนบทบาทผ่าน assignmentService ซึ่ง invalidate แคชสิทธิ์ผ่าน change hook เอง
+func NewUserHandler(db *gorm.DB, assignmentService *service.AssignmentService, cache *service.PermissionCache) *UserHandler {
 	return &UserHandler{
-		db: db,
+		db:                db,
+		assignmentService: assignmentService,
+		cache:             cache,
 	}
 }
 
-// GetUsers รับรายการผู้ใช้ทั้งหมด
+// GetUsers รับรายการผู้ใช้ทั้งหมด (แบ่งหน้าได้ด้วย page และ per_page)
 func (h *UserHandler) GetUsers(c *gin.Context) {
+	page, ok := paginate(c, h.db.Model(&models.User{}).Scopes(tenantUsers(c)), "users.id")
+	if !ok {
+		return
+	}
+
 	var users []models.User
-	result := h.db.Preload("Roles").Find(&users)
+	result := h.db.Scopes(tenantUsers(c), page).Preload("Roles").Find(&users)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
 	// แปลงเป็น response ที่ไม่มีข้อมูล sensitive
-	var usersResponse []map[string]interface{}
+	usersResponse := make([]map[string]interface{}, 0, len(users))
 	for _, user := range users {
 		usersResponse = append(usersResponse, user.ToResponse())
 	}
@@ -47,7 +61,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	}
 
 	var user models.User
-	result := h.db.Preload("Roles").First(&user, userID)
+	result := h.db.Scopes(tenantUsers(c)).Preload("Roles").First(&user, userID)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -58,16 +72,45 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // CreateUser สร้างผู้ใช้ใหม่
 func (h *UserHandler) CreateUser(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var request adminapi.CreateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// บทบาทกำหนดผ่าน /users/:id/roles เท่านั้น และผู้ใช้ระบบสร้างผ่าน API ไม่ได้
+	user := models.User{
+		Username:       request.Username,
+		Email:          request.Email,
+		FullName:       request.FullName,
+		OrganizationID: request.OrganizationID,
+	}
+
+	// เข้ารหัสที่นี่แทนการพึ่ง hook BeforeCreate ซึ่งข้ามค่าที่ยาวตั้งแต่ 60 ตัวอักษร (ถือว่าเป็น hash แล้ว)
+	if err := user.SetPassword(request.Password); err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password is too long"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	// ผู้ดูแล tenant สร้างผู้ใช้ได้เฉพาะใน tenant ของตนเอง
+	if tenantID := currentTenantID(c); tenantID != nil {
+		user.OrganizationID = tenantID
+	} else if user.OrganizationID != nil {
+		var organization models.Organization
+		if result := h.db.First(&organization, *user.OrganizationID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return
+		}
+	}
+
 	// ตรวจสอบว่ามี username หรือ email ซ้ำหรือไม่
 	var existingUser models.User
 	if result := h.db.Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser); result.RowsAffected > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username or email already exists"})
+		respondAlreadyExists(c, "Username or email already exists")
 		return
 	}
 
@@ -91,17 +134,12 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	var user models.User
-	if result := h.db.First(&user, userID); result.Error != nil {
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	var updateData struct {
-		Username string `json:"username"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
-		FullName string `json:"full_name"`
-	}
+	var updateData adminapi.UpdateUserRequest
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -113,9 +151,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if updateData.Username != "" {
 		// ตรวจสอบว่ามี username ซ้ำหรือไม่
 		if updateData.Username != user.Username {
+			if user.System {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Cannot rename system user"})
+				return
+			}
 			var existingUser models.User
 			if result := h.db.Where("username = ?", updateData.Username).First(&existingUser); result.RowsAffected > 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+				respondAlreadyExists(c, "Username already exists")
 				return
 			}
 		}
@@ -127,7 +169,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		if updateData.Email != user.Email {
 			var existingUser models.User
 			if result := h.db.Where("email = ?", updateData.Email).First(&existingUser); result.RowsAffected > 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+				respondAlreadyExists(c, "Email already exists")
 				return
 			}
 		}
@@ -147,11 +189,26 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		updates["full_name"] = updateData.FullName
 	}
 
+	// การย้ายผู้ใช้ไปยัง tenant อื่นทำได้เฉพาะผู้ดูแลระดับ global
+	if updateData.OrganizationID != nil && !models.SameOrganization(updateData.OrganizationID, user.OrganizationID) {
+		if currentTenantID(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Cannot move user to another organization"})
+			return
+		}
+		var organization models.Organization
+		if result := h.db.First(&organization, *updateData.OrganizationID); result.Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return
+		}
+		updates["organization_id"] = *updateData.OrganizationID
+	}
+
 	// อัปเดตข้อมูล
 	if result := h.db.Model(&user).Updates(updates); result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
+	h.cache.InvalidateUser(user.ID)
 
 	// ดึงข้อมูลผู้ใช้ที่อัปเดตแล้ว
 	h.db.Preload("Roles").First(&user, userID)
@@ -168,18 +225,39 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// ลบผู้ใช้
-	result := h.db.Delete(&models.User{}, userID)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	var user models.User
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if user.System {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete system user"})
 		return
 	}
 
+	// ลบผู้ใช้พร้อมบทบาทและการเป็นสมาชิกกลุ่ม ต้องยังเหลือผู้ใช้ที่จัดการบทบาทได้
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_members WHERE user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return service.EnsureRoleManagerRemains(tx)
+	})
+	if err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	h.cache.InvalidateUser(user.ID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
@@ -191,9 +269,7 @@ func (h *UserHandler) AddRoleToUser(c *gin.Context) {
 		return
 	}
 
-	var requestData struct {
-		RoleID uint `json:"role_id" binding:"required"`
-	}
+	var requestData adminapi.AssignRoleRequest
 
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -201,24 +277,52 @@ func (h *UserHandler) AddRoleToUser(c *gin.Context) {
 	}
 
 	var user models.User
-	if result := h.db.First(&user, userID); result.Error != nil {
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	var role models.Role
-	if result := h.db.First(&role, requestData.RoleID); result.Error != nil {
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, requestData.RoleID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
-	// เพิ่มบทบาทให้กับผู้ใช้
-	if err := h.db.Model(&user).Association("Roles").Append(&role); err != nil {
+	// บทบาทของ tenant กำหนดให้ได้เฉพาะผู้ใช้ใน tenant เดียวกัน
+	if !role.IsGlobal() && !models.SameOrganization(role.OrganizationID, user.OrganizationID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role belongs to another organization"})
+		return
+	}
+
+	// ผู้เรียกกำหนดได้เฉพาะบทบาทที่สิทธิ์ไม่เกินสิทธิ์ของตนเอง หรือบทบาทที่มอบต่อได้
+	grantedBy := c.GetUint("userID")
+	if err := service.CheckCanGrantRole(h.db, grantedBy, role.ID); err != nil {
+		respondGrantError(c, err, "Failed to add role to user")
+		return
+	}
+
+	// เพิ่มบทบาทให้กับผู้ใช้ (กำหนดช่วงเวลาที่มีผลได้)
+	binding, err := h.assignmentService.AssignRole(service.AssignRoleInput{
+		UserID:     user.ID,
+		RoleID:     role.ID,
+		ValidFrom:  requestData.ValidFrom,
+		ValidUntil: requestData.ValidUntil,
+		GrantedBy:  &grantedBy,
+		Reason:     requestData.Reason,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidValidityWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if respondSoDViolation(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add role to user"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Role added to user successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Role added to user successfully", "assignment": binding})
 }
 
 // RemoveRoleFromUser ลบบทบาทออกจากผู้ใช้
@@ -236,22 +340,49 @@ func (h *UserHandler) RemoveRoleFromUser(c *gin.Context) {
 	}
 
 	var user models.User
-	if result := h.db.First(&user, userID); result.Error != nil {
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	var role models.Role
-	if result := h.db.First(&role, roleID); result.Error != nil {
+	if result := h.db.Scopes(tenantRoles(c)).First(&role, roleID); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
 	}
 
 	// ลบบทบาทออกจากผู้ใช้
-	if err := h.db.Model(&user).Association("Roles").Delete(&role); err != nil {
+	actorID := c.GetUint("userID")
+	if err := h.assignmentService.RevokeRole(user.ID, role.ID, &actorID, c.Query("reason")); err != nil {
+		if respondLastRoleManager(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove role from user"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role removed from user successfully"})
 }
+
+// GetUserRoleAssignments รับรายการการกำหนดบทบาทของผู้ใช้ พร้อมช่วงเวลาที่มีผล ผู้ให้สิทธิ์ และเหตุผล
+func (h *UserHandler) GetUserRoleAssignments(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if result := h.db.Scopes(tenantUsers(c)).First(&user, userID); result.Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	bindings, err := h.assignmentService.GetAssignments(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role assignments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bindings)
+}
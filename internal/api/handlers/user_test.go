@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupMockDB สร้าง gorm.DB ที่ต่อกับ sqlmock สำหรับทดสอบ handler ที่ใช้ฐานข้อมูลโดยตรง
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	return gormDB, mock
+}
+
+// performJSON ส่ง request พร้อม body JSON ไปยัง router และคืนผลลัพธ์
+func performJSON(r http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	req, _ := http.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// bcryptOf ตรวจว่าค่าที่บันทึกเป็น bcrypt hash ของรหัสผ่านที่กำหนด
+type bcryptOf string
+
+func (p bcryptOf) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	return ok && bcrypt.CompareHashAndPassword([]byte(hash), []byte(p)) == nil
+}
+
+func setupUserHandlerTest(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	gin.SetMode(gin.TestMode)
+	db, mock := setupMockDB(t)
+
+	h := NewUserHandler(db, nil, nil)
+	r := gin.New()
+	r.POST("/users", h.CreateUser)
+	return r, mock
+}
+
+func TestCreateUser_HashesLongPassword(t *testing.T) {
+	r, mock := setupUserHandlerTest(t)
+
+	// รหัสผ่านยาวตั้งแต่ 60 ตัวอักษรต้องถูกเข้ารหัสเช่นกัน ไม่ถูกเก็บเป็นข้อความธรรมดา
+	password := strings.Repeat("correct horse battery staple ", 2) + "tango"
+	require.GreaterOrEqual(t, len(password), 60)
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 OR email = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users"`).
+		WithArgs("alice", "alice@example.com", bcryptOf(password), "", nil, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	w := performJSON(r, http.MethodPost, "/users", gin.H{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"password": password,
+	})
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), password)
+}
+
+func TestCreateUser_RequiresCredentials(t *testing.T) {
+	r, _ := setupUserHandlerTest(t)
+
+	// ผู้ใช้ที่ไม่มี username, email หรือ password ต้องถูกปฏิเสธก่อนถึงฐานข้อมูล
+	w := performJSON(r, http.MethodPost, "/users", gin.H{"full_name": "Nobody"})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateUser_DuplicateReturnsCode(t *testing.T) {
+	r, mock := setupUserHandlerTest(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 OR email = \$2`).
+		WithArgs("alice", "alice@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(7, "alice"))
+
+	w := performJSON(r, http.MethodPost, "/users", gin.H{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"password": "secret123",
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "Username or email already exists", "code": "already_exists"}`, w.Body.String())
+}
@@ -1,52 +1,119 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/service"
 	"github.com/yourusername/auth-api/pkg/jwt"
 )
 
-// AuthMiddleware ตรวจสอบความถูกต้องของ JWT token
-// func AuthMiddleware(jwtService *jwt.JWTService, authService *service.AuthService) gin.HandlerFunc {
-func AuthMiddleware(jwtService *jwt.JWTService, authService service.AuthServiceInterface) gin.HandlerFunc {
+// AuthOption ตัวเลือกของ AuthMiddleware และ TokenAuthMiddleware สำหรับแต่ละกลุ่ม route
+type AuthOption func(*authOptions)
+
+type authOptions struct {
+	audiences  []string
+	cookieName string
+}
+
+// AcceptAudiences ให้กลุ่ม route ยอมรับเฉพาะ token ที่ออกให้ audience ใด audience หนึ่งในรายการ
+// ใช้เพิ่มเติมจาก ValidationOptions.Audiences ของ JWTService ซึ่งมีผลกับทุก route
+func AcceptAudiences(audiences ...string) AuthOption {
+	return func(o *authOptions) {
+		o.audiences = append(o.audiences, audiences...)
+	}
+}
+
+// TokenCookie ให้อ่าน token จาก cookie ที่ระบุเมื่อ request ไม่มี header Authorization (session cookie ของแอปที่อยู่หลัง proxy)
+func TokenCookie(name string) AuthOption {
+	return func(o *authOptions) {
+		o.cookieName = name
+	}
+}
+
+func newAuthOptions(opts []AuthOption) *authOptions {
+	options := &authOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// TokenAuthMiddleware ตรวจสอบ JWT token อย่างเดียวโดยไม่เรียกฐานข้อมูล ใช้คู่กับ RequireTokenPermission
+// สำหรับ route ที่ต้องการความเร็ว ข้อมูลผู้ใช้ใน context มีเพียง userID, tenantID และ claims
+func TokenAuthMiddleware(jwtService *jwt.JWTService, opts ...AuthOption) gin.HandlerFunc {
+	options := newAuthOptions(opts)
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
+		claims, ok := bearerClaims(c, jwtService, options)
+		if !ok {
 			return
 		}
 
-		// ตรวจสอบรูปแบบ "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer <token>"})
-			c.Abort()
-			return
+		c.Set("claims", claims)
+		c.Set("userID", claims.UserID)
+		c.Set("tenantID", claims.TenantID)
+		c.Next()
+	}
+}
+
+// bearerClaims อ่านและตรวจสอบ token จาก header Authorization (หรือ cookie ถ้าตั้ง TokenCookie) ถ้าไม่ผ่านจะตอบ 401 และ abort
+func bearerClaims(c *gin.Context, jwtService *jwt.JWTService, options *authOptions) (*jwt.Claims, bool) {
+	var token string
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" && options.cookieName != "" {
+		token, _ = c.Cookie(options.cookieName)
+	}
+	if token == "" {
+		var err error
+		if token, err = service.BearerToken(authHeader); err != nil {
+			respondAuthError(c, err)
+			return nil, false
 		}
+	}
 
-		tokenString := parts[1]
-		claims, err := jwtService.ValidateToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+	claims, err := service.ValidateAccessToken(jwtService, token, options.audiences)
+	if err != nil {
+		respondAuthError(c, err)
+		return nil, false
+	}
+	return claims, true
+}
+
+// respondAuthError ตอบ 401 สำหรับ AuthenticationError และ 500 สำหรับ error อื่น แล้ว abort
+func respondAuthError(c *gin.Context, err error) {
+	var authErr *service.AuthenticationError
+	if errors.As(err, &authErr) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": authErr.Message})
+	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user roles"})
+	}
+	c.Abort()
+}
+
+// AuthMiddleware ตรวจสอบความถูกต้องของ JWT token
+// func AuthMiddleware(jwtService *jwt.JWTService, authService *service.AuthService) gin.HandlerFunc {
+func AuthMiddleware(jwtService *jwt.JWTService, authService service.AuthServiceInterface, opts ...AuthOption) gin.HandlerFunc {
+	options := newAuthOptions(opts)
+	return func(c *gin.Context) {
+		claims, ok := bearerClaims(c, jwtService, options)
+		if !ok {
 			return
 		}
 
-		// ดึงข้อมูลผู้ใช้จาก token
-		user, err := authService.GetUserByID(claims.UserID)
+		// ผู้ใช้ต้องยังอยู่และอยู่ใน tenant เดียวกับ token บทบาทที่มีผลรวมบทบาทที่ได้รับผ่านกลุ่มด้วย
+		principal, err := service.Authenticate(authService, claims)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			c.Abort()
+			respondAuthError(c, err)
 			return
 		}
 
 		// เก็บข้อมูลผู้ใช้ใน context สำหรับใช้ในขั้นตอนต่อไป
-		c.Set("user", user)
+		c.Set("claims", claims)
+		c.Set("user", principal.User)
+		c.Set("roles", principal.Roles)
 		c.Set("userID", claims.UserID)
+		c.Set("tenantID", principal.User.OrganizationID)
 		c.Next()
 	}
 }
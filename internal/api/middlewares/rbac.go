@@ -1,16 +1,50 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/auth-api/internal/models"
 	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/jwt"
+	"gorm.io/gorm"
 )
 
+// PermissionOption ตัวเลือกของ RequirePermission และ RequireTokenPermission
+type PermissionOption func(*permissionOptions)
+
+type permissionOptions struct {
+	usageRecorder  service.UsageRecorder
+	explainDenials bool
+}
+
+// RecordUsage ส่งผลการตัดสินสิทธิ์ทุกครั้งให้ recorder เพื่อสรุปการใช้งานสิทธิ์ (nil = ไม่บันทึก)
+func RecordUsage(recorder service.UsageRecorder) PermissionOption {
+	return func(o *permissionOptions) {
+		o.usageRecorder = recorder
+	}
+}
+
+// ExplainDenials แนบผลการประเมินสิทธิ์ในคำตอบ 403 ของ RequirePermission (ใช้เฉพาะสภาพแวดล้อมที่ไม่ใช่ production)
+func ExplainDenials(enabled bool) PermissionOption {
+	return func(o *permissionOptions) {
+		o.explainDenials = enabled
+	}
+}
+
+func newPermissionOptions(opts []PermissionOption) *permissionOptions {
+	options := &permissionOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
 // RequirePermission ตรวจสอบว่าผู้ใช้มีสิทธิ์ที่ต้องการหรือไม่
 // func RequirePermission(authService *service.AuthService, resource string, action string) gin.HandlerFunc {
-func RequirePermission(authService service.AuthServiceInterface, resource string, action string) gin.HandlerFunc {
+func RequirePermission(authService service.AuthServiceInterface, resource string, action string, opts ...PermissionOption) gin.HandlerFunc {
+	options := newPermissionOptions(opts)
 	return func(c *gin.Context) {
 		// ดึง userID จาก context ที่ถูกตั้งค่าโดย AuthMiddleware
 		userIDValue, exists := c.Get("userID")
@@ -20,6 +54,13 @@ func RequirePermission(authService service.AuthServiceInterface, resource string
 			return
 		}
 
+		// token ที่จำกัด scope ใช้ได้เฉพาะสิทธิ์ใน scope แม้ผู้ใช้จะมีสิทธิ์มากกว่านั้น
+		if !tokenScopeCovers(c, resource, action) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token scope does not cover " + resource + ":" + action})
+			c.Abort()
+			return
+		}
+
 		userID := userIDValue.(uint)
 		hasPermission, err := authService.HasPermission(userID, resource, action)
 		if err != nil {
@@ -28,6 +69,79 @@ func RequirePermission(authService service.AuthServiceInterface, resource string
 			return
 		}
 
+		if options.usageRecorder != nil {
+			options.usageRecorder.RecordUsage(userID, resource, action, hasPermission)
+		}
+
+		if !hasPermission {
+			response := gin.H{"error": "Permission denied"}
+			if explainer, ok := authService.(service.PermissionExplainer); ok && options.explainDenials {
+				if explanation, err := explainer.ExplainPermission(userID, resource, action); err == nil {
+					response["explanation"] = explanation
+				}
+			}
+			c.JSON(http.StatusForbidden, response)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tokenScopeCovers ตรวจสอบ scope ของ token ที่ AuthMiddleware เก็บไว้ (ไม่มี claims ถือว่าไม่จำกัด scope)
+func tokenScopeCovers(c *gin.Context, resource string, action string) bool {
+	claimsValue, exists := c.Get("claims")
+	if !exists {
+		return true
+	}
+	return claimsValue.(*jwt.Claims).ScopeCovers(resource, action)
+}
+
+// RequireTokenPermission ตรวจสอบสิทธิ์จากรายการที่แนบมากับ token โดยไม่เรียกฐานข้อมูล (ต้องใช้หลัง TokenAuthMiddleware หรือ AuthMiddleware)
+// ถ้าระบุ versions จะปฏิเสธ token ที่ชุดสิทธิ์ไม่ตรงกับปัจจุบันด้วย 401 เพื่อให้ผู้ใช้ login ใหม่
+// versions เป็น nil ได้ ซึ่งหมายถึงเชื่อสิทธิ์ใน token จนกว่าจะหมดอายุ
+func RequireTokenPermission(versions service.PermissionsVersionSource, resource string, action string, opts ...PermissionOption) gin.HandlerFunc {
+	options := newPermissionOptions(opts)
+	return func(c *gin.Context) {
+		claimsValue, exists := c.Get("claims")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		claims := claimsValue.(*jwt.Claims)
+		if !claims.HasEmbeddedPermissions() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token does not carry permissions"})
+			c.Abort()
+			return
+		}
+
+		if versions != nil {
+			version, err := versions.PermissionsVersion(claims.UserID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				c.Abort()
+				return
+			}
+			if version != claims.PermissionsVersion {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token permissions are outdated"})
+				c.Abort()
+				return
+			}
+		}
+
+		hasPermission := claims.HasPermission(resource, action)
+		if options.usageRecorder != nil {
+			options.usageRecorder.RecordUsage(claims.UserID, resource, action, hasPermission)
+		}
+
 		if !hasPermission {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 			c.Abort()
@@ -38,7 +152,9 @@ func RequirePermission(authService service.AuthServiceInterface, resource string
 	}
 }
 
-// RequireRole ตรวจสอบว่าผู้ใช้มีบทบาทที่ต้องการหรือไม่
+// RequireRole ตรวจสอบว่าผู้ใช้มีบทบาทระดับ global ที่ต้องการหรือไม่ (รวมบทบาทที่ได้รับผ่านกลุ่ม)
+// บทบาทของ tenant ไม่นับแม้ชื่อตรงกัน เพราะผู้ดูแล tenant ตั้งชื่อบทบาทของตนเองได้
+// route ใหม่ควรใช้ RequirePermission แทน
 func RequireRole(roleName string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userValue, exists := c.Get("user")
@@ -48,11 +164,22 @@ func RequireRole(roleName string) gin.HandlerFunc {
 			return
 		}
 
-		user := userValue.(*models.User)
-		hasRole := false
+		// token ที่จำกัด scope ไม่ได้รับอำนาจของบทบาททั้งบทบาท
+		if claimsValue, exists := c.Get("claims"); exists && claimsValue.(*jwt.Claims).IsScoped() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Scoped tokens cannot use role-protected routes"})
+			c.Abort()
+			return
+		}
 
-		for _, role := range user.Roles {
-			if role.Name == roleName {
+		// ใช้บทบาทที่มีผลจริง (รวมบทบาทจากกลุ่ม) ถ้า AuthMiddleware เตรียมไว้ให้
+		roles := userValue.(*models.User).Roles
+		if rolesValue, exists := c.Get("roles"); exists {
+			roles = rolesValue.([]models.Role)
+		}
+
+		hasRole := false
+		for _, role := range roles {
+			if role.Name == roleName && role.OrganizationID == nil {
 				hasRole = true
 				break
 			}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+// ServiceAuthMiddleware ยืนยันตัวตนของบริการอื่นด้วย HTTP Basic (client_id:client_secret)
+// ใช้กับ API ที่บริการอื่นเรียก ไม่ใช่ผู้ใช้ทั่วไป
+func ServiceAuthMiddleware(authenticator service.ServiceAccountAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="authz"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Service credentials are required"})
+			c.Abort()
+			return
+		}
+
+		account, err := authenticator.Authenticate(clientID, clientSecret)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidServiceCredentials) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid service credentials"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate service"})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("serviceAccount", account)
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+)
+
+// MockServiceAccountAuthenticator เป็น mock ของ ServiceAccountAuthenticator
+type MockServiceAccountAuthenticator struct {
+	AuthenticateFunc func(clientID string, clientSecret string) (*models.ServiceAccount, error)
+}
+
+// Authenticate implements ServiceAccountAuthenticator
+func (m *MockServiceAccountAuthenticator) Authenticate(clientID string, clientSecret string) (*models.ServiceAccount, error) {
+	return m.AuthenticateFunc(clientID, clientSecret)
+}
+
+func setupServiceAuthTest() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	authenticator := &MockServiceAccountAuthenticator{
+		AuthenticateFunc: func(clientID string, clientSecret string) (*models.ServiceAccount, error) {
+			if clientID == "svc_orders" && clientSecret == "secret" {
+				return &models.ServiceAccount{ID: 1, Name: "orders", ClientID: clientID}, nil
+			}
+			return nil, service.ErrInvalidServiceCredentials
+		},
+	}
+
+	r.Use(ServiceAuthMiddleware(authenticator))
+	r.POST("/check", func(c *gin.Context) {
+		// ส่งชื่อบัญชีบริการที่ middleware ตั้งไว้กลับไปให้ตรวจสอบ
+		account, _ := c.Get("serviceAccount")
+		c.JSON(http.StatusOK, gin.H{"service": account.(*models.ServiceAccount).Name})
+	})
+
+	return r
+}
+
+func TestServiceAuthMiddleware_MissingCredentials(t *testing.T) {
+	r := setupServiceAuthTest()
+
+	req, _ := http.NewRequest("POST", "/check", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Service credentials are required")
+}
+
+func TestServiceAuthMiddleware_InvalidCredentials(t *testing.T) {
+	r := setupServiceAuthTest()
+
+	req, _ := http.NewRequest("POST", "/check", nil)
+	req.SetBasicAuth("svc_orders", "wrong")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid service credentials")
+}
+
+func TestServiceAuthMiddleware_Success(t *testing.T) {
+	r := setupServiceAuthTest()
+
+	req, _ := http.NewRequest("POST", "/check", nil)
+	req.SetBasicAuth("svc_orders", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"service":"orders"`)
+}
@@ -1,25 +1,38 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/yourusername/auth-api/internal/rebac"
+	"github.com/yourusername/auth-api/internal/routeauth"
 )
 
 // Config โครงสร้างการตั้งค่าของแอปพลิเคชัน
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	JWT            JWTConfig
+	RoleExpiry     RoleExpiryConfig
+	AccessRequests AccessRequestsConfig
+	AccessReviews  AccessReviewsConfig
+	Authz          AuthzConfig
+	ReBAC          ReBACConfig
+	Policy         PolicyConfig
+	Usage          UsageConfig
+	ForwardAuth    ForwardAuthConfig
+	ExtAuthz       ExtAuthzConfig
 }
 
 // ServerConfig การตั้งค่าเซิร์ฟเวอร์
 type ServerConfig struct {
-	Port    string
-	Timeout time.Duration
+	Port        string
+	Timeout     time.Duration
+	Environment string // development, staging, production
 }
 
 // DatabaseConfig การตั้งค่าฐานข้อมูล
@@ -37,6 +50,74 @@ type JWTConfig struct {
 	SecretKey     string
 	Issuer        string
 	TokenDuration time.Duration
+	// EmbedPermissions แนบบทบาท สิทธิ์ และเวอร์ชันของชุดสิทธิ์ไปกับ token ที่ออกตอน login (stateless authorization)
+	EmbedPermissions bool
+	Audience         string        // aud ของ token ที่ login โดยไม่ระบุ client_id และ audience ที่ route ของ API นี้ยอมรับ (ว่าง = ไม่ตรวจ)
+	Clients          []JWTClient   // audience ของ token ตาม client_id ที่ส่งมาตอน login
+	StrictIssuer     bool          // ปฏิเสธ token ที่ iss ไม่ตรงกับ jwt.issuer
+	RequireNotBefore bool          // ปฏิเสธ token ที่ไม่มี nbf
+	Leeway           time.Duration // ความคลาดเคลื่อนของนาฬิกาที่ยอมรับในการตรวจ exp, nbf และ iat
+	PrivateKeyFile   string        // RSA private key (PEM) สำหรับเซ็น token ด้วย RS256 และเผยแพร่ JWKS (ว่าง = HS256 ด้วย secretKey)
+	KeyID            string        // kid ของ key (ว่าง = thumbprint ของ public key)
+}
+
+// JWTClient แอปที่ขอ token ได้ พร้อม audience ที่ token ของแอปนั้นจะได้รับ
+type JWTClient struct {
+	ID        string
+	Audiences []string
+}
+
+// RoleExpiryConfig การตั้งค่าการลบการกำหนดบทบาทที่หมดอายุ
+type RoleExpiryConfig struct {
+	SweepInterval time.Duration
+}
+
+// AccessRequestsConfig การตั้งค่าคำขอสิทธิ์ชั่วคราว
+type AccessRequestsConfig struct {
+	ApproverPermission string // สิทธิ์ของผู้อนุมัติในรูปแบบ resource:action
+	MaxDuration        time.Duration
+}
+
+// AccessReviewsConfig การตั้งค่าแคมเปญทบทวนสิทธิ์
+type AccessReviewsConfig struct {
+	SweepInterval time.Duration // ระยะเวลาระหว่างการตรวจหาแคมเปญที่เลยกำหนด
+}
+
+// AuthzConfig การตั้งค่าการตรวจสอบสิทธิ์
+type AuthzConfig struct {
+	ExplainDenials bool          // แนบคำอธิบายในคำตอบ 403 (ไม่มีผลเมื่อ server.environment เป็น production)
+	CacheTTL       time.Duration // อายุสูงสุดของแคชบทบาทและสิทธิ์ในหน่วยความจำ (0 = ปิดแคช)
+	CacheSync      bool          // กระจายการ invalidate แคชไปยัง instance อื่นผ่าน PostgreSQL LISTEN/NOTIFY
+}
+
+// ReBACConfig namespace configuration ของ relation tuples (object#relation@subject)
+type ReBACConfig struct {
+	Namespaces []rebac.Namespace
+}
+
+// PolicyConfig การตั้งค่า policy as code
+type PolicyConfig struct {
+	File string // ไฟล์ policy ที่จะ apply ทุกครั้งที่เริ่มระบบ (ว่าง = ไม่ apply)
+}
+
+// UsageConfig การตั้งค่าการสรุปการใช้งานสิทธิ์
+type UsageConfig struct {
+	FlushInterval time.Duration // ระยะเวลาระหว่างการบันทึกยอดที่สะสมไว้ลงฐานข้อมูล
+	Retention     time.Duration // เก็บยอดรวมรายวันไว้นานเท่าใด (0 = ไม่ลบ)
+}
+
+// ForwardAuthConfig การตั้งค่า forward-auth สำหรับแอปที่อยู่หลัง reverse proxy (GET /api/auth/verify)
+type ForwardAuthConfig struct {
+	CookieName    string           // cookie ที่เก็บ token เมื่อ request ไม่มี header Authorization (ว่าง = ไม่อ่าน cookie)
+	DefaultPolicy string           // deny หรือ authenticated เมื่อไม่มีกฎที่ตรงกัน
+	Rules         []routeauth.Rule // กฎแรกที่ตรงกับ request เป็นตัวตัดสิน
+}
+
+// ExtAuthzConfig การตั้งค่า Envoy ext_authz gRPC server ซึ่งเปิดบน port แยกจาก HTTP API
+type ExtAuthzConfig struct {
+	Port          string           // port ของ gRPC server (ว่าง = ปิด)
+	DefaultPolicy string           // deny หรือ authenticated เมื่อไม่มีกฎที่ตรงกัน
+	Rules         []routeauth.Rule // กฎแรกที่ตรงกับ request เป็นตัวตัดสิน
 }
 
 // LoadConfig โหลดการตั้งค่าจากไฟล์หรือตัวแปรสภาพแวดล้อม
@@ -65,6 +146,7 @@ func LoadConfig() (*Config, error) {
 	// Server config
 	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.timeout", 10*time.Second)
+	viper.SetDefault("server.environment", "development")
 
 	// Database config
 	viper.SetDefault("database.host", "localhost")
@@ -78,9 +160,47 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("jwt.secretKey", "your-secret-key")
 	viper.SetDefault("jwt.issuer", "auth-api")
 	viper.SetDefault("jwt.tokenDuration", 24*time.Hour)
+	viper.SetDefault("jwt.embedPermissions", false)
+	viper.SetDefault("jwt.audience", "")
+	viper.SetDefault("jwt.strictIssuer", true)
+	viper.SetDefault("jwt.requireNotBefore", false)
+	viper.SetDefault("jwt.leeway", time.Duration(0))
+	viper.SetDefault("jwt.privateKeyFile", "")
+	viper.SetDefault("jwt.keyID", "")
+
+	// Role expiry config
+	viper.SetDefault("roleExpiry.sweepInterval", time.Minute)
+
+	// Access request config
+	viper.SetDefault("accessRequests.approverPermission", "access_requests:approve")
+	viper.SetDefault("accessRequests.maxDuration", 8*time.Hour)
+
+	// Access review config
+	viper.SetDefault("accessReviews.sweepInterval", time.Minute)
+
+	// Authz config
+	viper.SetDefault("authz.explainDenials", false)
+	viper.SetDefault("authz.cacheTTL", 5*time.Minute)
+	viper.SetDefault("authz.cacheSync", true)
+
+	// Policy config
+	viper.SetDefault("policy.file", "")
+
+	// Usage config
+	viper.SetDefault("usage.flushInterval", 30*time.Second)
+	viper.SetDefault("usage.retention", 400*24*time.Hour)
+
+	// Forward-auth config
+	viper.SetDefault("forwardAuth.cookieName", "access_token")
+	viper.SetDefault("forwardAuth.defaultPolicy", routeauth.PolicyDeny)
+
+	// Envoy ext_authz config
+	viper.SetDefault("extAuthz.port", "")
+	viper.SetDefault("extAuthz.defaultPolicy", routeauth.PolicyDeny)
 
 	// ตรวจสอบตัวแปรสภาพแวดล้อมโดยตรง (สนับสนุนทั้งรูปแบบพื้นฐานและรูปแบบ Docker Compose)
 	checkEnvOverride("SERVER_PORT", "server.port")
+	checkEnvOverride("SERVER_ENVIRONMENT", "server.environment")
 	checkEnvOverride("DATABASE_HOST", "database.host")
 	checkEnvOverride("DATABASE_PORT", "database.port")
 	checkEnvOverride("DATABASE_USER", "database.user")
@@ -90,11 +210,33 @@ func LoadConfig() (*Config, error) {
 	checkEnvOverride("JWT_SECRETKEY", "jwt.secretKey")
 	checkEnvOverride("JWT_ISSUER", "jwt.issuer")
 	checkEnvOverrideDuration("JWT_TOKENDURATION", "jwt.tokenDuration")
+	checkEnvOverride("JWT_EMBEDPERMISSIONS", "jwt.embedPermissions")
+	checkEnvOverride("JWT_AUDIENCE", "jwt.audience")
+	checkEnvOverride("JWT_STRICTISSUER", "jwt.strictIssuer")
+	checkEnvOverride("JWT_REQUIRENOTBEFORE", "jwt.requireNotBefore")
+	checkEnvOverrideDuration("JWT_LEEWAY", "jwt.leeway")
+	checkEnvOverride("JWT_PRIVATEKEYFILE", "jwt.privateKeyFile")
+	checkEnvOverride("JWT_KEYID", "jwt.keyID")
+	checkEnvOverrideDuration("ROLEEXPIRY_SWEEPINTERVAL", "roleExpiry.sweepInterval")
+	checkEnvOverride("ACCESSREQUESTS_APPROVERPERMISSION", "accessRequests.approverPermission")
+	checkEnvOverrideDuration("ACCESSREQUESTS_MAXDURATION", "accessRequests.maxDuration")
+	checkEnvOverrideDuration("ACCESSREVIEWS_SWEEPINTERVAL", "accessReviews.sweepInterval")
+	checkEnvOverride("AUTHZ_EXPLAINDENIALS", "authz.explainDenials")
+	checkEnvOverrideDuration("AUTHZ_CACHETTL", "authz.cacheTTL")
+	checkEnvOverride("AUTHZ_CACHESYNC", "authz.cacheSync")
+	checkEnvOverride("POLICY_FILE", "policy.file")
+	checkEnvOverrideDuration("USAGE_FLUSHINTERVAL", "usage.flushInterval")
+	checkEnvOverrideDuration("USAGE_RETENTION", "usage.retention")
+	checkEnvOverride("FORWARDAUTH_COOKIENAME", "forwardAuth.cookieName")
+	checkEnvOverride("FORWARDAUTH_DEFAULTPOLICY", "forwardAuth.defaultPolicy")
+	checkEnvOverride("EXTAUTHZ_PORT", "extAuthz.port")
+	checkEnvOverride("EXTAUTHZ_DEFAULTPOLICY", "extAuthz.defaultPolicy")
 
 	config := &Config{
 		Server: ServerConfig{
-			Port:    viper.GetString("server.port"),
-			Timeout: viper.GetDuration("server.timeout"),
+			Port:        viper.GetString("server.port"),
+			Timeout:     viper.GetDuration("server.timeout"),
+			Environment: viper.GetString("server.environment"),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("database.host"),
@@ -105,15 +247,89 @@ func LoadConfig() (*Config, error) {
 			SSLMode:  viper.GetString("database.sslmode"),
 		},
 		JWT: JWTConfig{
-			SecretKey:     viper.GetString("jwt.secretKey"),
-			Issuer:        viper.GetString("jwt.issuer"),
-			TokenDuration: viper.GetDuration("jwt.tokenDuration"),
+			SecretKey:        viper.GetString("jwt.secretKey"),
+			Issuer:           viper.GetString("jwt.issuer"),
+			TokenDuration:    viper.GetDuration("jwt.tokenDuration"),
+			EmbedPermissions: viper.GetBool("jwt.embedPermissions"),
+			Audience:         viper.GetString("jwt.audience"),
+			StrictIssuer:     viper.GetBool("jwt.strictIssuer"),
+			RequireNotBefore: viper.GetBool("jwt.requireNotBefore"),
+			Leeway:           viper.GetDuration("jwt.leeway"),
+			PrivateKeyFile:   viper.GetString("jwt.privateKeyFile"),
+			KeyID:            viper.GetString("jwt.keyID"),
+		},
+		RoleExpiry: RoleExpiryConfig{
+			SweepInterval: viper.GetDuration("roleExpiry.sweepInterval"),
+		},
+		AccessRequests: AccessRequestsConfig{
+			ApproverPermission: viper.GetString("accessRequests.approverPermission"),
+			MaxDuration:        viper.GetDuration("accessRequests.maxDuration"),
+		},
+		AccessReviews: AccessReviewsConfig{
+			SweepInterval: viper.GetDuration("accessReviews.sweepInterval"),
+		},
+		Authz: AuthzConfig{
+			ExplainDenials: viper.GetBool("authz.explainDenials"),
+			CacheTTL:       viper.GetDuration("authz.cacheTTL"),
+			CacheSync:      viper.GetBool("authz.cacheSync"),
+		},
+		Policy: PolicyConfig{
+			File: viper.GetString("policy.file"),
+		},
+		Usage: UsageConfig{
+			FlushInterval: viper.GetDuration("usage.flushInterval"),
+			Retention:     viper.GetDuration("usage.retention"),
+		},
+		ForwardAuth: ForwardAuthConfig{
+			CookieName:    viper.GetString("forwardAuth.cookieName"),
+			DefaultPolicy: viper.GetString("forwardAuth.defaultPolicy"),
+		},
+		ExtAuthz: ExtAuthzConfig{
+			Port:          viper.GetString("extAuthz.port"),
+			DefaultPolicy: viper.GetString("extAuthz.defaultPolicy"),
 		},
 	}
 
+	// namespace เป็นโครงสร้างซ้อนกัน จึงอ่านได้จากไฟล์การตั้งค่าเท่านั้น
+	if err := viper.UnmarshalKey("rebac.namespaces", &config.ReBAC.Namespaces); err != nil {
+		return nil, err
+	}
+	if err := viper.UnmarshalKey("jwt.clients", &config.JWT.Clients); err != nil {
+		return nil, err
+	}
+	if err := viper.UnmarshalKey("forwardAuth.rules", &config.ForwardAuth.Rules); err != nil {
+		return nil, err
+	}
+	if err := viper.UnmarshalKey("extAuthz.rules", &config.ExtAuthz.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// validate ตรวจค่าที่ถ้าผิดจะทำให้ระบบล้มระหว่างทำงานแทนที่จะล้มตอนเริ่ม
+func (c *Config) validate() error {
+	// time.NewTicker panic เมื่อ interval ไม่เป็นบวก
+	intervals := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"roleExpiry.sweepInterval", c.RoleExpiry.SweepInterval},
+		{"accessReviews.sweepInterval", c.AccessReviews.SweepInterval},
+		{"usage.flushInterval", c.Usage.FlushInterval},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", interval.key, interval.value)
+		}
+	}
+	return nil
+}
+
 // checkEnvOverride ตรวจสอบตัวแปรสภาพแวดล้อมและกำหนดค่าให้ viper ถ้ามี
 func checkEnvOverride(envName, configPath string) {
 	if val, exists := os.LookupEnv(envName); exists {
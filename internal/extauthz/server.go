@@ -0,0 +1,168 @@
+// Package extauthz ให้บริการ Envoy external authorization (ext_authz v3 gRPC Check API)
+// เพื่อให้ service mesh ที่ใช้ Envoy ถามสิทธิ์ก่อนส่ง request ไปยังบริการปลายทาง
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/yourusername/auth-api/internal/routeauth"
+	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/jwt"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// header ที่ส่งต่อให้บริการปลายทางเมื่ออนุญาต (Envoy เขียนทับค่าที่ client ส่งมาเอง)
+const (
+	UserIDHeader    = "x-user-id"
+	UserRolesHeader = "x-user-roles"
+)
+
+// Server ตัดสินสิทธิ์ตามกฎ routeauth ด้วยการตรวจ token และสิทธิ์ชุดเดียวกับ AuthMiddleware และ RequirePermission
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+	jwtService  *jwt.JWTService
+	authService service.AuthServiceInterface
+	rules       *routeauth.Table
+	audiences   []string
+}
+
+// NewServer สร้าง Server ถ้าระบุ audiences จะยอมรับเฉพาะ token ที่ออกให้ audience ใด audience หนึ่งในรายการ
+func NewServer(jwtService *jwt.JWTService, authService service.AuthServiceInterface, rules *routeauth.Table, audiences ...string) *Server {
+	return &Server{
+		jwtService:  jwtService,
+		authService: authService,
+		rules:       rules,
+		audiences:   audiences,
+	}
+}
+
+// Register ลงทะเบียน Server กับ gRPC server
+func (s *Server) Register(grpcServer *grpc.Server) {
+	authv3.RegisterAuthorizationServer(grpcServer, s)
+}
+
+// Check ตัดสินสิทธิ์ของ request หนึ่งรายการ การปฏิเสธตอบเป็น DeniedResponse พร้อม HTTP status ที่ Envoy ส่งกลับให้ client
+// ส่วน error ของ gRPC ใช้เฉพาะ request ที่ไม่มีข้อมูล HTTP
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	httpRequest := req.GetAttributes().GetRequest().GetHttp()
+	if httpRequest == nil {
+		return nil, status.Error(codes.InvalidArgument, "request attributes must include http")
+	}
+
+	// path ของ Envoy มี query string ต่อท้ายและยังไม่ถอด percent-encoding จึงต้อง parse ก่อนจับคู่กฎ
+	// เหมือน ForwardAuthHandler มิฉะนั้น /%61dmin จะหลุดกฎของ /admin ไปใช้ค่าเริ่มต้น
+	requestURL, err := url.ParseRequestURI(httpRequest.GetPath())
+	if err != nil {
+		return deny(codes.InvalidArgument, typev3.StatusCode_BadRequest, "Invalid request path"), nil
+	}
+	requirement := s.rules.Match(httpRequest.GetMethod(), httpRequest.GetHost(), requestURL.Path)
+	if requirement.Public {
+		// route สาธารณะไม่มีผู้ใช้ จึงลบ header ระบุตัวตนที่ client อาจปลอมมา
+		return allow(nil, []string{UserIDHeader, UserRolesHeader}), nil
+	}
+
+	principal, err := s.authenticate(requestHeader(httpRequest, "authorization"))
+	if err != nil {
+		var authErr *service.AuthenticationError
+		if errors.As(err, &authErr) {
+			return deny(codes.Unauthenticated, typev3.StatusCode_Unauthorized, authErr.Message), nil
+		}
+		return deny(codes.Internal, typev3.StatusCode_InternalServerError, "Failed to load user roles"), nil
+	}
+	claims := principal.Claims
+
+	if requirement.Denied {
+		return deny(codes.PermissionDenied, typev3.StatusCode_Forbidden, "No rule allows this route"), nil
+	}
+	if requirement.NeedsPermission() {
+		allowed := claims.ScopeCovers(requirement.Resource, requirement.Action)
+		if allowed {
+			allowed, err = s.authService.HasPermission(claims.UserID, requirement.Resource, requirement.Action)
+			if err != nil {
+				return deny(codes.Internal, typev3.StatusCode_InternalServerError, "Failed to check permissions"), nil
+			}
+		}
+		if !allowed {
+			return deny(codes.PermissionDenied, typev3.StatusCode_Forbidden, "Permission denied"), nil
+		}
+	}
+
+	return allow(map[string]string{
+		UserIDHeader:    strconv.FormatUint(uint64(principal.User.ID), 10),
+		UserRolesHeader: strings.Join(service.RoleNames(principal.Roles), ","),
+	}, nil), nil
+}
+
+// authenticate ยืนยันตัวตนจาก header Authorization แบบ "Bearer <token>" ด้วยขั้นตอนเดียวกับ AuthMiddleware
+func (s *Server) authenticate(authHeader string) (*service.Principal, error) {
+	token, err := service.BearerToken(authHeader)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := service.ValidateAccessToken(s.jwtService, token, s.audiences)
+	if err != nil {
+		return nil, err
+	}
+	return service.Authenticate(s.authService, claims)
+}
+
+// requestHeader อ่าน header จาก headers หรือ header_map (เมื่อ Envoy ตั้ง encode_raw_headers) ชื่อ header ของ Envoy เป็นตัวพิมพ์เล็ก
+func requestHeader(httpRequest *authv3.AttributeContext_HttpRequest, name string) string {
+	if value, ok := httpRequest.GetHeaders()[name]; ok {
+		return value
+	}
+	for _, header := range httpRequest.GetHeaderMap().GetHeaders() {
+		if header.GetKey() == name {
+			if header.GetValue() != "" {
+				return header.GetValue()
+			}
+			return string(header.GetRawValue())
+		}
+	}
+	return ""
+}
+
+func allow(headers map[string]string, headersToRemove []string) *authv3.CheckResponse {
+	okResponse := &authv3.OkHttpResponse{HeadersToRemove: headersToRemove}
+	for key, value := range headers {
+		okResponse.Headers = append(okResponse.Headers, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: key, Value: value},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+	sort.Slice(okResponse.Headers, func(i, j int) bool {
+		return okResponse.Headers[i].Header.Key < okResponse.Headers[j].Header.Key
+	})
+	return &authv3.CheckResponse{
+		Status:       &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: okResponse},
+	}
+}
+
+// deny ตอบปฏิเสธด้วย body JSON รูปแบบเดียวกับ API ({"error": ...})
+func deny(code codes.Code, httpStatus typev3.StatusCode, message string) *authv3.CheckResponse {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: &authv3.DeniedHttpResponse{
+			Status: &typev3.HttpStatus{Code: httpStatus},
+			Headers: []*corev3.HeaderValueOption{{
+				Header:       &corev3.HeaderValue{Key: "content-type", Value: "application/json"},
+				AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			}},
+			Body: string(body),
+		}},
+	}
+}
@@ -0,0 +1,207 @@
+package extauthz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/routeauth"
+	"github.com/yourusername/auth-api/internal/service"
+	"github.com/yourusername/auth-api/pkg/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mockAuthService ผู้ใช้ 1 มีบทบาท editor ที่มีสิทธิ์ reports:read และได้บทบาท viewer ผ่านกลุ่ม
+type mockAuthService struct {
+	permissionErr error
+}
+
+func (m *mockAuthService) GetUserByID(userID uint) (*models.User, error) {
+	if userID != 1 {
+		return nil, errors.New("user not found")
+	}
+	return &models.User{ID: 1, Username: "alice", Roles: []models.Role{{ID: 1, Name: "editor"}}}, nil
+}
+
+func (m *mockAuthService) GetGroupRoles(userID uint) ([]models.Role, error) {
+	return []models.Role{{ID: 2, Name: "viewer"}, {ID: 1, Name: "editor"}}, nil
+}
+
+func (m *mockAuthService) HasPermission(userID uint, resource string, action string) (bool, error) {
+	if m.permissionErr != nil {
+		return false, m.permissionErr
+	}
+	return resource == "reports" && action == "read", nil
+}
+
+func (m *mockAuthService) Login(_ *service.LoginRequest) (*service.LoginResponse, error) {
+	return nil, nil
+}
+
+// newTestClient เปิด Server บน bufconn แล้วคืน client ที่ต่อผ่าน gRPC จริงภายใน process
+func newTestClient(t *testing.T, authService service.AuthServiceInterface, defaultPolicy string) (authv3.AuthorizationClient, *jwt.JWTService) {
+	t.Helper()
+	jwtService := jwt.NewJWTService("test-secret", "test-issuer", time.Hour)
+	rules, err := routeauth.NewTable([]routeauth.Rule{
+		{Path: "/static/**", Public: true},
+		{Path: "/reports/**", Methods: []string{"GET"}, Permission: "reports:read"},
+		{Path: "/billing/**", Permission: "billing:write"},
+		{Path: "/profile"},
+	}, defaultPolicy)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	NewServer(jwtService, authService, rules).Register(grpcServer)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return authv3.NewAuthorizationClient(conn), jwtService
+}
+
+func checkRequest(method string, path string, headers map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+			Method:  method,
+			Host:    "app.example.com",
+			Path:    path,
+			Headers: headers,
+		}},
+	}}
+}
+
+func okHeaders(response *authv3.CheckResponse) map[string]string {
+	headers := make(map[string]string)
+	for _, option := range response.GetOkResponse().GetHeaders() {
+		headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+	}
+	return headers
+}
+
+func TestCheck(t *testing.T) {
+	client, jwtService := newTestClient(t, &mockAuthService{}, routeauth.PolicyDeny)
+	ctx := context.Background()
+	token, err := jwtService.GenerateToken(1, "alice@example.com")
+	require.NoError(t, err)
+	bearer := map[string]string{"authorization": "Bearer " + token}
+
+	// อนุญาตพร้อม header ระบุตัวตนที่เขียนทับค่าเดิม
+	response, err := client.Check(ctx, checkRequest("GET", "/reports/monthly?year=2024", bearer))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.OK), response.GetStatus().GetCode())
+	assert.Equal(t, map[string]string{UserIDHeader: "1", UserRolesHeader: "editor,viewer"}, okHeaders(response))
+	for _, option := range response.GetOkResponse().GetHeaders() {
+		assert.Equal(t, corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD, option.GetAppendAction())
+	}
+
+	// route สาธารณะไม่ต้องมี token และลบ header ระบุตัวตนที่ client ส่งมา
+	response, err = client.Check(ctx, checkRequest("GET", "/static/app.js", map[string]string{UserIDHeader: "99"}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.OK), response.GetStatus().GetCode())
+	assert.ElementsMatch(t, []string{UserIDHeader, UserRolesHeader}, response.GetOkResponse().GetHeadersToRemove())
+
+	// route ที่ต้องยืนยันตัวตนอย่างเดียว
+	response, err = client.Check(ctx, checkRequest("GET", "/profile", bearer))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.OK), response.GetStatus().GetCode())
+}
+
+func TestCheck_Denied(t *testing.T) {
+	client, jwtService := newTestClient(t, &mockAuthService{}, routeauth.PolicyDeny)
+	ctx := context.Background()
+	token, err := jwtService.GenerateToken(1, "alice@example.com")
+	require.NoError(t, err)
+	unknownUser, err := jwtService.GenerateToken(2, "bob@example.com")
+	require.NoError(t, err)
+	scoped, err := jwtService.GenerateToken(1, "alice@example.com", jwt.WithScope([]string{"billing:write"}))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		code       codes.Code
+		httpStatus typev3.StatusCode
+	}{
+		{"missing token", "GET", "/reports/monthly", "", codes.Unauthenticated, typev3.StatusCode_Unauthorized},
+		{"invalid token", "GET", "/reports/monthly", "invalid", codes.Unauthenticated, typev3.StatusCode_Unauthorized},
+		{"unknown user", "GET", "/reports/monthly", unknownUser, codes.Unauthenticated, typev3.StatusCode_Unauthorized},
+		{"path traversal", "GET", "/static/../reports/monthly", "", codes.Unauthenticated, typev3.StatusCode_Unauthorized},
+		{"missing permission", "GET", "/billing/invoices", token, codes.PermissionDenied, typev3.StatusCode_Forbidden},
+		{"method not in rule", "POST", "/reports/monthly", token, codes.PermissionDenied, typev3.StatusCode_Forbidden},
+		{"scope does not cover permission", "GET", "/reports/monthly", scoped, codes.PermissionDenied, typev3.StatusCode_Forbidden},
+		{"no rule", "GET", "/unknown", token, codes.PermissionDenied, typev3.StatusCode_Forbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.token != "" {
+				headers["authorization"] = "Bearer " + tc.token
+			}
+			response, err := client.Check(ctx, checkRequest(tc.method, tc.path, headers))
+			require.NoError(t, err)
+			assert.Equal(t, int32(tc.code), response.GetStatus().GetCode())
+			assert.Equal(t, tc.httpStatus, response.GetDeniedResponse().GetStatus().GetCode())
+			assert.Contains(t, response.GetDeniedResponse().GetBody(), `"error"`)
+		})
+	}
+
+	// request ที่ไม่มีข้อมูล HTTP เป็น error ของ gRPC
+	_, err = client.Check(ctx, &authv3.CheckRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCheck_PermissionError(t *testing.T) {
+	client, jwtService := newTestClient(t, &mockAuthService{permissionErr: errors.New("database unavailable")}, routeauth.PolicyDeny)
+	token, err := jwtService.GenerateToken(1, "alice@example.com")
+	require.NoError(t, err)
+
+	response, err := client.Check(context.Background(), checkRequest("GET", "/reports/monthly", map[string]string{"authorization": "Bearer " + token}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.Internal), response.GetStatus().GetCode())
+	assert.Equal(t, typev3.StatusCode_InternalServerError, response.GetDeniedResponse().GetStatus().GetCode())
+}
+
+func TestCheck_EncodedPath(t *testing.T) {
+	// ค่าเริ่มต้นเป็น authenticated ถ้าไม่ถอด percent-encoding ก่อนจับคู่ /%62illing จะข้ามสิทธิ์ billing:write ไปได้
+	client, jwtService := newTestClient(t, &mockAuthService{}, routeauth.PolicyAuthenticated)
+	ctx := context.Background()
+	token, err := jwtService.GenerateToken(1, "alice@example.com")
+	require.NoError(t, err)
+	bearer := map[string]string{"authorization": "Bearer " + token}
+
+	response, err := client.Check(ctx, checkRequest("GET", "/%62illing/invoices?page=2", bearer))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.PermissionDenied), response.GetStatus().GetCode())
+	assert.Equal(t, typev3.StatusCode_Forbidden, response.GetDeniedResponse().GetStatus().GetCode())
+
+	response, err = client.Check(ctx, checkRequest("GET", "/%72eports/monthly", bearer))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.OK), response.GetStatus().GetCode())
+
+	// path ที่ parse ไม่ได้ตอบ 400
+	response, err = client.Check(ctx, checkRequest("GET", "/reports/%zz", bearer))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.InvalidArgument), response.GetStatus().GetCode())
+	assert.Equal(t, typev3.StatusCode_BadRequest, response.GetDeniedResponse().GetStatus().GetCode())
+}
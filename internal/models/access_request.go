@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// สถานะของคำขอสิทธิ์ชั่วคราว
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestDenied   = "denied"
+	AccessRequestRevoked  = "revoked"
+	AccessRequestExpired  = "expired"
+)
+
+// AccessRequest คือคำขอยกระดับสิทธิ์ชั่วคราว (just-in-time) ที่ต้องได้รับการอนุมัติก่อนได้รับบทบาท
+type AccessRequest struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RequesterID     uint       `gorm:"index;not null" json:"requester_id"`
+	RoleID          uint       `gorm:"not null" json:"role_id"`
+	Role            *Role      `gorm:"foreignKey:RoleID;constraint:-" json:"role,omitempty"` // ไม่มี foreign key เพื่อให้ลบบทบาทได้โดยประวัติคำขอยังอยู่
+	Justification   string     `gorm:"not null" json:"justification"`
+	DurationSeconds int64      `gorm:"not null" json:"duration_seconds"`
+	Status          string     `gorm:"index;not null;default:pending" json:"status"`
+	ApproverID      *uint      `json:"approver_id"`
+	DecisionNote    string     `json:"decision_note"`
+	DecidedAt       *time.Time `json:"decided_at"`
+	ExpiresAt       *time.Time `gorm:"index" json:"expires_at"` // เวลาที่บทบาทจะถูกถอนคืนอัตโนมัติ
+	OrganizationID  *uint      `gorm:"index" json:"organization_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Duration คืนระยะเวลาที่ขอสิทธิ์
+func (r *AccessRequest) Duration() time.Duration {
+	return time.Duration(r.DurationSeconds) * time.Second
+}
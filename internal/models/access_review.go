@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// สถานะของแคมเปญทบทวนสิทธิ์
+const (
+	AccessReviewOpen      = "open"       // ผู้ทบทวนยังตัดสินใจได้
+	AccessReviewClosed    = "closed"     // ถึงกำหนดหรือปิดแล้ว รอการรับรองผล
+	AccessReviewSignedOff = "signed_off" // รับรองผลแล้ว
+)
+
+// ผลการทบทวนของแต่ละรายการ
+const (
+	AccessReviewPending     = "pending"
+	AccessReviewApproved    = "approved"
+	AccessReviewRevoked     = "revoked"
+	AccessReviewAutoRevoked = "auto_revoked" // ไม่มีผู้ทบทวนก่อนกำหนดและแคมเปญตั้งให้ถอนอัตโนมัติ
+	AccessReviewUnreviewed  = "unreviewed"   // ไม่มีผู้ทบทวนก่อนกำหนด บทบาทยังคงอยู่
+)
+
+// AccessReviewCampaign แคมเปญทบทวนสิทธิ์ (certification) ที่เก็บสำเนาการกำหนดบทบาท ณ เวลาที่สร้าง
+type AccessReviewCampaign struct {
+	ID             uint               `gorm:"primaryKey" json:"id"`
+	Name           string             `gorm:"not null" json:"name"`
+	Description    string             `json:"description"`
+	Status         string             `gorm:"index;not null;default:open" json:"status"`
+	Deadline       time.Time          `gorm:"index;not null" json:"deadline"`
+	AutoRevoke     bool               `gorm:"not null;default:false" json:"auto_revoke"` // ถอนบทบาทที่ไม่มีผู้ทบทวนเมื่อถึงกำหนด
+	OrganizationID *uint              `gorm:"index" json:"organization_id"`
+	CreatedBy      uint               `gorm:"not null" json:"created_by"`
+	ClosedAt       *time.Time         `json:"closed_at"`
+	SignedOffBy    *uint              `json:"signed_off_by"`
+	SignedOffAt    *time.Time         `json:"signed_off_at"`
+	ReportDigest   string             `json:"report_digest,omitempty"` // SHA-256 ของรายงาน ณ เวลาที่รับรองผล
+	Items          []AccessReviewItem `gorm:"foreignKey:CampaignID" json:"items,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// AccessReviewItem การกำหนดบทบาทหนึ่งรายการที่ต้องทบทวน
+// ไม่มี foreign key ไปยังผู้ใช้และบทบาท เพื่อให้ลบผู้ใช้หรือบทบาทได้โดยผลการทบทวนยังคงอยู่เป็นหลักฐาน
+type AccessReviewItem struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CampaignID uint       `gorm:"index;not null" json:"campaign_id"`
+	UserID     uint       `gorm:"not null" json:"user_id"`
+	Username   string     `json:"username"` // ชื่อผู้ใช้ ณ เวลาที่สร้างแคมเปญ ยังอยู่แม้ผู้ใช้ถูกลบ
+	RoleID     uint       `gorm:"not null" json:"role_id"`
+	RoleName   string     `json:"role_name"`   // ชื่อบทบาท ณ เวลาที่สร้างแคมเปญ ยังอยู่แม้บทบาทถูกลบ
+	ValidUntil *time.Time `json:"valid_until"` // วันหมดอายุของการกำหนดบทบาท ณ เวลาที่สร้างแคมเปญ
+	ReviewerID uint       `gorm:"index;not null" json:"reviewer_id"`
+	Decision   string     `gorm:"index;not null;default:pending" json:"decision"`
+	Note       string     `json:"note"`
+	DecidedBy  *uint      `json:"decided_by"`
+	DecidedAt  *time.Time `json:"decided_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog บันทึกการเปลี่ยนแปลงสิทธิ์ที่สำคัญเพื่อใช้ตรวจสอบย้อนหลัง
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    *uint     `gorm:"index" json:"actor_id"`        // nil = ระบบเป็นผู้ดำเนินการ
+	Action     string    `gorm:"index;not null" json:"action"` // เช่น "role.assigned", "role.expired"
+	TargetType string    `gorm:"index:idx_audit_target" json:"target_type"`
+	TargetID   uint      `gorm:"index:idx_audit_target" json:"target_id"`
+	Details    string    `json:"details"` // ข้อมูลเพิ่มเติมในรูปแบบ JSON
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
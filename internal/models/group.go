@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Group คือกลุ่มผู้ใช้ที่ถือบทบาทร่วมกัน สมาชิกจะได้รับบทบาทของกลุ่มและของกลุ่มแม่ทั้งหมด
+type Group struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Name           string    `gorm:"uniqueIndex:idx_groups_org_name;not null" json:"name"`
+	Description    string    `json:"description"`
+	OrganizationID *uint     `gorm:"uniqueIndex:idx_groups_org_name" json:"organization_id"` // nil = กลุ่มระดับ global
+	ParentID       *uint     `gorm:"index" json:"parent_id"`                                 // กลุ่มแม่ (nested group)
+	Members        []User    `gorm:"many2many:group_members;" json:"members,omitempty"`
+	Roles          []Role    `gorm:"many2many:group_roles;" json:"roles,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Organization คือ tenant ที่แยกข้อมูลผู้ใช้และบทบาทออกจากกัน
+type Organization struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SameOrganization เปรียบเทียบ tenant สองค่า โดย nil หมายถึงระดับ global
+func SameOrganization(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
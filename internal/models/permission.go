@@ -1,14 +1,16 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
 type Permission struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	Resource    string    `gorm:"not null" json:"resource"` // เช่น "users", "roles", "articles"
-	Action      string    `gorm:"not null" json:"action"`   // เช่น "read", "write", "delete"
+	Resource    string    `gorm:"not null;uniqueIndex:idx_permissions_resource_action" json:"resource"` // เช่น "users", "roles", "articles"
+	Action      string    `gorm:"not null;uniqueIndex:idx_permissions_resource_action" json:"action"`   // เช่น "read", "write", "delete"
 	Description string    `json:"description"`
+	System      bool      `gorm:"not null;default:false" json:"system"` // สิทธิ์ที่ระบบสร้าง ลบหรือเปลี่ยน resource/action ไม่ได้
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -17,3 +19,12 @@ type Permission struct {
 func (p *Permission) Key() string {
 	return p.Resource + ":" + p.Action
 }
+
+// ParsePermissionKey แยก key รูปแบบ "resource:action" ออกเป็น resource และ action
+func ParsePermissionKey(key string) (string, string, bool) {
+	resource, action, found := strings.Cut(key, ":")
+	if !found || resource == "" || action == "" {
+		return "", "", false
+	}
+	return resource, action, true
+}
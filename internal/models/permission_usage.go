@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// PermissionUsage จำนวนครั้งที่ RequirePermission อนุญาตหรือปฏิเสธสิทธิ์หนึ่งของผู้ใช้ สรุปรวมเป็นรายวัน
+type PermissionUsage struct {
+	UserID     uint      `gorm:"primaryKey" json:"user_id"`
+	Resource   string    `gorm:"primaryKey" json:"resource"`
+	Action     string    `gorm:"primaryKey" json:"action"`
+	Day        time.Time `gorm:"primaryKey;type:date;index" json:"day"`
+	Allowed    int64     `gorm:"not null;default:0" json:"allowed"`
+	Denied     int64     `gorm:"not null;default:0" json:"denied"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// RelationTuple ความสัมพันธ์ object#relation@subject สำหรับการแชร์ระดับ instance (ReBAC)
+// subject เป็นได้ทั้งผู้ใช้โดยตรง (user:7) หรือ userset (team:9#member) เมื่อ SubjectRelation ไม่ว่าง
+// การลบไม่ลบแถวจริงแต่บันทึก DeletedRevision เพื่อให้อ่านสถานะ ณ revision เก่าได้ (consistency token)
+type RelationTuple struct {
+	ID               uint    `gorm:"primaryKey" json:"id"`
+	Namespace        string  `gorm:"not null;index:idx_relation_tuples_object;uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"namespace"`
+	ObjectID         string  `gorm:"not null;index:idx_relation_tuples_object;uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"object_id"`
+	Relation         string  `gorm:"not null;index:idx_relation_tuples_object;uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"relation"`
+	SubjectNamespace string  `gorm:"not null;uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"subject_namespace"`
+	SubjectObjectID  string  `gorm:"not null;uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"subject_object_id"`
+	SubjectRelation  string  `gorm:"not null;default:'';uniqueIndex:idx_relation_tuples_live,where:deleted_revision IS NULL" json:"subject_relation"`
+	CreatedRevision  uint64  `gorm:"not null;index" json:"created_revision"`
+	DeletedRevision  *uint64 `gorm:"index" json:"deleted_revision"`
+}
+
+// RelationRevision หนึ่งแถวต่อการเขียน tuple หนึ่งครั้ง ID ที่เพิ่มขึ้นเรื่อยๆ ใช้เป็น revision ของข้อมูลทั้งหมด
+type RelationRevision struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -5,10 +5,19 @@ import (
 )
 
 type Role struct {
-	ID          uint         `gorm:"primaryKey" json:"id"`
-	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
-	Description string       `json:"description"`
-	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	Name           string       `gorm:"uniqueIndex:idx_roles_org_name;not null" json:"name"`
+	Description    string       `json:"description"`
+	OrganizationID *uint        `gorm:"uniqueIndex:idx_roles_org_name" json:"organization_id"` // nil = บทบาทระดับ global
+	System         bool         `gorm:"not null;default:false" json:"system"`                  // บทบาทที่ระบบสร้าง ลบหรือเปลี่ยนชื่อไม่ได้
+	Permissions    []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+	// DelegableBy บทบาทที่ผู้ถือสามารถมอบบทบาทนี้ต่อได้ แม้จะไม่มีสิทธิ์ครบทุกข้อของบทบาทนี้
+	DelegableBy []Role    `gorm:"many2many:role_delegations;joinForeignKey:RoleID;joinReferences:GrantorRoleID" json:"delegable_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsGlobal ตรวจสอบว่าบทบาทใช้ได้กับทุก tenant หรือไม่
+func (r *Role) IsGlobal() bool {
+	return r.OrganizationID == nil
 }
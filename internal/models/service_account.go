@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// ServiceAccount บัญชีสำหรับบริการอื่นที่เรียก API การตัดสินสิทธิ์ (ยืนยันตัวตนด้วย client_id และ client_secret)
+type ServiceAccount struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Name        string     `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string     `gorm:"size:255" json:"description"`
+	ClientID    string     `gorm:"size:64;not null;uniqueIndex" json:"client_id"`
+	SecretHash  string     `gorm:"size:64;not null" json:"-"` // SHA-256 ของ secret แบบ hex (secret สุ่มยาวพอ ไม่ต้องใช้ bcrypt)
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
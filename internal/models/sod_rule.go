@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// ชนิดของกฎแบ่งแยกหน้าที่ (Separation of Duties)
+const (
+	// SoDMutuallyExclusive ผู้ใช้หนึ่งคนถือบทบาทในชุดนี้ได้ไม่เกิน MaxAllowed บทบาท
+	SoDMutuallyExclusive = "mutually_exclusive"
+	// SoDMaxCardinality แต่ละบทบาทในชุดนี้มีผู้ถือได้ไม่เกิน MaxAllowed คน
+	SoDMaxCardinality = "max_cardinality"
+)
+
+// SoDRule กฎแบ่งแยกหน้าที่ระหว่างบทบาท
+type SoDRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Kind        string    `gorm:"size:30;not null" json:"kind"`
+	MaxAllowed  int       `gorm:"not null;default:1" json:"max_allowed"`
+	Roles       []Role    `gorm:"many2many:sod_rule_roles;joinForeignKey:RuleID;joinReferences:RoleID" json:"roles,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName ตั้งชื่อตารางเอง เพราะชื่อที่ gorm สร้างจาก SoDRule อ่านยาก (so_d_rules)
+func (SoDRule) TableName() string {
+	return "sod_rules"
+}
+
+// RoleIDs คืน ID ของบทบาทในกฎ
+func (r *SoDRule) RoleIDs() []uint {
+	ids := make([]uint, 0, len(r.Roles))
+	for _, role := range r.Roles {
+		ids = append(ids, role.ID)
+	}
+	return ids
+}
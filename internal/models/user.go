@@ -8,14 +8,16 @@ import (
 )
 
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Username  string    `gorm:"uniqueIndex;not null" json:"username"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	FullName  string    `json:"full_name"`
-	Roles     []Role    `gorm:"many2many:user_roles;" json:"roles,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Username       string    `gorm:"uniqueIndex;not null" json:"username"`
+	Email          string    `gorm:"uniqueIndex;not null" json:"email"`
+	Password       string    `gorm:"not null" json:"-"`
+	FullName       string    `json:"full_name"`
+	OrganizationID *uint     `gorm:"index" json:"organization_id"`         // nil = ผู้ใช้ระดับ global
+	System         bool      `gorm:"not null;default:false" json:"system"` // ผู้ใช้ที่ระบบสร้าง ลบหรือเปลี่ยน username ไม่ได้
+	Roles          []Role    `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // SetPassword เข้ารหัส password ด้วย bcrypt
@@ -49,10 +51,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 // ToResponse คืนค่า user โดยไม่มีข้อมูลที่ sensitive
 func (u *User) ToResponse() map[string]interface{} {
 	return map[string]interface{}{
-		"id":        u.ID,
-		"username":  u.Username,
-		"email":     u.Email,
-		"full_name": u.FullName,
-		"roles":     u.Roles,
+		"id":              u.ID,
+		"username":        u.Username,
+		"email":           u.Email,
+		"full_name":       u.FullName,
+		"organization_id": u.OrganizationID,
+		"system":          u.System,
+		"roles":           u.Roles,
 	}
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// UserRole คือการกำหนดบทบาทให้ผู้ใช้ (ตาราง user_roles) พร้อมช่วงเวลาที่การกำหนดมีผล
+type UserRole struct {
+	UserID     uint       `gorm:"primaryKey" json:"user_id"`
+	RoleID     uint       `gorm:"primaryKey" json:"role_id"`
+	ValidFrom  *time.Time `json:"valid_from"`               // nil = มีผลทันที
+	ValidUntil *time.Time `gorm:"index" json:"valid_until"` // nil = ไม่มีวันหมดอายุ
+	GrantedBy  *uint      `json:"granted_by"`
+	Reason     string     `json:"reason"`
+	Role       *Role      `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsActive ตรวจสอบว่าการกำหนดบทบาทมีผล ณ เวลาที่ระบุหรือไม่
+func (ur *UserRole) IsActive(now time.Time) bool {
+	if ur.ValidFrom != nil && ur.ValidFrom.After(now) {
+		return false
+	}
+	if ur.ValidUntil != nil && !ur.ValidUntil.After(now) {
+		return false
+	}
+	return true
+}
@@ -1,10 +1,14 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/pkg/adminapi"
 	"gorm.io/gorm"
 )
 
@@ -89,3 +93,42 @@ func TestUser_ToResponse(t *testing.T) {
 	_, hasUpdatedAt := response["updated_at"]
 	assert.False(t, hasUpdatedAt)
 }
+
+// TestAdminAPIMatchesModels response ใน pkg/adminapi ต้องอ่าน JSON ที่ handlers ส่งออกได้ครบทุก field
+func TestAdminAPIMatchesModels(t *testing.T) {
+	now := time.Now()
+	orgID := uint(3)
+	grantedBy := uint(1)
+	permission := Permission{ID: 1, Resource: "users", Action: "read", Description: "Read users", System: true, CreatedAt: now, UpdatedAt: now}
+	role := Role{
+		ID: 2, Name: "viewer", Description: "Viewer", OrganizationID: &orgID, CreatedAt: now, UpdatedAt: now,
+		Permissions: []Permission{permission},
+		DelegableBy: []Role{{ID: 4, Name: "manager", CreatedAt: now, UpdatedAt: now}},
+	}
+	user := User{ID: 5, Username: "testuser", Email: "test@example.com", Password: "hashed", FullName: "Test User", OrganizationID: &orgID, Roles: []Role{role}}
+
+	cases := []struct {
+		name     string
+		model    any
+		response any
+	}{
+		{"user", user.ToResponse(), &adminapi.User{}},
+		{"role", role, &adminapi.Role{}},
+		{"permission", permission, &adminapi.Permission{}},
+		{"assignment", UserRole{UserID: 5, RoleID: 2, ValidUntil: &now, GrantedBy: &grantedBy, Reason: "audit", Role: &role, CreatedAt: now}, &adminapi.RoleAssignment{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.model)
+			require.NoError(t, err)
+
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			require.NoError(t, decoder.Decode(tc.response))
+
+			roundTrip, err := json.Marshal(tc.response)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(data), string(roundTrip))
+		})
+	}
+}
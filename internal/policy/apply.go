@@ -0,0 +1,384 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/service"
+	"gorm.io/gorm"
+)
+
+// ประเภทการเปลี่ยนแปลงในแผน
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+	// ChangeKeep สิ่งที่ไม่อยู่ใน policy แต่ไม่ถูกลบ (สิทธิ์ที่บทบาทของ tenant ยังถืออยู่ Detail คือชื่อบทบาทเหล่านั้น)
+	ChangeKeep = "keep"
+)
+
+// errDryRun ใช้ rollback transaction ของการ dry-run
+var errDryRun = errors.New("policy dry run")
+
+// SystemRecordError คืนเมื่อ policy จะลบสิทธิ์หรือบทบาทที่ระบบสร้าง (system) ซึ่งลบไม่ได้
+type SystemRecordError struct {
+	Records []string
+}
+
+func (e *SystemRecordError) Error() string {
+	return fmt.Sprintf("policy would delete system records: %s", strings.Join(e.Records, ", "))
+}
+
+// Change การเปลี่ยนแปลงหนึ่งรายการ Kind เป็น permission, role, grant (สิทธิ์ของบทบาท)
+// assignment (ผู้ใช้ที่ถูกถอนบทบาท Detail คือ username) หรือ group_role (กลุ่มที่ถูกถอนบทบาท Detail คือชื่อกลุ่ม)
+type Change struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Plan ผลการ apply หรือ dry-run
+type Plan struct {
+	DryRun  bool     `json:"dry_run"`
+	Changes []Change `json:"changes"`
+}
+
+func (p *Plan) add(action, kind, name, detail string) {
+	p.Changes = append(p.Changes, Change{Action: action, Kind: kind, Name: name, Detail: detail})
+}
+
+// ApplyOptions ตัวเลือกของ Apply
+type ApplyOptions struct {
+	// ActorID ผู้ apply ต้องมีสิทธิ์ที่มีอยู่แล้วทุกข้อที่จะเพิ่มให้บทบาท nil = ระบบ (เช่น seed) ไม่ตรวจสอบ
+	ActorID *uint
+	// DryRun ทำทุกขั้นตอนใน transaction แล้ว rollback เพื่อดูแผนและ error ที่จะเกิด
+	DryRun bool
+	// CreateOnly สร้างเฉพาะสิทธิ์และบทบาทที่ยังไม่มี ไม่แก้ไขหรือลบของเดิม (ใช้ตอน seed)
+	CreateOnly bool
+}
+
+// Apply ปรับสิทธิ์และบทบาทระดับ global ในฐานข้อมูลให้ตรงกับ policy ภายใน transaction เดียว
+// สิทธิ์และบทบาทที่ไม่อยู่ใน policy จะถูกลบ ยกเว้นเป็น system ซึ่งทำให้ apply ล้มเหลวทั้งหมด
+// บทบาทของ tenant ไม่ถูกแตะต้อง สิทธิ์ที่บทบาทของ tenant ยังถืออยู่จึงถูกเก็บไว้และรายงานใน plan เป็น keep
+func Apply(db *gorm.DB, policy *Policy, options ApplyOptions) (*Plan, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{DryRun: options.DryRun, Changes: []Change{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := apply(tx, policy, options, plan); err != nil {
+			return err
+		}
+		if options.DryRun {
+			return errDryRun
+		}
+		if len(plan.Changes) == 0 {
+			return nil
+		}
+		return service.RecordAudit(tx, options.ActorID, "policy.apply", "policy", 0, map[string]interface{}{"changes": plan.Changes})
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func apply(tx *gorm.DB, policy *Policy, options ApplyOptions, plan *Plan) error {
+	var existingPermissions []models.Permission
+	if err := tx.Find(&existingPermissions).Error; err != nil {
+		return err
+	}
+	var existingRoles []models.Role
+	if err := tx.Preload("Permissions").Where("organization_id IS NULL").Find(&existingRoles).Error; err != nil {
+		return err
+	}
+
+	permissions := make(map[string]models.Permission, len(existingPermissions))
+	for _, perm := range existingPermissions {
+		permissions[perm.Key()] = perm
+	}
+	roles := make(map[string]models.Role, len(existingRoles))
+	for _, role := range existingRoles {
+		roles[role.Name] = role
+	}
+
+	// สิ่งที่ต้องลบ ตรวจสอบ system ก่อนเปลี่ยนแปลงใดๆ
+	var staleRoles []models.Role
+	var stalePermissions []models.Permission
+	if !options.CreateOnly {
+		desiredRoles := make(map[string]bool, len(policy.Roles))
+		for _, role := range policy.Roles {
+			desiredRoles[role.Name] = true
+		}
+		desiredPermissions := make(map[string]bool, len(policy.Permissions))
+		for _, perm := range policy.Permissions {
+			desiredPermissions[perm.Key()] = true
+		}
+
+		var systemRecords []string
+		for _, role := range existingRoles {
+			if !desiredRoles[role.Name] {
+				if role.System {
+					systemRecords = append(systemRecords, "role "+role.Name)
+				}
+				staleRoles = append(staleRoles, role)
+			}
+		}
+		for _, perm := range existingPermissions {
+			if !desiredPermissions[perm.Key()] {
+				if perm.System {
+					systemRecords = append(systemRecords, "permission "+perm.Key())
+				}
+				stalePermissions = append(stalePermissions, perm)
+			}
+		}
+		if len(systemRecords) > 0 {
+			sort.Strings(systemRecords)
+			return &SystemRecordError{Records: systemRecords}
+		}
+	}
+
+	// สิทธิ์ทั้งหมดหลังการ apply ใช้แปลง * ในสิทธิ์ของบทบาท
+	// โหมด CreateOnly ไม่ลบสิทธิ์เดิม จึงรวมสิทธิ์ที่มีอยู่แล้วด้วย
+	var keys []string
+	for _, perm := range policy.Permissions {
+		keys = append(keys, perm.Key())
+	}
+	if options.CreateOnly {
+		for key := range permissions {
+			if !containsKey(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	if options.ActorID != nil {
+		var granted []models.Permission
+		seen := make(map[string]bool)
+		for _, role := range policy.Roles {
+			current, exists := roles[role.Name]
+			if exists && options.CreateOnly {
+				continue
+			}
+			held := heldKeys(current.Permissions)
+			for _, key := range resolvePatterns(role.Permissions, keys) {
+				perm, exists := permissions[key]
+				// สิทธิ์ที่ policy สร้างขึ้นใหม่ยังไม่มีใครถือ ผู้ apply เป็นผู้สร้างจึงให้ได้
+				if !exists || held[key] || seen[key] {
+					continue
+				}
+				seen[key] = true
+				granted = append(granted, perm)
+			}
+		}
+		if err := service.CheckCanGrantPermissions(tx, *options.ActorID, granted); err != nil {
+			return err
+		}
+	}
+
+	// สิทธิ์
+	for _, desired := range policy.Permissions {
+		current, exists := permissions[desired.Key()]
+		if !exists {
+			perm := models.Permission{Resource: desired.Resource, Action: desired.Action, Description: desired.Description}
+			if err := tx.Create(&perm).Error; err != nil {
+				return err
+			}
+			permissions[perm.Key()] = perm
+			plan.add(ChangeCreate, "permission", perm.Key(), "")
+		} else if !options.CreateOnly && current.Description != desired.Description {
+			if err := tx.Model(&current).Update("description", desired.Description).Error; err != nil {
+				return err
+			}
+			plan.add(ChangeUpdate, "permission", current.Key(), "description")
+		}
+	}
+
+	// บทบาทและสิทธิ์ของบทบาท
+	for _, desired := range policy.Roles {
+		current, exists := roles[desired.Name]
+		if exists && options.CreateOnly {
+			continue
+		}
+		if !exists {
+			current = models.Role{Name: desired.Name, Description: desired.Description}
+			if err := tx.Omit("Permissions", "DelegableBy").Create(&current).Error; err != nil {
+				return err
+			}
+			plan.add(ChangeCreate, "role", current.Name, "")
+		} else if current.Description != desired.Description {
+			if err := tx.Model(&current).Update("description", desired.Description).Error; err != nil {
+				return err
+			}
+			plan.add(ChangeUpdate, "role", current.Name, "description")
+		}
+
+		wanted := resolvePatterns(desired.Permissions, keys)
+		held := heldKeys(current.Permissions)
+		var additions []models.Permission
+		for _, key := range wanted {
+			if !held[key] {
+				additions = append(additions, permissions[key])
+				plan.add(ChangeCreate, "grant", current.Name, key)
+			}
+		}
+		if len(additions) > 0 {
+			if err := tx.Model(&current).Association("Permissions").Append(additions); err != nil {
+				return err
+			}
+		}
+
+		wantedSet := make(map[string]bool, len(wanted))
+		for _, key := range wanted {
+			wantedSet[key] = true
+		}
+		var removals []models.Permission
+		for _, perm := range current.Permissions {
+			if !wantedSet[perm.Key()] {
+				removals = append(removals, perm)
+				plan.add(ChangeDelete, "grant", current.Name, perm.Key())
+			}
+		}
+		if len(removals) > 0 {
+			if err := tx.Model(&current).Association("Permissions").Delete(removals); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, role := range staleRoles {
+		if err := deleteRole(tx, role, options.ActorID, plan); err != nil {
+			return err
+		}
+		plan.add(ChangeDelete, "role", role.Name, "")
+	}
+	// สิทธิ์ที่บทบาทของ tenant ยังถืออยู่ไม่ถูกลบ เพราะ policy ไม่ได้ดูแลบทบาทของ tenant
+	// บทบาทระดับ global ถูกถอนสิทธิ์นั้นไปแล้วข้างบน จึงเหลือเพียงบทบาทของ tenant ที่ถือ
+	for _, perm := range stalePermissions {
+		var tenantRoles []string
+		err := tx.Table("role_permissions").
+			Joins("JOIN roles ON roles.id = role_permissions.role_id").
+			Where("role_permissions.permission_id = ? AND roles.organization_id IS NOT NULL", perm.ID).
+			Order("roles.name").
+			Pluck("roles.name", &tenantRoles).Error
+		if err != nil {
+			return err
+		}
+		if len(tenantRoles) > 0 {
+			plan.add(ChangeKeep, "permission", perm.Key(), strings.Join(tenantRoles, ", "))
+			continue
+		}
+
+		if err := tx.Exec("DELETE FROM role_permissions WHERE permission_id = ?", perm.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&perm).Error; err != nil {
+			return err
+		}
+		plan.add(ChangeDelete, "permission", perm.Key(), "")
+	}
+
+	if options.CreateOnly {
+		return nil
+	}
+	return service.EnsureRoleManagerRemains(tx)
+}
+
+// deleteRole ลบบทบาทพร้อมข้อมูลที่อ้างถึงบทบาทนั้นทั้งหมด ผู้ใช้ที่ถือบทบาทถูกถอนผ่าน AssignmentService
+// เพื่อให้มี audit log ของแต่ละคน และทั้งผู้ใช้และกลุ่มที่เสียบทบาทถูกระบุในแผน (รวมถึงตอน dry-run)
+func deleteRole(tx *gorm.DB, role models.Role, actorID *uint, plan *Plan) error {
+	var holders []struct {
+		UserID   uint
+		Username string
+	}
+	err := tx.Table("user_roles").
+		Select("user_roles.user_id, users.username").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.role_id = ?", role.ID).
+		Order("users.username").
+		Scan(&holders).Error
+	if err != nil {
+		return err
+	}
+	assignments := service.NewAssignmentService(tx)
+	for _, holder := range holders {
+		if err := assignments.RevokeRole(holder.UserID, role.ID, actorID, "role removed by policy"); err != nil {
+			return err
+		}
+		plan.add(ChangeDelete, "assignment", role.Name, holder.Username)
+	}
+
+	var groupNames []string
+	err = tx.Table("group_roles").
+		Joins("JOIN groups ON groups.id = group_roles.group_id").
+		Where("group_roles.role_id = ?", role.ID).
+		Order("groups.name").
+		Pluck("groups.name", &groupNames).Error
+	if err != nil {
+		return err
+	}
+	for _, name := range groupNames {
+		plan.add(ChangeDelete, "group_role", role.Name, name)
+	}
+
+	// Apply ล้างแคชทั้งหมดหลัง commit จึงไม่ต้องใช้รายชื่อผู้ถือบทบาทที่คืนมา
+	_, err = service.DeleteRole(tx, &role)
+	return err
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, existing := range keys {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+func heldKeys(permissions []models.Permission) map[string]bool {
+	held := make(map[string]bool, len(permissions))
+	for _, perm := range permissions {
+		held[perm.Key()] = true
+	}
+	return held
+}
+
+// Export สร้าง policy จากสิทธิ์และบทบาทระดับ global ในฐานข้อมูลปัจจุบัน (ไม่ใช้ *)
+func Export(db *gorm.DB) (*Policy, error) {
+	var permissions []models.Permission
+	if err := db.Order("resource, action").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	var roles []models.Role
+	if err := db.Preload("Permissions").Where("organization_id IS NULL").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{Permissions: []Permission{}, Roles: []Role{}}
+	for _, perm := range permissions {
+		policy.Permissions = append(policy.Permissions, Permission{
+			Resource:    perm.Resource,
+			Action:      perm.Action,
+			Description: perm.Description,
+		})
+	}
+	for _, role := range roles {
+		keys := make([]string, 0, len(role.Permissions))
+		for _, perm := range role.Permissions {
+			keys = append(keys, perm.Key())
+		}
+		sort.Strings(keys)
+		policy.Roles = append(policy.Roles, Role{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: keys,
+		})
+	}
+	return policy, nil
+}
@@ -0,0 +1,138 @@
+// Package policy จัดการสิทธิ์และบทบาทระดับ global แบบ declarative (policy as code)
+// ไฟล์ policy ระบุสิทธิ์ บทบาท และสิทธิ์ของแต่ละบทบาท Apply จะปรับฐานข้อมูลให้ตรงกับไฟล์ และ Export สร้างไฟล์จากสถานะปัจจุบัน
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// Policy เนื้อหาของไฟล์ policy (YAML หรือ JSON)
+type Policy struct {
+	Permissions []Permission `yaml:"permissions" json:"permissions"`
+	Roles       []Role       `yaml:"roles" json:"roles"`
+}
+
+// Permission สิทธิ์หนึ่งข้อ
+type Permission struct {
+	Resource    string `yaml:"resource" json:"resource"`
+	Action      string `yaml:"action" json:"action"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+func (p Permission) Key() string {
+	return p.Resource + ":" + p.Action
+}
+
+// Role บทบาทระดับ global และสิทธิ์ของบทบาทในรูป resource:action
+// ใช้ * แทน resource หรือ action ได้ เช่น "*" (ทุกสิทธิ์) หรือ "*:read" (สิทธิ์อ่านทั้งหมด)
+type Role struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+// Parse อ่านไฟล์ policy รูปแบบ YAML หรือ JSON (JSON เป็น YAML ที่ถูกต้องอยู่แล้ว) และตรวจสอบความถูกต้อง
+func Parse(data []byte) (*Policy, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var policy Policy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// YAML แปลง policy เป็น YAML ในรูปแบบเดียวกับที่ Parse อ่านได้
+func (p *Policy) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), encoder.Close()
+}
+
+// Validate ตรวจสอบว่าไม่มีสิทธิ์หรือบทบาทซ้ำ และสิทธิ์ของทุกบทบาทอ้างถึงสิทธิ์ที่มีใน policy
+func (p *Policy) Validate() error {
+	keys := make(map[string]bool, len(p.Permissions))
+	for _, perm := range p.Permissions {
+		if perm.Resource == "" || perm.Action == "" ||
+			strings.ContainsAny(perm.Resource, ":*") || strings.ContainsAny(perm.Action, ":*") {
+			return fmt.Errorf("%w: permission %q must have a resource and action without ':' or '*'", ErrInvalidPolicy, perm.Key())
+		}
+		if keys[perm.Key()] {
+			return fmt.Errorf("%w: duplicate permission %q", ErrInvalidPolicy, perm.Key())
+		}
+		keys[perm.Key()] = true
+	}
+
+	names := make(map[string]bool, len(p.Roles))
+	for _, role := range p.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("%w: role without a name", ErrInvalidPolicy)
+		}
+		if names[role.Name] {
+			return fmt.Errorf("%w: duplicate role %q", ErrInvalidPolicy, role.Name)
+		}
+		names[role.Name] = true
+
+		for _, pattern := range role.Permissions {
+			if !validPattern(pattern) {
+				return fmt.Errorf("%w: role %q has invalid permission %q", ErrInvalidPolicy, role.Name, pattern)
+			}
+			// pattern ที่ไม่มี * ต้องตรงกับสิทธิ์ใน policy ส่วน * อาจตรงกับสิทธิ์ที่มีอยู่แล้วในฐานข้อมูล
+			if !strings.Contains(pattern, "*") && !keys[pattern] {
+				return fmt.Errorf("%w: role %q refers to undeclared permission %q", ErrInvalidPolicy, role.Name, pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	resource, action, found := strings.Cut(pattern, ":")
+	return found && resource != "" && action != ""
+}
+
+// matchPattern ตรวจสอบว่า key รูปแบบ resource:action ตรงกับ pattern หรือไม่
+func matchPattern(pattern string, key string) bool {
+	if pattern == "*" || pattern == key {
+		return true
+	}
+	patternResource, patternAction, _ := strings.Cut(pattern, ":")
+	resource, action, _ := strings.Cut(key, ":")
+	return (patternResource == "*" || patternResource == resource) &&
+		(patternAction == "*" || patternAction == action)
+}
+
+// resolvePatterns แปลง pattern ของบทบาทเป็นรายการ key ที่เรียงแล้ว
+func resolvePatterns(patterns []string, keys []string) []string {
+	var resolved []string
+	for _, key := range keys {
+		for _, pattern := range patterns {
+			if matchPattern(pattern, key) {
+				resolved = append(resolved, key)
+				break
+			}
+		}
+	}
+	sort.Strings(resolved)
+	return resolved
+}
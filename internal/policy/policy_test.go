@@ -0,0 +1,326 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestParse(t *testing.T) {
+	parsed, err := Parse([]byte(`
+permissions:
+  - resource: users
+    action: read
+  - resource: users
+    action: write
+roles:
+  - name: viewer
+    permissions: ["*:read"]
+`))
+	require.NoError(t, err)
+	assert.Len(t, parsed.Permissions, 2)
+	assert.Equal(t, []string{"*:read"}, parsed.Roles[0].Permissions)
+
+	// JSON เป็น YAML ที่ถูกต้อง
+	parsed, err = Parse([]byte(`{"permissions": [{"resource": "users", "action": "read"}], "roles": [{"name": "viewer", "permissions": ["users:read"]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "viewer", parsed.Roles[0].Name)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":          "permissions: []\nrole: []\n",
+		"duplicate permission":   "permissions:\n  - {resource: users, action: read}\n  - {resource: users, action: read}\n",
+		"undeclared permission":  "permissions: []\nroles:\n  - {name: viewer, permissions: [users:read]}\n",
+		"wildcard in permission": "permissions:\n  - {resource: '*', action: read}\n",
+		"duplicate role":         "roles:\n  - {name: viewer}\n  - {name: viewer}\n",
+	}
+	for name, data := range cases {
+		_, err := Parse([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidPolicy, name)
+	}
+}
+
+func TestResolvePatterns(t *testing.T) {
+	keys := []string{"roles:read", "roles:write", "users:read", "users:write"}
+
+	assert.Equal(t, keys, resolvePatterns([]string{"*"}, keys))
+	assert.Equal(t, []string{"roles:read", "users:read"}, resolvePatterns([]string{"*:read"}, keys))
+	assert.Equal(t, []string{"users:read", "users:write"}, resolvePatterns([]string{"users:*", "users:read"}, keys))
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	original := &Policy{
+		Permissions: []Permission{{Resource: "users", Action: "read", Description: "อ่านข้อมูลผู้ใช้"}},
+		Roles:       []Role{{Name: "viewer", Permissions: []string{"users:read"}}},
+	}
+
+	data, err := original.YAML()
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, original, parsed)
+}
+
+func TestApply_RefusesToDeleteSystemRecords(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action", "system"}).
+			AddRow(1, "users", "read", true).
+			AddRow(2, "reports", "read", false))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE organization_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "system"}).AddRow(1, "admin", true))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}))
+	mock.ExpectRollback()
+
+	plan, err := Apply(db, &Policy{
+		Permissions: []Permission{{Resource: "reports", Action: "read"}},
+	}, ApplyOptions{DryRun: true})
+
+	assert.Nil(t, plan)
+	var systemErr *SystemRecordError
+	require.ErrorAs(t, err, &systemErr)
+	assert.Equal(t, []string{"permission users:read", "role admin"}, systemErr.Records)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// setupApplyTest สร้าง gorm.DB ที่ต่อกับ sqlmock และตรวจว่า expectation ครบเมื่อจบการทดสอบ
+func setupApplyTest(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	return db, mock
+}
+
+// expectRoleManagerRemains คาดหวังการตรวจ EnsureRoleManagerRemains ที่พบผู้จัดการบทบาทโดยตรง
+func expectRoleManagerRemains(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE id IN \(SELECT "user_id" FROM "user_roles"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+}
+
+var viewerPolicy = &Policy{
+	Permissions: []Permission{{Resource: "users", Action: "read"}},
+	Roles:       []Role{{Name: "viewer", Permissions: []string{"users:*"}}},
+}
+
+// expectCreateViewer คาดหวังการสร้างสิทธิ์ users:read และบทบาท viewer บนฐานข้อมูลว่าง
+func expectCreateViewer(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE organization_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery(`INSERT INTO "permissions"`).
+		WithArgs("users", "read", "", false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "roles"`).
+		WithArgs("viewer", "", nil, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`UPDATE "roles" SET "updated_at"=\$1 WHERE "id" = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "permissions" .* ON CONFLICT DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`INSERT INTO "role_permissions" \("role_id","permission_id"\) VALUES \(\$1,\$2\) ON CONFLICT DO NOTHING`).
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRoleManagerRemains(mock)
+}
+
+func TestApply_CreatesMissingRecords(t *testing.T) {
+	db, mock := setupApplyTest(t)
+
+	expectCreateViewer(mock)
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	plan, err := Apply(db, viewerPolicy, ApplyOptions{})
+	require.NoError(t, err)
+
+	assert.False(t, plan.DryRun)
+	assert.Equal(t, []Change{
+		{Action: ChangeCreate, Kind: "permission", Name: "users:read"},
+		{Action: ChangeCreate, Kind: "role", Name: "viewer"},
+		{Action: ChangeCreate, Kind: "grant", Name: "viewer", Detail: "users:read"},
+	}, plan.Changes)
+}
+
+func TestApply_DryRunRollsBack(t *testing.T) {
+	db, mock := setupApplyTest(t)
+
+	// dry-run ทำทุกขั้นตอนเหมือนจริงแต่ rollback และไม่บันทึก audit log
+	expectCreateViewer(mock)
+	mock.ExpectRollback()
+
+	plan, err := Apply(db, viewerPolicy, ApplyOptions{DryRun: true})
+	require.NoError(t, err)
+
+	assert.True(t, plan.DryRun)
+	assert.Len(t, plan.Changes, 3)
+}
+
+func TestApply_Idempotent(t *testing.T) {
+	db, mock := setupApplyTest(t)
+
+	// ฐานข้อมูลตรงกับ policy อยู่แล้ว การ apply ซ้ำไม่มีการเปลี่ยนแปลงและไม่บันทึก audit log
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(1, "users", "read"))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE organization_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "viewer"))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(1, 1))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(1, "users", "read"))
+	expectRoleManagerRemains(mock)
+	mock.ExpectCommit()
+
+	plan, err := Apply(db, viewerPolicy, ApplyOptions{})
+	require.NoError(t, err)
+
+	assert.Empty(t, plan.Changes)
+}
+
+func TestApply_UpdatesAndDeletes(t *testing.T) {
+	db, mock := setupApplyTest(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action", "description"}).
+			AddRow(1, "users", "read", "old").
+			AddRow(2, "reports", "read", ""))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE organization_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "viewer").AddRow(2, "legacy"))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(1, 1).AddRow(1, 2).AddRow(2, 2))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(1, "users", "read").AddRow(2, "reports", "read"))
+
+	// คำอธิบายของสิทธิ์ที่เปลี่ยน และสิทธิ์ของบทบาทที่ไม่อยู่ใน policy แล้ว
+	mock.ExpectExec(`UPDATE "permissions" SET "description"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs("Read users", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1 AND "role_permissions"\."permission_id" = \$2`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// บทบาทที่ไม่อยู่ใน policy: ถอนจากผู้ใช้ทีละคนพร้อม audit log แล้วลบข้อมูลที่อ้างถึง
+	mock.ExpectQuery(`SELECT user_roles.user_id, users.username FROM "user_roles" JOIN users`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username"}).AddRow(5, "alice"))
+	mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2`).
+		WithArgs(5, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRoleManagerRemains(mock)
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(nil, "role.revoked", "user", 5, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT "groups"."name" FROM "group_roles" JOIN groups`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ops"))
+	mock.ExpectQuery(`SELECT "user_id" FROM "user_roles" WHERE role_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery(`SELECT "group_id" FROM "group_roles" WHERE role_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(10))
+	mock.ExpectQuery(`SELECT "id" FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT "user_id" FROM "group_members" WHERE group_id IN \(\$1\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(6))
+	mock.ExpectExec(`DELETE FROM "user_roles" WHERE role_id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "group_roles" WHERE role_id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "sod_rule_roles" WHERE role_id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE "access_requests" SET "decided_at"=\$1,"decision_note"=\$2,"status"=\$3,"updated_at"=\$4 WHERE role_id = \$5 AND status = \$6`).
+		WithArgs(sqlmock.AnyArg(), "role deleted", models.AccessRequestDenied, sqlmock.AnyArg(), 2, models.AccessRequestPending).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM role_delegations`).WithArgs(2, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "roles" WHERE "roles"\."id" = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// สิทธิ์ที่ไม่อยู่ใน policy และไม่มีบทบาทของ tenant ถือ
+	mock.ExpectQuery(`SELECT "roles"\."name" FROM "role_permissions" JOIN roles ON roles\.id = role_permissions\.role_id WHERE role_permissions\.permission_id = \$1 AND roles\.organization_id IS NOT NULL ORDER BY roles\.name`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectExec(`DELETE FROM role_permissions WHERE permission_id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM "permissions" WHERE "permissions"\."id" = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	expectRoleManagerRemains(mock)
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(nil, "policy.apply", "policy", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	plan, err := Apply(db, &Policy{
+		Permissions: []Permission{{Resource: "users", Action: "read", Description: "Read users"}},
+		Roles:       []Role{{Name: "viewer", Permissions: []string{"users:read"}}},
+	}, ApplyOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Change{
+		{Action: ChangeUpdate, Kind: "permission", Name: "users:read", Detail: "description"},
+		{Action: ChangeDelete, Kind: "grant", Name: "viewer", Detail: "reports:read"},
+		{Action: ChangeDelete, Kind: "assignment", Name: "legacy", Detail: "alice"},
+		{Action: ChangeDelete, Kind: "group_role", Name: "legacy", Detail: "ops"},
+		{Action: ChangeDelete, Kind: "role", Name: "legacy"},
+		{Action: ChangeDelete, Kind: "permission", Name: "reports:read"},
+	}, plan.Changes)
+}
+
+func TestApply_KeepsPermissionHeldByTenantRole(t *testing.T) {
+	db, mock := setupApplyTest(t)
+
+	// reports:read ไม่อยู่ใน policy แต่บทบาทของ tenant ยังถืออยู่ จึงไม่ถูกลบและถูกรายงานใน plan
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).
+			AddRow(1, "users", "read").
+			AddRow(2, "reports", "read"))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE organization_id IS NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "viewer"))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(1, 1))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(1, "users", "read"))
+	mock.ExpectQuery(`SELECT "roles"\."name" FROM "role_permissions" JOIN roles`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("acme-auditor").AddRow("globex-auditor"))
+	expectRoleManagerRemains(mock)
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(nil, "policy.apply", "policy", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	plan, err := Apply(db, viewerPolicy, ApplyOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []Change{
+		{Action: ChangeKeep, Kind: "permission", Name: "reports:read", Detail: "acme-auditor, globex-auditor"},
+	}, plan.Changes)
+}
@@ -0,0 +1,130 @@
+package rebac
+
+import (
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore Store บน PostgreSQL (ตาราง relation_tuples และ relation_revisions)
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Write(writes []Tuple, deletes []Tuple) (Revision, error) {
+	var revision Revision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// เขียนทีละรายการ เพื่อให้ลำดับ revision ตรงกับลำดับการ commit
+		// ผู้อ่านที่ได้ revision N จะไม่พลาดการเขียนที่ revision น้อยกว่า N ซึ่ง commit ทีหลัง
+		if err := tx.Exec("LOCK TABLE relation_revisions IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		record := models.RelationRevision{}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		revision = Revision(record.ID)
+		deletedAt := record.ID
+
+		for _, tuple := range deletes {
+			err := tx.Model(&models.RelationTuple{}).
+				Scopes(matchTuple(tuple)).
+				Where("deleted_revision IS NULL").
+				Update("deleted_revision", deletedAt).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, tuple := range writes {
+			row := toRecord(tuple)
+			row.CreatedRevision = record.ID
+			// tuple ที่มีอยู่แล้วไม่ถือเป็น error (เขียนซ้ำได้)
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return revision, err
+}
+
+func (s *DBStore) Read(filter Filter, at Revision) ([]Tuple, error) {
+	query := s.db.Model(&models.RelationTuple{}).Scopes(liveAt(at))
+	if filter.Namespace != "" {
+		query = query.Where("namespace = ?", filter.Namespace)
+	}
+	if filter.ObjectID != "" {
+		query = query.Where("object_id = ?", filter.ObjectID)
+	}
+	if filter.Relation != "" {
+		query = query.Where("relation = ?", filter.Relation)
+	}
+
+	var rows []models.RelationTuple
+	if err := query.Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	tuples := make([]Tuple, 0, len(rows))
+	for _, row := range rows {
+		tuples = append(tuples, fromRecord(row))
+	}
+	return tuples, nil
+}
+
+func (s *DBStore) ObjectIDs(namespace string, at Revision) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&models.RelationTuple{}).
+		Scopes(liveAt(at)).
+		Where("namespace = ?", namespace).
+		Distinct("object_id").
+		Order("object_id").
+		Pluck("object_id", &ids).Error
+	return ids, err
+}
+
+func (s *DBStore) LatestRevision() (Revision, error) {
+	var latest uint64
+	err := s.db.Model(&models.RelationRevision{}).Select("COALESCE(MAX(id), 0)").Scan(&latest).Error
+	return Revision(latest), err
+}
+
+// liveAt เลือก tuple ที่ถูกสร้างแล้วและยังไม่ถูกลบ ณ revision ที่ระบุ
+func liveAt(at Revision) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("created_revision <= ?", uint64(at)).
+			Where("deleted_revision IS NULL OR deleted_revision > ?", uint64(at))
+	}
+}
+
+func matchTuple(tuple Tuple) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("namespace = ? AND object_id = ? AND relation = ?", tuple.Object.Namespace, tuple.Object.ID, tuple.Relation).
+			Where("subject_namespace = ? AND subject_object_id = ? AND subject_relation = ?", tuple.Subject.Namespace, tuple.Subject.ID, tuple.Subject.Relation)
+	}
+}
+
+func toRecord(tuple Tuple) models.RelationTuple {
+	return models.RelationTuple{
+		Namespace:        tuple.Object.Namespace,
+		ObjectID:         tuple.Object.ID,
+		Relation:         tuple.Relation,
+		SubjectNamespace: tuple.Subject.Namespace,
+		SubjectObjectID:  tuple.Subject.ID,
+		SubjectRelation:  tuple.Subject.Relation,
+	}
+}
+
+func fromRecord(row models.RelationTuple) Tuple {
+	return Tuple{
+		Object:   Object{Namespace: row.Namespace, ID: row.ObjectID},
+		Relation: row.Relation,
+		Subject:  Subject{Namespace: row.SubjectNamespace, ID: row.SubjectObjectID, Relation: row.SubjectRelation},
+	}
+}
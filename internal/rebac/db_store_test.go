@@ -0,0 +1,144 @@
+package rebac
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupDBStore(t *testing.T) (*DBStore, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	return NewDBStore(db), mock
+}
+
+var tupleColumns = []string{"id", "namespace", "object_id", "relation", "subject_namespace", "subject_object_id", "subject_relation", "created_revision", "deleted_revision"}
+
+func TestDBStore_Write(t *testing.T) {
+	store, mock := setupDBStore(t)
+	tuples := mustTuples(t, "folder:4#owner@user:1", "document:7#parent@folder:4")
+
+	// ล็อกตาราง revision ก่อน เพื่อให้ revision เรียงตามลำดับการ commit แล้วลบและเขียนด้วย revision เดียวกัน
+	mock.ExpectBegin()
+	mock.ExpectExec(`LOCK TABLE relation_revisions IN EXCLUSIVE MODE`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO "relation_revisions" \("created_at"\) VALUES \(\$1\) RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+	mock.ExpectExec(`UPDATE "relation_tuples" SET "deleted_revision"=\$1 WHERE deleted_revision IS NULL AND \(namespace = \$2 AND object_id = \$3 AND relation = \$4\) AND \(subject_namespace = \$5 AND subject_object_id = \$6 AND subject_relation = \$7\)`).
+		WithArgs(12, "document", "7", "parent", "folder", "4", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "relation_tuples" .* ON CONFLICT DO NOTHING RETURNING "id"`).
+		WithArgs("folder", "4", "owner", "user", "1", "", 12, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+	mock.ExpectCommit()
+
+	revision, err := store.Write(tuples[:1], tuples[1:])
+	require.NoError(t, err)
+	assert.Equal(t, Revision(12), revision)
+}
+
+func TestDBStore_WriteRollsBackOnError(t *testing.T) {
+	store, mock := setupDBStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`LOCK TABLE relation_revisions`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO "relation_revisions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(13))
+	mock.ExpectQuery(`INSERT INTO "relation_tuples"`).
+		WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	_, err := store.Write(mustTuples(t, "folder:4#owner@user:1"), nil)
+	assert.Error(t, err)
+}
+
+func TestDBStore_ReadAtRevision(t *testing.T) {
+	store, mock := setupDBStore(t)
+
+	// tuple ที่ถูกสร้างหลัง revision หรือถูกลบก่อนหรือ ณ revision ต้องไม่ถูกอ่าน
+	mock.ExpectQuery(`SELECT \* FROM "relation_tuples" WHERE namespace = \$1 AND object_id = \$2 AND created_revision <= \$3 AND \(deleted_revision IS NULL OR deleted_revision > \$4\) ORDER BY id`).
+		WithArgs("folder", "4", 5, 5).
+		WillReturnRows(sqlmock.NewRows(tupleColumns).
+			AddRow(1, "folder", "4", "owner", "user", "1", "", 2, nil).
+			AddRow(2, "folder", "4", "viewer", "team", "9", "member", 3, 8))
+
+	tuples, err := store.Read(Filter{Namespace: "folder", ObjectID: "4"}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, mustTuples(t, "folder:4#owner@user:1", "folder:4#viewer@team:9#member"), tuples)
+}
+
+func TestDBStore_ObjectIDs(t *testing.T) {
+	store, mock := setupDBStore(t)
+
+	mock.ExpectQuery(`SELECT DISTINCT "object_id" FROM "relation_tuples" WHERE namespace = \$1 AND created_revision <= \$2 AND \(deleted_revision IS NULL OR deleted_revision > \$3\) ORDER BY object_id`).
+		WithArgs("document", 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"object_id"}).AddRow("1").AddRow("7"))
+
+	ids, err := store.ObjectIDs("document", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "7"}, ids)
+}
+
+func TestDBStore_LatestRevision(t *testing.T) {
+	store, mock := setupDBStore(t)
+
+	// ยังไม่เคยเขียน = revision 0
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(id\), 0\) FROM "relation_revisions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(0))
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(id\), 0\) FROM "relation_revisions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(12))
+
+	revision, err := store.LatestRevision()
+	require.NoError(t, err)
+	assert.Equal(t, Revision(0), revision)
+
+	revision, err = store.LatestRevision()
+	require.NoError(t, err)
+	assert.Equal(t, Revision(12), revision)
+}
+
+func TestDBStore_ConsistencyToken(t *testing.T) {
+	store, mock := setupDBStore(t)
+	engine, err := NewEngine(store, testNamespaces())
+	require.NoError(t, err)
+
+	latest := func(revision int) {
+		mock.ExpectQuery(`SELECT COALESCE\(MAX\(id\), 0\) FROM "relation_revisions"`).
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(revision))
+	}
+	readAt := func(revision int) {
+		mock.ExpectQuery(`SELECT \* FROM "relation_tuples" WHERE namespace = \$1 AND created_revision <= \$2`).
+			WithArgs("folder", revision, revision).
+			WillReturnRows(sqlmock.NewRows(tupleColumns))
+	}
+
+	// token ที่ไม่ exact อ่าน ณ revision ล่าสุด
+	latest(9)
+	readAt(9)
+	_, revision, err := engine.Read(Filter{Namespace: "folder"}, Consistency{Token: "5"})
+	require.NoError(t, err)
+	assert.Equal(t, Revision(9), revision)
+
+	// exact อ่าน ณ revision ของ token พอดี
+	latest(9)
+	readAt(5)
+	_, revision, err = engine.Read(Filter{Namespace: "folder"}, Consistency{Token: "5", Exact: true})
+	require.NoError(t, err)
+	assert.Equal(t, Revision(5), revision)
+
+	// token ที่ใหม่กว่า revision ล่าสุดไม่ได้ออกโดยระบบนี้
+	latest(9)
+	_, _, err = engine.Read(Filter{Namespace: "folder"}, Consistency{Token: "10"})
+	assert.ErrorIs(t, err, ErrInvalidConsistencyToken)
+}
@@ -0,0 +1,322 @@
+package rebac
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxDepth จำนวนชั้นสูงสุดของการไล่ userset ก่อนถือว่า configuration หรือข้อมูลซับซ้อนเกินไป
+const maxDepth = 25
+
+var ErrMaxDepthExceeded = errors.New("relation graph is too deep to evaluate")
+
+// Consistency ระบุว่าจะอ่านข้อมูล ณ revision ใด
+// Token ว่าง = revision ล่าสุด, มี Token = อย่างน้อยใหม่เท่า Token, Exact = อ่าน ณ revision ของ Token พอดี
+type Consistency struct {
+	Token string
+	Exact bool
+}
+
+// ExpandNode โครงสร้างต้นไม้ของ userset ที่ได้จาก Expand
+type ExpandNode struct {
+	Operation string        `json:"operation"` // union, this, computed_userset, tuple_to_userset
+	Object    string        `json:"object"`
+	Relation  string        `json:"relation"`
+	Subjects  []string      `json:"subjects,omitempty"`
+	Children  []*ExpandNode `json:"children,omitempty"`
+}
+
+// Engine ประเมินความสัมพันธ์ตาม namespace configuration บน Store ที่กำหนด
+type Engine struct {
+	store      Store
+	namespaces namespaceSet
+}
+
+// NewEngine ตรวจสอบ namespace configuration แล้วสร้าง Engine
+func NewEngine(store Store, namespaces []Namespace) (*Engine, error) {
+	set, err := newNamespaceSet(namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{store: store, namespaces: set}, nil
+}
+
+// Write ตรวจสอบ tuple กับ namespace configuration แล้วเขียนลง store คืน revision ของการเขียน
+func (e *Engine) Write(writes []Tuple, deletes []Tuple) (Revision, error) {
+	for _, tuple := range writes {
+		if err := e.validateTuple(tuple); err != nil {
+			return 0, err
+		}
+	}
+	return e.store.Write(writes, deletes)
+}
+
+// Read คืน tuple ที่ตรงเงื่อนไข ณ revision ที่เลือกตาม consistency
+func (e *Engine) Read(filter Filter, consistency Consistency) ([]Tuple, Revision, error) {
+	at, err := e.snapshot(consistency)
+	if err != nil {
+		return nil, 0, err
+	}
+	tuples, err := e.store.Read(filter, at)
+	return tuples, at, err
+}
+
+// Check ตรวจสอบว่า subject มี relation กับ object หรือไม่
+func (e *Engine) Check(object Object, relation string, subject Subject, consistency Consistency) (bool, Revision, error) {
+	if _, err := e.namespaces.relation(object.Namespace, relation); err != nil {
+		return false, 0, err
+	}
+	at, err := e.snapshot(consistency)
+	if err != nil {
+		return false, 0, err
+	}
+
+	allowed, err := e.check(object, relation, subject, at, map[string]bool{}, 0)
+	return allowed, at, err
+}
+
+// Expand คืนต้นไม้ของ userset ทั้งหมดที่มี relation กับ object
+func (e *Engine) Expand(object Object, relation string, consistency Consistency) (*ExpandNode, Revision, error) {
+	if _, err := e.namespaces.relation(object.Namespace, relation); err != nil {
+		return nil, 0, err
+	}
+	at, err := e.snapshot(consistency)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	node, err := e.expand(object, relation, at, map[string]bool{}, 0)
+	return node, at, err
+}
+
+// ListObjects คืน ID ของวัตถุใน namespace ที่ subject มี relation ด้วย
+// ประเมินทีละวัตถุที่มี tuple อยู่ จึงเหมาะกับ namespace ที่มีวัตถุไม่มาก
+func (e *Engine) ListObjects(namespace string, relation string, subject Subject, consistency Consistency) ([]string, Revision, error) {
+	if _, err := e.namespaces.relation(namespace, relation); err != nil {
+		return nil, 0, err
+	}
+	at, err := e.snapshot(consistency)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	candidates, err := e.store.ObjectIDs(namespace, at)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	objects := []string{}
+	for _, id := range candidates {
+		allowed, err := e.check(Object{Namespace: namespace, ID: id}, relation, subject, at, map[string]bool{}, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+		if allowed {
+			objects = append(objects, id)
+		}
+	}
+	return objects, at, nil
+}
+
+// snapshot เลือก revision ที่จะใช้อ่าน token ที่ใหม่กว่า revision ล่าสุดถือว่าไม่ถูกต้อง
+func (e *Engine) snapshot(consistency Consistency) (Revision, error) {
+	latest, err := e.store.LatestRevision()
+	if err != nil {
+		return 0, err
+	}
+	if consistency.Token == "" {
+		return latest, nil
+	}
+
+	requested, err := ParseToken(consistency.Token)
+	if err != nil {
+		return 0, err
+	}
+	if requested > latest {
+		return 0, ErrInvalidConsistencyToken
+	}
+	if consistency.Exact {
+		return requested, nil
+	}
+	return latest, nil
+}
+
+func (e *Engine) validateTuple(tuple Tuple) error {
+	relation, err := e.namespaces.relation(tuple.Object.Namespace, tuple.Relation)
+	if err != nil {
+		return err
+	}
+	if !relation.allowsDirect() {
+		return fmt.Errorf("%w: %s#%s is computed and cannot be written directly", ErrInvalidTuple, tuple.Object.Namespace, tuple.Relation)
+	}
+	if tuple.Subject.IsUserset() {
+		_, err := e.namespaces.relation(tuple.Subject.Namespace, tuple.Subject.Relation)
+		return err
+	}
+	if tuple.Subject.Namespace != UserNamespace {
+		if _, ok := e.namespaces[tuple.Subject.Namespace]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownNamespace, tuple.Subject.Namespace)
+		}
+	}
+	return nil
+}
+
+// check ประเมิน relation ของ object ตาม rewrite visited ป้องกันการวนซ้ำในเส้นทางเดียวกัน
+func (e *Engine) check(object Object, relation string, subject Subject, at Revision, visited map[string]bool, depth int) (bool, error) {
+	if depth > maxDepth {
+		return false, ErrMaxDepthExceeded
+	}
+	key := object.String() + "#" + relation
+	if visited[key] {
+		return false, nil
+	}
+	visited[key] = true
+	defer delete(visited, key)
+
+	definition, err := e.namespaces.relation(object.Namespace, relation)
+	if err != nil {
+		return false, err
+	}
+	rewrite := Rewrite{This: true}
+	if definition.Rewrite != nil {
+		rewrite = *definition.Rewrite
+	}
+
+	return e.checkRewrite(rewrite, object, relation, subject, at, visited, depth)
+}
+
+func (e *Engine) checkRewrite(rewrite Rewrite, object Object, relation string, subject Subject, at Revision, visited map[string]bool, depth int) (bool, error) {
+	switch {
+	case rewrite.This:
+		tuples, err := e.store.Read(Filter{Namespace: object.Namespace, ObjectID: object.ID, Relation: relation}, at)
+		if err != nil {
+			return false, err
+		}
+		for _, tuple := range tuples {
+			if tuple.Subject == subject {
+				return true, nil
+			}
+			if tuple.Subject.IsUserset() {
+				allowed, err := e.check(tuple.Subject.Object(), tuple.Subject.Relation, subject, at, visited, depth+1)
+				if err != nil || allowed {
+					return allowed, err
+				}
+			}
+		}
+		return false, nil
+
+	case rewrite.ComputedUserset != "":
+		return e.check(object, rewrite.ComputedUserset, subject, at, visited, depth+1)
+
+	case rewrite.TupleToUserset != nil:
+		tuples, err := e.store.Read(Filter{Namespace: object.Namespace, ObjectID: object.ID, Relation: rewrite.TupleToUserset.Tupleset}, at)
+		if err != nil {
+			return false, err
+		}
+		for _, tuple := range tuples {
+			target := tuple.Subject.Object()
+			if _, err := e.namespaces.relation(target.Namespace, rewrite.TupleToUserset.ComputedUserset); err != nil {
+				continue // วัตถุปลายทางไม่มี relation นี้ ไม่ให้สิทธิ์
+			}
+			allowed, err := e.check(target, rewrite.TupleToUserset.ComputedUserset, subject, at, visited, depth+1)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+
+	default:
+		for _, child := range rewrite.Union {
+			allowed, err := e.checkRewrite(child, object, relation, subject, at, visited, depth)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+	}
+}
+
+func (e *Engine) expand(object Object, relation string, at Revision, visited map[string]bool, depth int) (*ExpandNode, error) {
+	if depth > maxDepth {
+		return nil, ErrMaxDepthExceeded
+	}
+	key := object.String() + "#" + relation
+	if visited[key] {
+		// วงจร: แสดงเป็นโหนดว่างเพื่อไม่ให้ต้นไม้ไม่มีที่สิ้นสุด
+		return &ExpandNode{Operation: "this", Object: object.String(), Relation: relation}, nil
+	}
+	visited[key] = true
+	defer delete(visited, key)
+
+	definition, err := e.namespaces.relation(object.Namespace, relation)
+	if err != nil {
+		return nil, err
+	}
+	rewrite := Rewrite{This: true}
+	if definition.Rewrite != nil {
+		rewrite = *definition.Rewrite
+	}
+
+	return e.expandRewrite(rewrite, object, relation, at, visited, depth)
+}
+
+func (e *Engine) expandRewrite(rewrite Rewrite, object Object, relation string, at Revision, visited map[string]bool, depth int) (*ExpandNode, error) {
+	node := &ExpandNode{Object: object.String(), Relation: relation}
+
+	switch {
+	case rewrite.This:
+		node.Operation = "this"
+		tuples, err := e.store.Read(Filter{Namespace: object.Namespace, ObjectID: object.ID, Relation: relation}, at)
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range tuples {
+			node.Subjects = append(node.Subjects, tuple.Subject.String())
+			if tuple.Subject.IsUserset() {
+				child, err := e.expand(tuple.Subject.Object(), tuple.Subject.Relation, at, visited, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, child)
+			}
+		}
+
+	case rewrite.ComputedUserset != "":
+		node.Operation = "computed_userset"
+		child, err := e.expand(object, rewrite.ComputedUserset, at, visited, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+
+	case rewrite.TupleToUserset != nil:
+		node.Operation = "tuple_to_userset"
+		tuples, err := e.store.Read(Filter{Namespace: object.Namespace, ObjectID: object.ID, Relation: rewrite.TupleToUserset.Tupleset}, at)
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range tuples {
+			target := tuple.Subject.Object()
+			if _, err := e.namespaces.relation(target.Namespace, rewrite.TupleToUserset.ComputedUserset); err != nil {
+				continue
+			}
+			child, err := e.expand(target, rewrite.TupleToUserset.ComputedUserset, at, visited, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		}
+
+	default:
+		node.Operation = "union"
+		for _, rewriteChild := range rewrite.Union {
+			child, err := e.expandRewrite(rewriteChild, object, relation, at, visited, depth)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		}
+	}
+
+	return node, nil
+}
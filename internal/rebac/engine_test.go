@@ -0,0 +1,151 @@
+package rebac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNamespaces: สมาชิก team, folder ที่ owner เป็น viewer, และ document ที่สืบ viewer จาก parent folder
+func testNamespaces() []Namespace {
+	return []Namespace{
+		{Name: "team", Relations: []Relation{{Name: "member"}}},
+		{Name: "folder", Relations: []Relation{
+			{Name: "owner"},
+			{Name: "viewer", Rewrite: &Rewrite{Union: []Rewrite{
+				{This: true},
+				{ComputedUserset: "owner"},
+			}}},
+		}},
+		{Name: "document", Relations: []Relation{
+			{Name: "parent"},
+			{Name: "owner"},
+			{Name: "viewer", Rewrite: &Rewrite{Union: []Rewrite{
+				{This: true},
+				{ComputedUserset: "owner"},
+				{TupleToUserset: &TupleToUserset{Tupleset: "parent", ComputedUserset: "viewer"}},
+			}}},
+		}},
+	}
+}
+
+func mustTuples(t *testing.T, values ...string) []Tuple {
+	tuples := make([]Tuple, 0, len(values))
+	for _, value := range values {
+		tuple, err := ParseTuple(value)
+		require.NoError(t, err)
+		tuples = append(tuples, tuple)
+	}
+	return tuples
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	engine, err := NewEngine(NewMemoryStore(), testNamespaces())
+	require.NoError(t, err)
+	return engine
+}
+
+func TestParseTuple(t *testing.T) {
+	tuple, err := ParseTuple("folder:4#owner@team:9#member")
+	require.NoError(t, err)
+	assert.Equal(t, Object{Namespace: "folder", ID: "4"}, tuple.Object)
+	assert.Equal(t, "owner", tuple.Relation)
+	assert.Equal(t, Subject{Namespace: "team", ID: "9", Relation: "member"}, tuple.Subject)
+	assert.Equal(t, "folder:4#owner@team:9#member", tuple.String())
+
+	for _, invalid := range []string{"folder:4#owner", "folder#owner@user:1", "folder:4@user:1", "folder:4#owner@user:1#"} {
+		_, err := ParseTuple(invalid)
+		assert.ErrorIs(t, err, ErrInvalidTuple, invalid)
+	}
+}
+
+func TestCheck_TeamOwnsFolderContainingDocument(t *testing.T) {
+	engine := newTestEngine(t)
+	_, err := engine.Write(mustTuples(t,
+		"team:9#member@user:7",
+		"folder:4#owner@team:9#member",
+		"document:123#parent@folder:4",
+	), nil)
+	require.NoError(t, err)
+
+	document := Object{Namespace: "document", ID: "123"}
+	allowed, _, err := engine.Check(document, "viewer", Subject{Namespace: UserNamespace, ID: "7"}, Consistency{})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, _, err = engine.Check(document, "viewer", Subject{Namespace: UserNamespace, ID: "8"}, Consistency{})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// owner ของ document ไม่ได้มาจาก folder
+	allowed, _, err = engine.Check(document, "owner", Subject{Namespace: UserNamespace, ID: "7"}, Consistency{})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestCheck_ExactSnapshot(t *testing.T) {
+	engine := newTestEngine(t)
+	granted, err := engine.Write(mustTuples(t, "document:1#viewer@user:7"), nil)
+	require.NoError(t, err)
+	_, err = engine.Write(nil, mustTuples(t, "document:1#viewer@user:7"))
+	require.NoError(t, err)
+
+	document := Object{Namespace: "document", ID: "1"}
+	user := Subject{Namespace: UserNamespace, ID: "7"}
+
+	allowed, _, err := engine.Check(document, "viewer", user, Consistency{Token: granted.Token()})
+	require.NoError(t, err)
+	assert.False(t, allowed, "at-least-as-fresh reads the latest revision")
+
+	allowed, at, err := engine.Check(document, "viewer", user, Consistency{Token: granted.Token(), Exact: true})
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, granted, at)
+
+	_, _, err = engine.Check(document, "viewer", user, Consistency{Token: "99"})
+	assert.ErrorIs(t, err, ErrInvalidConsistencyToken)
+}
+
+func TestWrite_RejectsUnknownRelation(t *testing.T) {
+	engine := newTestEngine(t)
+
+	_, err := engine.Write(mustTuples(t, "document:1#editor@user:7"), nil)
+	assert.ErrorIs(t, err, ErrUnknownRelation)
+
+	_, err = engine.Write(mustTuples(t, "document:1#viewer@group:1#member"), nil)
+	assert.ErrorIs(t, err, ErrUnknownNamespace)
+}
+
+func TestListObjectsAndExpand(t *testing.T) {
+	engine := newTestEngine(t)
+	_, err := engine.Write(mustTuples(t,
+		"team:9#member@user:7",
+		"folder:4#owner@team:9#member",
+		"document:1#parent@folder:4",
+		"document:2#owner@user:8",
+		"document:3#viewer@user:7",
+	), nil)
+	require.NoError(t, err)
+
+	objects, _, err := engine.ListObjects("document", "viewer", Subject{Namespace: UserNamespace, ID: "7"}, Consistency{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "3"}, objects)
+
+	tree, _, err := engine.Expand(Object{Namespace: "folder", ID: "4"}, "viewer", Consistency{})
+	require.NoError(t, err)
+	assert.Equal(t, "union", tree.Operation)
+	require.Len(t, tree.Children, 2)
+	owner := tree.Children[1].Children[0]
+	assert.Equal(t, []string{"team:9#member"}, owner.Subjects)
+	assert.Equal(t, []string{"user:7"}, owner.Children[0].Subjects)
+}
+
+func TestNewEngine_InvalidRewrite(t *testing.T) {
+	_, err := NewEngine(NewMemoryStore(), []Namespace{
+		{Name: "document", Relations: []Relation{
+			{Name: "viewer", Rewrite: &Rewrite{ComputedUserset: "owner"}},
+		}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package rebac
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownNamespace = errors.New("unknown namespace")
+	ErrUnknownRelation  = errors.New("unknown relation")
+)
+
+// Namespace นิยามประเภทวัตถุและ relation ที่มีได้ เช่น document มี owner, parent, viewer
+type Namespace struct {
+	Name      string     `mapstructure:"name" json:"name"`
+	Relations []Relation `mapstructure:"relations" json:"relations"`
+}
+
+// Relation นิยาม relation หนึ่งตัว ถ้าไม่มี Rewrite จะได้เฉพาะ tuple ที่เขียนไว้โดยตรง (this)
+type Relation struct {
+	Name    string   `mapstructure:"name" json:"name"`
+	Rewrite *Rewrite `mapstructure:"rewrite" json:"rewrite,omitempty"`
+}
+
+// Rewrite กฎการคำนวณ userset ของ relation ต้องกำหนดอย่างใดอย่างหนึ่งเท่านั้น
+//   - This: tuple ที่เขียนไว้โดยตรงกับ relation นี้
+//   - ComputedUserset: ผู้ที่มี relation อื่นกับวัตถุเดียวกัน (เช่น owner เป็น viewer ด้วย)
+//   - TupleToUserset: ตาม tuple ของ relation หนึ่งไปยังวัตถุอื่น แล้วใช้ relation ของวัตถุนั้น (เช่น viewer ของ parent folder)
+//   - Union: รวมผลของกฎย่อยทั้งหมด
+type Rewrite struct {
+	This            bool            `mapstructure:"this" json:"this,omitempty"`
+	ComputedUserset string          `mapstructure:"computedUserset" json:"computed_userset,omitempty"`
+	TupleToUserset  *TupleToUserset `mapstructure:"tupleToUserset" json:"tuple_to_userset,omitempty"`
+	Union           []Rewrite       `mapstructure:"union" json:"union,omitempty"`
+}
+
+// TupleToUserset อ่าน tuple ของ relation Tupleset แล้วใช้ relation ComputedUserset ของวัตถุปลายทาง
+type TupleToUserset struct {
+	Tupleset        string `mapstructure:"tupleset" json:"tupleset"`
+	ComputedUserset string `mapstructure:"computedUserset" json:"computed_userset"`
+}
+
+// namespaceSet namespace configuration ที่ตรวจสอบแล้ว จัดเก็บแบบค้นหาด้วยชื่อ
+type namespaceSet map[string]map[string]Relation
+
+// newNamespaceSet ตรวจสอบ configuration ว่าทุก relation ที่อ้างถึงมีอยู่จริง
+func newNamespaceSet(namespaces []Namespace) (namespaceSet, error) {
+	set := make(namespaceSet, len(namespaces))
+	for _, namespace := range namespaces {
+		if namespace.Name == "" || namespace.Name == UserNamespace {
+			return nil, fmt.Errorf("invalid namespace name %q", namespace.Name)
+		}
+		if _, exists := set[namespace.Name]; exists {
+			return nil, fmt.Errorf("duplicate namespace %q", namespace.Name)
+		}
+		relations := make(map[string]Relation, len(namespace.Relations))
+		for _, relation := range namespace.Relations {
+			if relation.Name == "" {
+				return nil, fmt.Errorf("namespace %q has a relation without a name", namespace.Name)
+			}
+			if _, exists := relations[relation.Name]; exists {
+				return nil, fmt.Errorf("namespace %q has duplicate relation %q", namespace.Name, relation.Name)
+			}
+			relations[relation.Name] = relation
+		}
+		set[namespace.Name] = relations
+	}
+
+	for name, relations := range set {
+		for _, relation := range relations {
+			if relation.Rewrite == nil {
+				continue
+			}
+			if err := validateRewrite(*relation.Rewrite, relations); err != nil {
+				return nil, fmt.Errorf("namespace %q relation %q: %w", name, relation.Name, err)
+			}
+		}
+	}
+
+	return set, nil
+}
+
+func validateRewrite(rewrite Rewrite, relations map[string]Relation) error {
+	kinds := 0
+	if rewrite.This {
+		kinds++
+	}
+	if rewrite.ComputedUserset != "" {
+		kinds++
+		if _, ok := relations[rewrite.ComputedUserset]; !ok {
+			return fmt.Errorf("computed userset refers to unknown relation %q", rewrite.ComputedUserset)
+		}
+	}
+	if rewrite.TupleToUserset != nil {
+		kinds++
+		if _, ok := relations[rewrite.TupleToUserset.Tupleset]; !ok {
+			return fmt.Errorf("tuple-to-userset refers to unknown tupleset relation %q", rewrite.TupleToUserset.Tupleset)
+		}
+		// relation ปลายทางอยู่ใน namespace ของวัตถุปลายทาง ซึ่งรู้ได้ตอนประเมินเท่านั้น
+		if rewrite.TupleToUserset.ComputedUserset == "" {
+			return errors.New("tuple-to-userset needs a computed userset relation")
+		}
+	}
+	if len(rewrite.Union) > 0 {
+		kinds++
+		for _, child := range rewrite.Union {
+			if err := validateRewrite(child, relations); err != nil {
+				return err
+			}
+		}
+	}
+	if kinds != 1 {
+		return errors.New("rewrite must set exactly one of this, computedUserset, tupleToUserset or union")
+	}
+	return nil
+}
+
+// relation คืนนิยามของ relation หรือ error ถ้าไม่มี namespace หรือ relation นี้
+func (s namespaceSet) relation(namespace string, name string) (Relation, error) {
+	relations, ok := s[namespace]
+	if !ok {
+		return Relation{}, fmt.Errorf("%w: %s", ErrUnknownNamespace, namespace)
+	}
+	relation, ok := relations[name]
+	if !ok {
+		return Relation{}, fmt.Errorf("%w: %s#%s", ErrUnknownRelation, namespace, name)
+	}
+	return relation, nil
+}
+
+// allowsDirect บอกว่า relation รับ tuple ที่เขียนโดยตรงหรือไม่ (ไม่มี rewrite หรือ rewrite มี this)
+func (r Relation) allowsDirect() bool {
+	return r.Rewrite == nil || r.Rewrite.includesThis()
+}
+
+func (r Rewrite) includesThis() bool {
+	if r.This {
+		return true
+	}
+	for _, child := range r.Union {
+		if child.includesThis() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package rebac
+
+import (
+	"sort"
+	"sync"
+)
+
+// Filter เงื่อนไขการอ่าน tuple ช่องที่ว่างหมายถึงไม่กรอง
+type Filter struct {
+	Namespace string
+	ObjectID  string
+	Relation  string
+}
+
+func (f Filter) matches(tuple Tuple) bool {
+	return (f.Namespace == "" || f.Namespace == tuple.Object.Namespace) &&
+		(f.ObjectID == "" || f.ObjectID == tuple.Object.ID) &&
+		(f.Relation == "" || f.Relation == tuple.Relation)
+}
+
+// Store ที่เก็บ tuple ที่อ่านสถานะ ณ revision ใดก็ได้
+type Store interface {
+	// Write เพิ่มและลบ tuple ในการเขียนครั้งเดียว แล้วคืน revision ใหม่
+	Write(writes []Tuple, deletes []Tuple) (Revision, error)
+	// Read คืน tuple ที่มีผล ณ revision ที่ระบุ
+	Read(filter Filter, at Revision) ([]Tuple, error)
+	// ObjectIDs คืน ID ของวัตถุทั้งหมดใน namespace ที่มี tuple ณ revision ที่ระบุ
+	ObjectIDs(namespace string, at Revision) ([]string, error)
+	// LatestRevision คืน revision ล่าสุด
+	LatestRevision() (Revision, error)
+}
+
+// memoryTuple tuple พร้อมช่วง revision ที่มีผล
+type memoryTuple struct {
+	tuple   Tuple
+	created Revision
+	deleted Revision // 0 = ยังไม่ถูกลบ
+}
+
+func (t memoryTuple) liveAt(at Revision) bool {
+	return t.created <= at && (t.deleted == 0 || t.deleted > at)
+}
+
+// MemoryStore Store ในหน่วยความจำ ใช้ในการทดสอบและการรันแบบไม่มีฐานข้อมูล
+type MemoryStore struct {
+	mu       sync.RWMutex
+	tuples   []memoryTuple
+	revision Revision
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Write(writes []Tuple, deletes []Tuple) (Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revision++
+	for _, tuple := range deletes {
+		for i := range s.tuples {
+			if s.tuples[i].deleted == 0 && s.tuples[i].tuple == tuple {
+				s.tuples[i].deleted = s.revision
+			}
+		}
+	}
+	for _, tuple := range writes {
+		if !s.liveLocked(tuple) {
+			s.tuples = append(s.tuples, memoryTuple{tuple: tuple, created: s.revision})
+		}
+	}
+	return s.revision, nil
+}
+
+func (s *MemoryStore) liveLocked(tuple Tuple) bool {
+	for _, existing := range s.tuples {
+		if existing.deleted == 0 && existing.tuple == tuple {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) Read(filter Filter, at Revision) ([]Tuple, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tuples []Tuple
+	for _, existing := range s.tuples {
+		if existing.liveAt(at) && filter.matches(existing.tuple) {
+			tuples = append(tuples, existing.tuple)
+		}
+	}
+	return tuples, nil
+}
+
+func (s *MemoryStore) ObjectIDs(namespace string, at Revision) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, existing := range s.tuples {
+		if existing.liveAt(at) && existing.tuple.Object.Namespace == namespace && !seen[existing.tuple.Object.ID] {
+			seen[existing.tuple.Object.ID] = true
+			ids = append(ids, existing.tuple.Object.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) LatestRevision() (Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision, nil
+}
@@ -0,0 +1,121 @@
+package rebac
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UserNamespace namespace ของผู้ใช้ (subject ปลายทาง) ไม่ต้องกำหนดใน namespace configuration
+const UserNamespace = "user"
+
+var (
+	ErrInvalidTuple            = errors.New("invalid relation tuple")
+	ErrInvalidConsistencyToken = errors.New("invalid consistency token")
+)
+
+// Object วัตถุที่มีความสัมพันธ์ เช่น document:123
+type Object struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+}
+
+func (o Object) String() string {
+	return o.Namespace + ":" + o.ID
+}
+
+// Subject ผู้ที่มีความสัมพันธ์ เป็นผู้ใช้ (user:7) หรือ userset (team:9#member) เมื่อ Relation ไม่ว่าง
+type Subject struct {
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+	Relation  string `json:"relation,omitempty"`
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.ID
+	}
+	return s.Namespace + ":" + s.ID + "#" + s.Relation
+}
+
+// IsUserset บอกว่า subject อ้างถึงกลุ่มของผู้ที่มี relation กับวัตถุอื่น
+func (s Subject) IsUserset() bool {
+	return s.Relation != ""
+}
+
+// Object คืนวัตถุที่ subject อ้างถึง (ใช้เมื่อไล่ userset และ tuple-to-userset)
+func (s Subject) Object() Object {
+	return Object{Namespace: s.Namespace, ID: s.ID}
+}
+
+// Tuple ความสัมพันธ์ object#relation@subject
+type Tuple struct {
+	Object   Object  `json:"object"`
+	Relation string  `json:"relation"`
+	Subject  Subject `json:"subject"`
+}
+
+func (t Tuple) String() string {
+	return t.Object.String() + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// ParseObject แปลง "namespace:id" เป็น Object
+func ParseObject(value string) (Object, error) {
+	namespace, id, ok := strings.Cut(value, ":")
+	if !ok || namespace == "" || id == "" || strings.ContainsAny(id, "#@") {
+		return Object{}, fmt.Errorf("%w: object %q must be namespace:id", ErrInvalidTuple, value)
+	}
+	return Object{Namespace: namespace, ID: id}, nil
+}
+
+// ParseSubject แปลง "namespace:id" หรือ "namespace:id#relation" เป็น Subject
+func ParseSubject(value string) (Subject, error) {
+	objectPart, relation, hasRelation := strings.Cut(value, "#")
+	if hasRelation && relation == "" {
+		return Subject{}, fmt.Errorf("%w: subject %q has an empty relation", ErrInvalidTuple, value)
+	}
+	object, err := ParseObject(objectPart)
+	if err != nil {
+		return Subject{}, fmt.Errorf("%w: subject %q must be namespace:id or namespace:id#relation", ErrInvalidTuple, value)
+	}
+	return Subject{Namespace: object.Namespace, ID: object.ID, Relation: relation}, nil
+}
+
+// ParseTuple แปลง "namespace:id#relation@subject" เป็น Tuple
+func ParseTuple(value string) (Tuple, error) {
+	objectRelation, subjectPart, ok := strings.Cut(value, "@")
+	if !ok {
+		return Tuple{}, fmt.Errorf("%w: %q must be object#relation@subject", ErrInvalidTuple, value)
+	}
+	objectPart, relation, ok := strings.Cut(objectRelation, "#")
+	if !ok || relation == "" {
+		return Tuple{}, fmt.Errorf("%w: %q must be object#relation@subject", ErrInvalidTuple, value)
+	}
+	object, err := ParseObject(objectPart)
+	if err != nil {
+		return Tuple{}, err
+	}
+	subject, err := ParseSubject(subjectPart)
+	if err != nil {
+		return Tuple{}, err
+	}
+	return Tuple{Object: object, Relation: relation, Subject: subject}, nil
+}
+
+// Revision ลำดับการเขียน tuple ทุกครั้งที่เขียนจะได้ revision ใหม่ที่มากกว่าเดิม
+type Revision uint64
+
+// Token แปลง revision เป็น consistency token ที่ส่งให้ client (client ควรถือเป็นค่าทึบ)
+func (r Revision) Token() string {
+	return strconv.FormatUint(uint64(r), 10)
+}
+
+// ParseToken แปลง consistency token กลับเป็น revision
+func ParseToken(token string) (Revision, error) {
+	value, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidConsistencyToken
+	}
+	return Revision(value), nil
+}
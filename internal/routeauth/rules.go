@@ -0,0 +1,168 @@
+// Package routeauth จับคู่ request ของแอปที่อยู่หลัง proxy (host, method, path) กับสิทธิ์ resource:action ที่ต้องมี
+// ใช้กับ forward-auth (nginx auth_request / Traefik ForwardAuth) และ Envoy ext_authz
+package routeauth
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yourusername/auth-api/internal/models"
+)
+
+// นโยบายเมื่อไม่มีกฎใดตรงกับ request
+const (
+	PolicyDeny          = "deny"          // ปฏิเสธทุก request (403) ยกเว้นที่มีกฎ
+	PolicyAuthenticated = "authenticated" // อนุญาตผู้ใช้ที่ยืนยันตัวตนแล้วทุกคน
+)
+
+// Rule กฎหนึ่งข้อ กฎแรกที่ตรงกับ request เป็นตัวตัดสิน
+//   - Host: host ของแอป ใช้ wildcard แบบ path.Match ได้ เช่น *.example.com (ว่าง = ทุก host)
+//   - Methods: HTTP method ที่ตรงกับกฎ (ว่าง = ทุก method)
+//   - Path: เทียบทีละ segment แบบ path.Match และ /** ท้ายสุดตรงกับทุก path ที่อยู่ใต้ลงไป เช่น /reports/*/export หรือ /admin/**
+//   - Permission: สิทธิ์ resource:action ที่ต้องมี (ว่าง = ผู้ใช้ที่ยืนยันตัวตนแล้วทุกคน)
+//   - Public: ไม่ต้องยืนยันตัวตน
+type Rule struct {
+	Host       string   `mapstructure:"host" json:"host,omitempty"`
+	Methods    []string `mapstructure:"methods" json:"methods,omitempty"`
+	Path       string   `mapstructure:"path" json:"path"`
+	Permission string   `mapstructure:"permission" json:"permission,omitempty"`
+	Public     bool     `mapstructure:"public" json:"public,omitempty"`
+}
+
+// Requirement สิ่งที่ request ต้องผ่านตามกฎที่ตรงกัน
+type Requirement struct {
+	Public   bool // อนุญาตโดยไม่ต้องยืนยันตัวตน
+	Denied   bool // ไม่มีกฎที่ตรงกันและนโยบายคือ deny
+	Resource string
+	Action   string // Resource และ Action ว่าง = ผู้ใช้ที่ยืนยันตัวตนแล้วทุกคน
+}
+
+// NeedsPermission ตรวจว่าต้องมีสิทธิ์เฉพาะหรือไม่
+func (r Requirement) NeedsPermission() bool {
+	return r.Resource != ""
+}
+
+// Table กฎทั้งหมดที่ตรวจสอบความถูกต้องแล้ว
+type Table struct {
+	rules         []compiledRule
+	defaultPolicy string
+}
+
+type compiledRule struct {
+	Rule
+	segments    []string
+	requirement Requirement
+}
+
+// NewTable ตรวจสอบกฎและนโยบายเริ่มต้น (ว่าง = deny)
+func NewTable(rules []Rule, defaultPolicy string) (*Table, error) {
+	if defaultPolicy == "" {
+		defaultPolicy = PolicyDeny
+	}
+	if defaultPolicy != PolicyDeny && defaultPolicy != PolicyAuthenticated {
+		return nil, fmt.Errorf("default policy must be %q or %q", PolicyDeny, PolicyAuthenticated)
+	}
+
+	table := &Table{defaultPolicy: defaultPolicy}
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Path, err)
+		}
+		table.rules = append(table.rules, compiled)
+	}
+	return table, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	if !strings.HasPrefix(rule.Path, "/") {
+		return compiledRule{}, fmt.Errorf("path must start with /")
+	}
+	if _, err := path.Match(rule.Host, ""); err != nil {
+		return compiledRule{}, fmt.Errorf("invalid host pattern: %w", err)
+	}
+
+	segments := splitPath(rule.Path)
+	for i, segment := range segments {
+		if segment == "**" {
+			if i != len(segments)-1 {
+				return compiledRule{}, fmt.Errorf("** is only allowed as the last segment")
+			}
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return compiledRule{}, fmt.Errorf("invalid path pattern: %w", err)
+		}
+	}
+
+	methods := make([]string, 0, len(rule.Methods))
+	for _, method := range rule.Methods {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	rule.Methods = methods
+
+	requirement := Requirement{Public: rule.Public}
+	if rule.Permission != "" {
+		if rule.Public {
+			return compiledRule{}, fmt.Errorf("public rule cannot require a permission")
+		}
+		resource, action, ok := models.ParsePermissionKey(rule.Permission)
+		if !ok {
+			return compiledRule{}, fmt.Errorf("permission must be resource:action")
+		}
+		requirement.Resource, requirement.Action = resource, action
+	}
+	return compiledRule{Rule: rule, segments: segments, requirement: requirement}, nil
+}
+
+// Match คืนสิ่งที่ request ต้องผ่าน requestPath ต้องเป็น path ที่ไม่มี query string และ host อาจมี port ต่อท้าย
+// path ถูก clean ก่อนเทียบ เพื่อไม่ให้ข้ามกฎด้วย path เช่น /public/../admin
+func (t *Table) Match(method string, host string, requestPath string) Requirement {
+	method = strings.ToUpper(method)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	segments := splitPath(path.Clean("/" + requestPath))
+	for _, rule := range t.rules {
+		if rule.matches(method, host, segments) {
+			return rule.requirement
+		}
+	}
+	return Requirement{Denied: t.defaultPolicy == PolicyDeny}
+}
+
+func (r *compiledRule) matches(method string, host string, segments []string) bool {
+	if r.Host != "" {
+		if ok, _ := path.Match(strings.ToLower(r.Host), strings.ToLower(host)); !ok {
+			return false
+		}
+	}
+	if len(r.Methods) > 0 && !slices.Contains(r.Methods, method) {
+		return false
+	}
+
+	for i, pattern := range r.segments {
+		if pattern == "**" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if ok, _ := path.Match(pattern, segments[i]); !ok {
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
+
+// splitPath แยก path เป็น segment โดย "/" ได้รายการว่าง
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,70 @@
+package routeauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable_Match(t *testing.T) {
+	table, err := NewTable([]Rule{
+		{Path: "/static/**", Public: true},
+		{Path: "/healthz", Public: true},
+		{Host: "admin.example.com", Path: "/**", Permission: "admin:access"},
+		{Path: "/reports/*/export", Methods: []string{"get"}, Permission: "reports:export"},
+		{Path: "/reports/**", Methods: []string{"GET"}, Permission: "reports:read"},
+		{Path: "/reports/**", Permission: "reports:write"},
+		{Path: "/profile"},
+	}, PolicyDeny)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		method string
+		host   string
+		path   string
+		want   Requirement
+	}{
+		{"public prefix", "GET", "app.example.com", "/static/js/app.js", Requirement{Public: true}},
+		{"public exact", "GET", "", "/healthz", Requirement{Public: true}},
+		{"exact does not match subpath", "GET", "", "/healthz/db", Requirement{Denied: true}},
+		{"host with port", "GET", "admin.example.com:8443", "/settings", Requirement{Resource: "admin", Action: "access"}},
+		{"single segment wildcard", "get", "", "/reports/2024/export", Requirement{Resource: "reports", Action: "export"}},
+		{"wildcard does not span segments", "GET", "", "/reports/2024/q1/export", Requirement{Resource: "reports", Action: "read"}},
+		{"double star matches prefix itself", "GET", "", "/reports", Requirement{Resource: "reports", Action: "read"}},
+		{"method falls through", "DELETE", "", "/reports/1", Requirement{Resource: "reports", Action: "write"}},
+		{"authenticated only", "GET", "", "/profile", Requirement{}},
+		{"dot segments are cleaned", "GET", "", "/static/../profile/../reports/1", Requirement{Resource: "reports", Action: "read"}},
+		{"no rule", "GET", "", "/billing", Requirement{Denied: true}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, table.Match(tc.method, tc.host, tc.path))
+		})
+	}
+
+	authenticated, err := NewTable(nil, PolicyAuthenticated)
+	require.NoError(t, err)
+	assert.Equal(t, Requirement{}, authenticated.Match("GET", "", "/anything"))
+}
+
+func TestNewTable_Invalid(t *testing.T) {
+	cases := map[string]Rule{
+		"relative path":          {Path: "reports"},
+		"double star not last":   {Path: "/**/reports"},
+		"bad path pattern":       {Path: "/reports/[a"},
+		"bad host pattern":       {Host: "[a", Path: "/"},
+		"bad permission":         {Path: "/reports", Permission: "reports"},
+		"public with permission": {Path: "/reports", Public: true, Permission: "reports:read"},
+	}
+	for name, rule := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTable([]Rule{rule}, PolicyDeny)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewTable(nil, "allow")
+	assert.Error(t, err)
+}
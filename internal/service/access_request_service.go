@@ -0,0 +1,341 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAccessRequestNotFound   = errors.New("access request not found")
+	ErrAccessRequestNotPending = errors.New("access request is not pending")
+	ErrAccessRequestNotActive  = errors.New("access request is not approved")
+	ErrInvalidAccessDuration   = errors.New("duration must be positive and within the allowed maximum")
+	ErrSelfApproval            = errors.New("requesters cannot decide on their own access requests")
+	ErrRoleNotAvailable        = errors.New("role is not available to the requester's organization")
+	ErrConflictingRoleBinding  = errors.New("requester has a scheduled assignment of this role that the request would shorten")
+)
+
+// AccessRequestService จัดการคำขอยกระดับสิทธิ์ชั่วคราว ตั้งแต่การขอ การอนุมัติ/ปฏิเสธ
+// จนถึงการถอนสิทธิ์เมื่อหมดเวลา โดยใช้ AssignmentService ในการกำหนดบทบาทจริง
+type AccessRequestService struct {
+	db          *gorm.DB
+	assignments *AssignmentService
+	maxDuration time.Duration
+}
+
+func NewAccessRequestService(db *gorm.DB, assignments *AssignmentService, maxDuration time.Duration) *AccessRequestService {
+	s := &AccessRequestService{
+		db:          db,
+		assignments: assignments,
+		maxDuration: maxDuration,
+	}
+
+	// เมื่อ sweeper ลบบทบาทที่หมดอายุ ให้ปิดคำขอที่เกี่ยวข้องด้วย
+	assignments.OnExpired(s.markExpired)
+
+	return s
+}
+
+// CreateAccessRequestInput ข้อมูลสำหรับการขอสิทธิ์ชั่วคราว
+type CreateAccessRequestInput struct {
+	RequesterID   uint
+	RoleID        uint
+	Duration      time.Duration
+	Justification string
+}
+
+// AccessRequestFilter เงื่อนไขการค้นหาคำขอสิทธิ์
+type AccessRequestFilter struct {
+	RequesterID    *uint
+	OrganizationID *uint
+	Status         string
+}
+
+// Create สร้างคำขอสิทธิ์ชั่วคราวในสถานะ pending
+func (s *AccessRequestService) Create(input CreateAccessRequestInput) (*models.AccessRequest, error) {
+	if input.Duration <= 0 || (s.maxDuration > 0 && input.Duration > s.maxDuration) {
+		return nil, ErrInvalidAccessDuration
+	}
+
+	var requester models.User
+	if err := s.db.First(&requester, input.RequesterID).Error; err != nil {
+		return nil, err
+	}
+
+	var role models.Role
+	if err := s.db.First(&role, input.RoleID).Error; err != nil {
+		return nil, err
+	}
+	if !role.IsGlobal() && (requester.OrganizationID == nil || *role.OrganizationID != *requester.OrganizationID) {
+		return nil, ErrRoleNotAvailable
+	}
+
+	request := &models.AccessRequest{
+		RequesterID:     requester.ID,
+		RoleID:          role.ID,
+		Justification:   input.Justification,
+		DurationSeconds: int64(input.Duration / time.Second),
+		Status:          models.AccessRequestPending,
+		OrganizationID:  requester.OrganizationID,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(request).Error; err != nil {
+			return err
+		}
+		return RecordAudit(tx, &requester.ID, "access_request.created", "access_request", request.ID, map[string]interface{}{
+			"role_id":       role.ID,
+			"duration":      input.Duration.String(),
+			"justification": input.Justification,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Get ดึงคำขอสิทธิ์ตาม ID
+func (s *AccessRequestService) Get(id uint) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	if err := s.db.Preload("Role").First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccessRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// List ค้นหาคำขอสิทธิ์ตามเงื่อนไข เรียงจากใหม่ไปเก่า
+func (s *AccessRequestService) List(filter AccessRequestFilter) ([]models.AccessRequest, error) {
+	query := s.db.Preload("Role").Order("created_at DESC")
+	if filter.RequesterID != nil {
+		query = query.Where("requester_id = ?", *filter.RequesterID)
+	}
+	if filter.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var requests []models.AccessRequest
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// Approve อนุมัติคำขอและกำหนดบทบาทให้ผู้ขอจนถึงเวลาหมดอายุ
+func (s *AccessRequestService) Approve(id uint, approverID uint, note string) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.lockPending(tx, id, approverID, &request); err != nil {
+			return err
+		}
+		// ผู้อนุมัติต้องให้บทบาทนี้ได้เองด้วย มิฉะนั้นสิทธิ์อนุมัติคำขอจะกลายเป็นช่องทางยกระดับสิทธิ์ผู้อื่น
+		if err := CheckCanGrantRole(tx, approverID, request.RoleID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		expiresAt := now.Add(request.Duration())
+
+		// การกำหนดบทบาทที่มีอยู่ซึ่งครอบคลุม [now, expiresAt] แล้วไม่ต้องกำหนดซ้ำ
+		// ถ้าไม่ครอบคลุม จะแทนที่ได้เฉพาะเมื่อสิ้นสุดไม่หลัง expiresAt (การกำหนดใหม่ยาวกว่าส่วนที่เหลือ)
+		// มิฉะนั้น (เช่นบทบาทถาวรที่เริ่มในอนาคต) การแทนที่จะทำให้บทบาทสั้นลงและถูกถอนเมื่อคำขอหมดอายุ จึงปฏิเสธ
+		var existing models.UserRole
+		result := tx.Where("user_id = ? AND role_id = ?", request.RequesterID, request.RoleID).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		alreadyHeld := false
+		if result.RowsAffected > 0 {
+			endsAfterRequest := existing.ValidUntil == nil || !existing.ValidUntil.Before(expiresAt)
+			alreadyHeld = existing.IsActive(now) && endsAfterRequest
+			if !alreadyHeld && endsAfterRequest {
+				return ErrConflictingRoleBinding
+			}
+		}
+
+		if !alreadyHeld {
+			_, err := s.assignments.withDB(tx).AssignRole(AssignRoleInput{
+				UserID:     request.RequesterID,
+				RoleID:     request.RoleID,
+				ValidUntil: &expiresAt,
+				GrantedBy:  &approverID,
+				Reason:     accessRequestReason(request.ID, request.Justification),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&request).Updates(map[string]interface{}{
+			"status":        models.AccessRequestApproved,
+			"approver_id":   approverID,
+			"decision_note": note,
+			"decided_at":    now,
+			"expires_at":    expiresAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, &approverID, "access_request.approved", "access_request", request.ID, map[string]interface{}{
+			"requester_id": request.RequesterID,
+			"role_id":      request.RoleID,
+			"expires_at":   expiresAt,
+			"already_held": alreadyHeld,
+			"note":         note,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.assignments.notifyChanged(request.RequesterID)
+	return s.Get(id)
+}
+
+// Deny ปฏิเสธคำขอ
+func (s *AccessRequestService) Deny(id uint, approverID uint, note string) (*models.AccessRequest, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var request models.AccessRequest
+		if err := s.lockPending(tx, id, approverID, &request); err != nil {
+			return err
+		}
+
+		err := tx.Model(&request).Updates(map[string]interface{}{
+			"status":        models.AccessRequestDenied,
+			"approver_id":   approverID,
+			"decision_note": note,
+			"decided_at":    time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, &approverID, "access_request.denied", "access_request", request.ID, map[string]interface{}{
+			"requester_id": request.RequesterID,
+			"role_id":      request.RoleID,
+			"note":         note,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(id)
+}
+
+// Revoke ถอนสิทธิ์ที่อนุมัติไปแล้วก่อนหมดเวลา
+func (s *AccessRequestService) Revoke(id uint, actorID uint, note string) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessRequestNotFound
+			}
+			return err
+		}
+		if request.Status != models.AccessRequestApproved {
+			return ErrAccessRequestNotActive
+		}
+
+		// ถอนเฉพาะการกำหนดบทบาทที่เกิดจากคำขอนี้ ไม่แตะบทบาทที่ผู้ใช้ได้รับจากช่องทางอื่น
+		var binding models.UserRole
+		result := tx.Where("user_id = ? AND role_id = ? AND reason LIKE ?",
+			request.RequesterID, request.RoleID, accessRequestReasonPrefix(request.ID)+"%").Limit(1).Find(&binding)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := s.assignments.withDB(tx).RevokeRole(request.RequesterID, request.RoleID, &actorID, note); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&request).Update("status", models.AccessRequestRevoked).Error; err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, &actorID, "access_request.revoked", "access_request", request.ID, map[string]interface{}{
+			"requester_id": request.RequesterID,
+			"role_id":      request.RoleID,
+			"note":         note,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.assignments.notifyChanged(request.RequesterID)
+	return s.Get(id)
+}
+
+// lockPending ล็อกคำขอสำหรับการตัดสินใจ และตรวจสอบว่ายังรออนุมัติและผู้อนุมัติไม่ใช่ผู้ขอเอง
+func (s *AccessRequestService) lockPending(tx *gorm.DB, id uint, approverID uint, request *models.AccessRequest) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccessRequestNotFound
+		}
+		return err
+	}
+	if request.Status != models.AccessRequestPending {
+		return ErrAccessRequestNotPending
+	}
+	if request.RequesterID == approverID {
+		return ErrSelfApproval
+	}
+	return nil
+}
+
+// markExpired ปิดคำขอที่อนุมัติแล้วเมื่อบทบาทที่ได้รับหมดอายุ (เรียกจาก sweeper ของ AssignmentService)
+func (s *AccessRequestService) markExpired(tx *gorm.DB, binding models.UserRole) error {
+	id, ok := parseAccessRequestReason(binding.Reason)
+	if !ok {
+		return nil
+	}
+
+	result := tx.Model(&models.AccessRequest{}).
+		Where("id = ? AND status = ?", id, models.AccessRequestApproved).
+		Update("status", models.AccessRequestExpired)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	return RecordAudit(tx, nil, "access_request.expired", "access_request", id, map[string]interface{}{
+		"requester_id": binding.UserID,
+		"role_id":      binding.RoleID,
+	})
+}
+
+// accessRequestReasonPrefix คือคำนำหน้าเหตุผลของการกำหนดบทบาทที่มาจากคำขอ ใช้เชื่อมการกำหนดบทบาทกลับไปยังคำขอ
+func accessRequestReasonPrefix(id uint) string {
+	return fmt.Sprintf("access request #%d:", id)
+}
+
+func accessRequestReason(id uint, justification string) string {
+	return accessRequestReasonPrefix(id) + " " + justification
+}
+
+func parseAccessRequestReason(reason string) (uint, bool) {
+	if !strings.HasPrefix(reason, "access request #") {
+		return 0, false
+	}
+	var id uint
+	if _, err := fmt.Sscanf(reason, "access request #%d:", &id); err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type AccessRequestServiceTestSuite struct {
+	suite.Suite
+	mock                 sqlmock.Sqlmock
+	accessRequestService *AccessRequestService
+}
+
+func (s *AccessRequestServiceTestSuite) SetupTest() {
+	// สร้าง mock ของฐานข้อมูล
+	db, mock, err := sqlmock.New()
+	s.NoError(err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	s.NoError(err)
+	s.mock = mock
+
+	s.accessRequestService = NewAccessRequestService(gormDB, NewAssignmentService(gormDB), 8*time.Hour)
+}
+
+func (s *AccessRequestServiceTestSuite) AfterTest(_, _ string) {
+	// ตรวจสอบว่ามีการเรียก expect ทั้งหมดหรือไม่
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestAccessRequestServiceSuite(t *testing.T) {
+	suite.Run(t, new(AccessRequestServiceTestSuite))
+}
+
+func (s *AccessRequestServiceTestSuite) TestCreate_DurationTooLong() {
+	// ระยะเวลาเกินค่าสูงสุดต้องถูกปฏิเสธโดยไม่แตะฐานข้อมูล
+	request, err := s.accessRequestService.Create(CreateAccessRequestInput{
+		RequesterID:   1,
+		RoleID:        2,
+		Duration:      24 * time.Hour,
+		Justification: "incident",
+	})
+
+	s.ErrorIs(err, ErrInvalidAccessDuration)
+	s.Nil(request)
+}
+
+func (s *AccessRequestServiceTestSuite) TestApprove_SelfApproval() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_requests" WHERE "access_requests"\."id" = \$1 ORDER BY .* FOR UPDATE`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requester_id", "role_id", "duration_seconds", "status"}).
+			AddRow(5, 3, 2, 7200, models.AccessRequestPending))
+	s.mock.ExpectRollback()
+
+	request, err := s.accessRequestService.Approve(5, 3, "")
+
+	s.ErrorIs(err, ErrSelfApproval)
+	s.Nil(request)
+}
+
+func (s *AccessRequestServiceTestSuite) TestApprove_NotPending() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_requests" WHERE "access_requests"\."id" = \$1 ORDER BY .* FOR UPDATE`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requester_id", "role_id", "duration_seconds", "status"}).
+			AddRow(5, 3, 2, 7200, models.AccessRequestDenied))
+	s.mock.ExpectRollback()
+
+	request, err := s.accessRequestService.Approve(5, 4, "")
+
+	s.ErrorIs(err, ErrAccessRequestNotPending)
+	s.Nil(request)
+}
+
+func (s *AccessRequestServiceTestSuite) TestParseAccessRequestReason() {
+	id, ok := parseAccessRequestReason(accessRequestReason(42, "prod incident"))
+	s.True(ok)
+	s.Equal(uint(42), id)
+
+	_, ok = parseAccessRequestReason("manual grant")
+	s.False(ok)
+}
+
+func (s *AccessRequestServiceTestSuite) TestApprove_ApproverCannotGrantRole() {
+	// ผู้อนุมัติที่ไม่มีสิทธิ์ของบทบาทและไม่ได้รับการมอบต่อ ต้องอนุมัติไม่ได้
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_requests" WHERE "access_requests"\."id" = \$1 ORDER BY .* FOR UPDATE`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requester_id", "role_id", "duration_seconds", "status"}).
+			AddRow(5, 3, 2, 7200, models.AccessRequestPending))
+	s.mock.ExpectQuery(`SELECT "role_id" FROM "user_roles" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "admin"))
+	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(2, 9))
+	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" = \$1`).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(9, "users", "delete"))
+	s.mock.ExpectRollback()
+
+	request, err := s.accessRequestService.Approve(5, 4, "")
+
+	var delegationErr *DelegationError
+	s.ErrorAs(err, &delegationErr)
+	s.Equal([]string{"users:delete"}, delegationErr.MissingPermissions)
+	s.Nil(request)
+}
+
+func (s *AccessRequestServiceTestSuite) TestApprove_KeepsScheduledPermanentBinding() {
+	// ผู้ขอมีบทบาทถาวรที่เริ่มในอนาคต การอนุมัติต้องไม่แทนที่ด้วยการกำหนดชั่วคราวที่ถูกถอนเมื่อคำขอหมดอายุ
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_requests" WHERE "access_requests"\."id" = \$1 ORDER BY .* FOR UPDATE`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "requester_id", "role_id", "duration_seconds", "status"}).
+			AddRow(5, 3, 2, 7200, models.AccessRequestPending))
+	// ผู้อนุมัติได้รับการมอบให้กำหนดบทบาท 2 ผ่านบทบาท 1
+	s.mock.ExpectQuery(`SELECT "role_id" FROM "user_roles" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(1))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectQuery(`SELECT count\(\*\) FROM "role_delegations"`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2 LIMIT \$3`).
+		WithArgs(3, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "valid_from", "valid_until"}).
+			AddRow(3, 2, time.Now().Add(time.Hour), nil))
+	s.mock.ExpectRollback()
+
+	request, err := s.accessRequestService.Approve(5, 4, "")
+
+	s.ErrorIs(err, ErrConflictingRoleBinding)
+	s.Nil(request)
+}
@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAccessReviewNotFound     = errors.New("access review not found")
+	ErrAccessReviewNotOpen      = errors.New("access review is not open")
+	ErrAccessReviewNotClosed    = errors.New("access review must be closed before sign-off")
+	ErrAccessReviewItemNotFound = errors.New("access review item not found")
+	ErrAccessReviewItemDecided  = errors.New("access review item has already been decided")
+	ErrNotReviewer              = errors.New("only the assigned reviewer can decide on this item")
+	ErrSelfReview               = errors.New("users cannot review their own access")
+	ErrInvalidAccessReview      = errors.New("an access review needs at least one role and a future deadline")
+	ErrInvalidReviewDecision    = errors.New("decision must be approved or revoked")
+)
+
+// AccessReviewService จัดการแคมเปญทบทวนสิทธิ์ ตั้งแต่การสร้างสำเนาการกำหนดบทบาท การตัดสินใจของผู้ทบทวน
+// การปิดเมื่อถึงกำหนด จนถึงการรับรองผล การถอนบทบาททั้งหมดทำผ่าน AssignmentService.RevokeRole
+// ซึ่งเป็นเส้นทางเดียวกับการลบบทบาทออกจากผู้ใช้ (audit log และการป้องกันผู้จัดการบทบาทคนสุดท้าย)
+type AccessReviewService struct {
+	db          *gorm.DB
+	assignments *AssignmentService
+}
+
+func NewAccessReviewService(db *gorm.DB, assignments *AssignmentService) *AccessReviewService {
+	return &AccessReviewService{
+		db:          db,
+		assignments: assignments,
+	}
+}
+
+// CreateAccessReviewInput ข้อมูลสำหรับสร้างแคมเปญ
+type CreateAccessReviewInput struct {
+	Name        string
+	Description string
+	RoleIDs     []uint
+	Deadline    time.Time
+	AutoRevoke  bool
+	// Reviewers ผู้ทบทวนของแต่ละบทบาท (role ID -> user ID) บทบาทที่ไม่ระบุใช้ DefaultReviewerID หรือผู้สร้าง
+	Reviewers         map[uint]uint
+	DefaultReviewerID *uint
+	CreatedBy         uint
+	// OrganizationID ไม่เป็น nil จะทบทวนเฉพาะผู้ใช้ใน tenant นั้น
+	OrganizationID *uint
+}
+
+// AccessReviewFilter เงื่อนไขการค้นหาแคมเปญ
+type AccessReviewFilter struct {
+	OrganizationID *uint
+	Status         string
+}
+
+// AccessReviewReport สรุปผลแคมเปญสำหรับผู้ตรวจสอบ
+type AccessReviewReport struct {
+	Campaign models.AccessReviewCampaign `json:"campaign"`
+	Summary  map[string]int              `json:"summary"` // จำนวนรายการแยกตามผลการทบทวน
+	Items    []models.AccessReviewItem   `json:"items"`
+}
+
+// Create สร้างแคมเปญพร้อมสำเนาการกำหนดบทบาทโดยตรงที่มีผลอยู่ของบทบาทที่เลือก
+// บทบาทที่ได้รับผ่านกลุ่มไม่อยู่ในแคมเปญ เพราะถอนจากผู้ใช้รายคนไม่ได้
+func (s *AccessReviewService) Create(input CreateAccessReviewInput) (*models.AccessReviewCampaign, error) {
+	if len(input.RoleIDs) == 0 || !input.Deadline.After(time.Now()) {
+		return nil, ErrInvalidAccessReview
+	}
+
+	campaign := &models.AccessReviewCampaign{
+		Name:           input.Name,
+		Description:    input.Description,
+		Status:         models.AccessReviewOpen,
+		Deadline:       input.Deadline,
+		AutoRevoke:     input.AutoRevoke,
+		OrganizationID: input.OrganizationID,
+		CreatedBy:      input.CreatedBy,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Create(campaign).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		query := tx.Model(&models.UserRole{}).
+			Select("user_roles.user_id, user_roles.role_id, user_roles.valid_until, users.username, roles.name AS role_name").
+			Joins("JOIN users ON users.id = user_roles.user_id").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Where("user_roles.role_id IN ?", input.RoleIDs).
+			Where("user_roles.valid_from IS NULL OR user_roles.valid_from <= ?", now).
+			Where("user_roles.valid_until IS NULL OR user_roles.valid_until > ?", now)
+		if input.OrganizationID != nil {
+			query = query.Where("users.organization_id = ?", *input.OrganizationID)
+		}
+		var bindings []struct {
+			UserID     uint
+			RoleID     uint
+			ValidUntil *time.Time
+			Username   string
+			RoleName   string
+		}
+		if err := query.Order("user_roles.role_id, user_roles.user_id").Scan(&bindings).Error; err != nil {
+			return err
+		}
+
+		items := make([]models.AccessReviewItem, 0, len(bindings))
+		for _, binding := range bindings {
+			reviewerID := input.CreatedBy
+			if id, ok := input.Reviewers[binding.RoleID]; ok {
+				reviewerID = id
+			} else if input.DefaultReviewerID != nil {
+				reviewerID = *input.DefaultReviewerID
+			}
+			items = append(items, models.AccessReviewItem{
+				CampaignID: campaign.ID,
+				UserID:     binding.UserID,
+				Username:   binding.Username,
+				RoleID:     binding.RoleID,
+				RoleName:   binding.RoleName,
+				ValidUntil: binding.ValidUntil,
+				ReviewerID: reviewerID,
+				Decision:   models.AccessReviewPending,
+			})
+		}
+		if len(items) > 0 {
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+		}
+
+		return RecordAudit(tx, &input.CreatedBy, "access_review.created", "access_review", campaign.ID, map[string]interface{}{
+			"role_ids":    input.RoleIDs,
+			"deadline":    input.Deadline,
+			"auto_revoke": input.AutoRevoke,
+			"items":       len(items),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(campaign.ID)
+}
+
+// Get ดึงแคมเปญพร้อมรายการทั้งหมด
+func (s *AccessReviewService) Get(id uint) (*models.AccessReviewCampaign, error) {
+	var campaign models.AccessReviewCampaign
+	err := s.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&campaign, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccessReviewNotFound
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// List ดึงแคมเปญตามเงื่อนไข (ไม่รวมรายการ)
+func (s *AccessReviewService) List(filter AccessReviewFilter) ([]models.AccessReviewCampaign, error) {
+	query := s.db.Order("created_at DESC")
+	if filter.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var campaigns []models.AccessReviewCampaign
+	if err := query.Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// ListReviewerItems ดึงรายการที่มอบหมายให้ผู้ทบทวนในแคมเปญที่ยังเปิดอยู่ (decision ว่าง = ทุกผล)
+func (s *AccessReviewService) ListReviewerItems(reviewerID uint, decision string) ([]models.AccessReviewItem, error) {
+	query := s.db.Joins("JOIN access_review_campaigns ON access_review_campaigns.id = access_review_items.campaign_id").
+		Where("access_review_items.reviewer_id = ? AND access_review_campaigns.status = ?", reviewerID, models.AccessReviewOpen)
+	if decision != "" {
+		query = query.Where("access_review_items.decision = ?", decision)
+	}
+
+	var items []models.AccessReviewItem
+	if err := query.Order("access_review_items.id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Decide บันทึกผลการทบทวนหนึ่งรายการ ผู้ทบทวนที่ได้รับมอบหมายหรือผู้ดูแลแคมเปญ (isAdmin) เท่านั้นที่ตัดสินได้
+// และไม่มีใครทบทวนสิทธิ์ของตนเองได้ การเลือก revoked จะถอนบทบาทออกจากผู้ใช้ทันที
+func (s *AccessReviewService) Decide(campaignID uint, itemID uint, actorID uint, isAdmin bool, decision string, note string) (*models.AccessReviewItem, error) {
+	if decision != models.AccessReviewApproved && decision != models.AccessReviewRevoked {
+		return nil, ErrInvalidReviewDecision
+	}
+
+	var item models.AccessReviewItem
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var campaign models.AccessReviewCampaign
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&campaign, campaignID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessReviewNotFound
+			}
+			return err
+		}
+		if campaign.Status != models.AccessReviewOpen {
+			return ErrAccessReviewNotOpen
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("campaign_id = ?", campaignID).First(&item, itemID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessReviewItemNotFound
+			}
+			return err
+		}
+		if item.Decision != models.AccessReviewPending {
+			return ErrAccessReviewItemDecided
+		}
+		if item.UserID == actorID {
+			return ErrSelfReview
+		}
+		if item.ReviewerID != actorID && !isAdmin {
+			return ErrNotReviewer
+		}
+
+		if decision == models.AccessReviewRevoked {
+			if err := s.assignments.withDB(tx).RevokeRole(item.UserID, item.RoleID, &actorID, accessReviewReason(campaignID, note)); err != nil {
+				return err
+			}
+		}
+
+		return s.decide(tx, &item, decision, &actorID, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if decision == models.AccessReviewRevoked {
+		s.assignments.notifyChanged(item.UserID)
+	}
+	return &item, nil
+}
+
+// Close ปิดแคมเปญก่อนกำหนด รายการที่ยังไม่มีผู้ทบทวนจะถูกจัดการเหมือนถึงกำหนด
+func (s *AccessReviewService) Close(id uint, actorID *uint) (*models.AccessReviewCampaign, error) {
+	var revokedUserIDs []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var campaign models.AccessReviewCampaign
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&campaign, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessReviewNotFound
+			}
+			return err
+		}
+		if campaign.Status != models.AccessReviewOpen {
+			return ErrAccessReviewNotOpen
+		}
+		var err error
+		revokedUserIDs, err = s.close(tx, &campaign, actorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.assignments.notifyChanged(revokedUserIDs...)
+	return s.Get(id)
+}
+
+// CloseDue ปิดแคมเปญที่เปิดอยู่และเลยกำหนดแล้ว คืนจำนวนแคมเปญที่ปิด
+func (s *AccessReviewService) CloseDue(now time.Time) (int, error) {
+	var ids []uint
+	err := s.db.Model(&models.AccessReviewCampaign{}).
+		Where("status = ? AND deadline <= ?", models.AccessReviewOpen, now).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, id := range ids {
+		if _, err := s.Close(id, nil); err != nil {
+			if errors.Is(err, ErrAccessReviewNotOpen) {
+				continue // ถูกปิดไปแล้วระหว่างนั้น
+			}
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// StartDeadlineSweeper รัน CloseDue เป็นระยะจนกว่า context จะถูกยกเลิก
+func (s *AccessReviewService) StartDeadlineSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			closed, err := s.CloseDue(time.Now())
+			if err != nil {
+				log.Printf("Failed to close overdue access reviews: %v", err)
+				continue
+			}
+			if closed > 0 {
+				log.Printf("Closed %d overdue access reviews", closed)
+			}
+		}
+	}
+}
+
+// SignOff รับรองผลแคมเปญที่ปิดแล้ว และบันทึก digest ของรายงาน ณ เวลานั้นเพื่อใช้ยืนยันว่ารายงานไม่ถูกแก้ไข
+func (s *AccessReviewService) SignOff(id uint, actorID uint) (*AccessReviewReport, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var campaign models.AccessReviewCampaign
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&campaign, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessReviewNotFound
+			}
+			return err
+		}
+		if campaign.Status != models.AccessReviewClosed {
+			return ErrAccessReviewNotClosed
+		}
+
+		var items []models.AccessReviewItem
+		if err := tx.Where("campaign_id = ?", id).Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		digest, err := reportDigest(campaign, items)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = tx.Model(&campaign).Updates(map[string]interface{}{
+			"status":        models.AccessReviewSignedOff,
+			"signed_off_by": actorID,
+			"signed_off_at": now,
+			"report_digest": digest,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, &actorID, "access_review.signed_off", "access_review", id, map[string]interface{}{
+			"report_digest": digest,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Report(id)
+}
+
+// Report สรุปผลแคมเปญ
+func (s *AccessReviewService) Report(id uint) (*AccessReviewReport, error) {
+	campaign, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AccessReviewReport{
+		Summary: map[string]int{
+			models.AccessReviewPending:     0,
+			models.AccessReviewApproved:    0,
+			models.AccessReviewRevoked:     0,
+			models.AccessReviewAutoRevoked: 0,
+			models.AccessReviewUnreviewed:  0,
+		},
+		Items: campaign.Items,
+	}
+	for _, item := range campaign.Items {
+		report.Summary[item.Decision]++
+	}
+	campaign.Items = nil
+	report.Campaign = *campaign
+	return report, nil
+}
+
+// close จัดการรายการที่ยังไม่มีผู้ทบทวน (ถอนบทบาทถ้าแคมเปญตั้ง AutoRevoke) แล้วเปลี่ยนสถานะเป็น closed
+// คืน ID ของผู้ใช้ที่ถูกถอนบทบาท
+func (s *AccessReviewService) close(tx *gorm.DB, campaign *models.AccessReviewCampaign, actorID *uint) ([]uint, error) {
+	var pending []models.AccessReviewItem
+	err := tx.Where("campaign_id = ? AND decision = ?", campaign.ID, models.AccessReviewPending).
+		Order("id").Find(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var revokedUserIDs []uint
+
+	for i := range pending {
+		item := &pending[i]
+		if !campaign.AutoRevoke {
+			if err := s.decide(tx, item, models.AccessReviewUnreviewed, actorID, ""); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// ถอนแต่ละรายการใน savepoint ถ้าการถอนทำให้ไม่เหลือผู้จัดการบทบาท รายการนั้นคงบทบาทไว้และระบุเหตุผล
+		err := tx.Transaction(func(nested *gorm.DB) error {
+			return s.assignments.withDB(nested).RevokeRole(item.UserID, item.RoleID, actorID, accessReviewReason(campaign.ID, "not reviewed before the deadline"))
+		})
+		switch {
+		case err == nil:
+			revokedUserIDs = append(revokedUserIDs, item.UserID)
+			err = s.decide(tx, item, models.AccessReviewAutoRevoked, actorID, "")
+		case errors.Is(err, ErrLastRoleManager):
+			err = s.decide(tx, item, models.AccessReviewUnreviewed, actorID, err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	err = tx.Model(campaign).Updates(map[string]interface{}{
+		"status":    models.AccessReviewClosed,
+		"closed_at": now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = RecordAudit(tx, actorID, "access_review.closed", "access_review", campaign.ID, map[string]interface{}{
+		"unreviewed":  len(pending),
+		"auto_revoke": campaign.AutoRevoke,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revokedUserIDs, nil
+}
+
+// decide บันทึกผลของรายการพร้อม audit log
+func (s *AccessReviewService) decide(tx *gorm.DB, item *models.AccessReviewItem, decision string, actorID *uint, note string) error {
+	now := time.Now()
+	err := tx.Model(item).Updates(map[string]interface{}{
+		"decision":   decision,
+		"note":       note,
+		"decided_by": actorID,
+		"decided_at": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return RecordAudit(tx, actorID, "access_review.item_"+decision, "access_review", item.CampaignID, map[string]interface{}{
+		"item_id": item.ID,
+		"user_id": item.UserID,
+		"role_id": item.RoleID,
+		"note":    note,
+	})
+}
+
+// reportDigest คำนวณ SHA-256 ของแคมเปญและผลของทุกรายการในรูป JSON
+func reportDigest(campaign models.AccessReviewCampaign, items []models.AccessReviewItem) (string, error) {
+	type digestItem struct {
+		ID        uint       `json:"id"`
+		UserID    uint       `json:"user_id"`
+		RoleID    uint       `json:"role_id"`
+		Reviewer  uint       `json:"reviewer_id"`
+		Decision  string     `json:"decision"`
+		DecidedBy *uint      `json:"decided_by"`
+		DecidedAt *time.Time `json:"decided_at"`
+		Note      string     `json:"note"`
+	}
+	content := struct {
+		ID       uint         `json:"id"`
+		Name     string       `json:"name"`
+		Deadline time.Time    `json:"deadline"`
+		ClosedAt *time.Time   `json:"closed_at"`
+		Items    []digestItem `json:"items"`
+	}{ID: campaign.ID, Name: campaign.Name, Deadline: campaign.Deadline.UTC(), ClosedAt: campaign.ClosedAt, Items: []digestItem{}}
+	for _, item := range items {
+		content.Items = append(content.Items, digestItem{
+			ID:        item.ID,
+			UserID:    item.UserID,
+			RoleID:    item.RoleID,
+			Reviewer:  item.ReviewerID,
+			Decision:  item.Decision,
+			DecidedBy: item.DecidedBy,
+			DecidedAt: item.DecidedAt,
+			Note:      item.Note,
+		})
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func accessReviewReason(campaignID uint, note string) string {
+	reason := fmt.Sprintf("access review #%d", campaignID)
+	if note != "" {
+		reason += ": " + note
+	}
+	return reason
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type AccessReviewServiceTestSuite struct {
+	suite.Suite
+	mock                sqlmock.Sqlmock
+	accessReviewService *AccessReviewService
+}
+
+func (s *AccessReviewServiceTestSuite) SetupTest() {
+	// สร้าง mock ของฐานข้อมูล
+	db, mock, err := sqlmock.New()
+	s.NoError(err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	s.NoError(err)
+	s.mock = mock
+
+	s.accessReviewService = NewAccessReviewService(gormDB, NewAssignmentService(gormDB))
+}
+
+func (s *AccessReviewServiceTestSuite) AfterTest(_, _ string) {
+	// ตรวจสอบว่ามีการเรียก expect ทั้งหมดหรือไม่
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestAccessReviewServiceSuite(t *testing.T) {
+	suite.Run(t, new(AccessReviewServiceTestSuite))
+}
+
+func (s *AccessReviewServiceTestSuite) TestCreate_DeadlineInPast() {
+	campaign, err := s.accessReviewService.Create(CreateAccessReviewInput{
+		Name:      "Q1",
+		RoleIDs:   []uint{1},
+		Deadline:  time.Now().Add(-time.Hour),
+		CreatedBy: 1,
+	})
+
+	s.ErrorIs(err, ErrInvalidAccessReview)
+	s.Nil(campaign)
+}
+
+// expectItem จำลองแคมเปญที่เปิดอยู่และรายการของผู้ใช้ 3 ที่มอบหมายให้ผู้ทบทวน 4
+func (s *AccessReviewServiceTestSuite) expectItem() {
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_review_campaigns" WHERE "access_review_campaigns"\."id" = \$1 ORDER BY .* FOR SHARE`).
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(7, models.AccessReviewOpen))
+	s.mock.ExpectQuery(`SELECT \* FROM "access_review_items" WHERE campaign_id = \$1 AND "access_review_items"\."id" = \$2 ORDER BY .* FOR UPDATE`).
+		WithArgs(7, 9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "user_id", "role_id", "reviewer_id", "decision"}).
+			AddRow(9, 7, 3, 2, 4, models.AccessReviewPending))
+}
+
+func (s *AccessReviewServiceTestSuite) TestDecide_SelfReview() {
+	// ผู้ดูแลแคมเปญก็ทบทวนสิทธิ์ของตนเองไม่ได้
+	s.expectItem()
+	s.mock.ExpectRollback()
+
+	item, err := s.accessReviewService.Decide(7, 9, 3, true, models.AccessReviewApproved, "")
+
+	s.ErrorIs(err, ErrSelfReview)
+	s.Nil(item)
+}
+
+func (s *AccessReviewServiceTestSuite) TestDecide_NotReviewer() {
+	s.expectItem()
+	s.mock.ExpectRollback()
+
+	item, err := s.accessReviewService.Decide(7, 9, 5, false, models.AccessReviewRevoked, "")
+
+	s.ErrorIs(err, ErrNotReviewer)
+	s.Nil(item)
+}
+
+func (s *AccessReviewServiceTestSuite) TestDecide_RevokeUsesAssignmentService() {
+	s.expectItem()
+	// ถอนผ่าน RevokeRole: ลบการกำหนดบทบาท ตรวจสอบผู้จัดการบทบาท และบันทึก role.revoked
+	s.mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectExec(`DELETE FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2`).
+		WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	s.mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(4, "role.revoked", "user", 3, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectExec(`UPDATE "access_review_items" SET .*"decision"=\$`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(4, "access_review.item_revoked", "access_review", 7, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	s.mock.ExpectCommit()
+
+	item, err := s.accessReviewService.Decide(7, 9, 4, false, models.AccessReviewRevoked, "left the team")
+
+	s.NoError(err)
+	s.Equal(models.AccessReviewRevoked, item.Decision)
+}
+
+func (s *AccessReviewServiceTestSuite) TestCreate_SnapshotsNames() {
+	// รายการเก็บชื่อผู้ใช้และชื่อบทบาทไว้ เพื่อให้ผลการทบทวนยังอ่านได้หลังผู้ใช้หรือบทบาทถูกลบ
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(`INSERT INTO "access_review_campaigns"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	s.mock.ExpectQuery(`SELECT user_roles.user_id, user_roles.role_id, user_roles.valid_until, users.username, roles.name AS role_name FROM "user_roles" JOIN users .* JOIN roles`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "valid_until", "username", "role_name"}).
+			AddRow(3, 2, nil, "alice", "auditor"))
+	s.mock.ExpectQuery(`INSERT INTO "access_review_items" \("campaign_id","user_id","username","role_id","role_name",`).
+		WithArgs(7, 3, "alice", 2, "auditor", nil, 1, models.AccessReviewPending, "", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	s.mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(1, "access_review.created", "access_review", 7, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectCommit()
+	s.mock.ExpectQuery(`SELECT \* FROM "access_review_campaigns" WHERE "access_review_campaigns"\."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(7, models.AccessReviewOpen))
+	s.mock.ExpectQuery(`SELECT \* FROM "access_review_items" WHERE "access_review_items"\."campaign_id" = \$1 ORDER BY id`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "user_id", "username", "role_id", "role_name"}).
+			AddRow(9, 7, 3, "alice", 2, "auditor"))
+
+	campaign, err := s.accessReviewService.Create(CreateAccessReviewInput{
+		Name:      "Q1",
+		RoleIDs:   []uint{2},
+		Deadline:  time.Now().Add(time.Hour),
+		CreatedBy: 1,
+	})
+
+	s.NoError(err)
+	s.Require().Len(campaign.Items, 1)
+	s.Equal("alice", campaign.Items[0].Username)
+	s.Equal("auditor", campaign.Items[0].RoleName)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidValidityWindow เกิดเมื่อช่วงเวลาของการกำหนดบทบาทไม่ถูกต้อง
+var ErrInvalidValidityWindow = errors.New("valid_until must be after valid_from and in the future")
+
+// AssignmentService จัดการการกำหนดบทบาทให้ผู้ใช้ ทุกการเพิ่ม/ถอนบทบาทต้องผ่าน service นี้
+// เพื่อให้มีการบันทึก audit log เสมอ
+type AssignmentService struct {
+	db          *gorm.DB
+	expiryHooks []ExpiryHook
+	changeHooks []ChangeHook
+}
+
+// ExpiryHook ถูกเรียกภายใน transaction เดียวกับการลบการกำหนดบทบาทที่หมดอายุ
+type ExpiryHook func(tx *gorm.DB, binding models.UserRole) error
+
+// ChangeHook ถูกเรียกหลัง commit เมื่อการกำหนดบทบาทของผู้ใช้เปลี่ยน (เช่นเพื่อ invalidate แคชสิทธิ์)
+type ChangeHook func(userIDs ...uint)
+
+func NewAssignmentService(db *gorm.DB) *AssignmentService {
+	return &AssignmentService{
+		db: db,
+	}
+}
+
+// OnExpired ลงทะเบียน hook ที่จะถูกเรียกเมื่อ sweeper ลบการกำหนดบทบาทที่หมดอายุ
+func (s *AssignmentService) OnExpired(hook ExpiryHook) {
+	s.expiryHooks = append(s.expiryHooks, hook)
+}
+
+// OnChanged ลงทะเบียน hook ที่จะถูกเรียกหลังการกำหนดหรือถอนบทบาทถูก commit
+func (s *AssignmentService) OnChanged(hook ChangeHook) {
+	s.changeHooks = append(s.changeHooks, hook)
+}
+
+// notifyChanged เรียก change hook ทั้งหมด ต้องเรียกหลัง transaction ที่เปลี่ยนการกำหนดบทบาท commit แล้วเท่านั้น
+func (s *AssignmentService) notifyChanged(userIDs ...uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+	for _, hook := range s.changeHooks {
+		hook(userIDs...)
+	}
+}
+
+// withDB คืน AssignmentService ที่ทำงานบน transaction ที่กำหนด
+// change hook ไม่ถูกส่งต่อ เพราะ transaction ยังไม่ commit ผู้เรียกต้อง notifyChanged เองหลัง commit
+func (s *AssignmentService) withDB(tx *gorm.DB) *AssignmentService {
+	return &AssignmentService{
+		db:          tx,
+		expiryHooks: s.expiryHooks,
+	}
+}
+
+// AssignRoleInput ข้อมูลสำหรับการกำหนดบทบาทให้ผู้ใช้
+type AssignRoleInput struct {
+	UserID     uint
+	RoleID     uint
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+	GrantedBy  *uint
+	Reason     string
+}
+
+// AssignRole กำหนดบทบาทให้ผู้ใช้ ถ้ามีการกำหนดอยู่แล้วจะอัปเดตช่วงเวลาและเหตุผลแทน
+func (s *AssignmentService) AssignRole(input AssignRoleInput) (*models.UserRole, error) {
+	now := time.Now()
+	if input.ValidUntil != nil {
+		if !input.ValidUntil.After(now) {
+			return nil, ErrInvalidValidityWindow
+		}
+		if input.ValidFrom != nil && !input.ValidUntil.After(*input.ValidFrom) {
+			return nil, ErrInvalidValidityWindow
+		}
+	}
+
+	binding := &models.UserRole{
+		UserID:     input.UserID,
+		RoleID:     input.RoleID,
+		ValidFrom:  input.ValidFrom,
+		ValidUntil: input.ValidUntil,
+		GrantedBy:  input.GrantedBy,
+		Reason:     input.Reason,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := CheckSoD(tx, map[uint][]uint{input.UserID: {input.RoleID}}); err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"valid_from", "valid_until", "granted_by", "reason"}),
+		}).Create(binding).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, input.GrantedBy, "role.assigned", "user", input.UserID, map[string]interface{}{
+			"role_id":     input.RoleID,
+			"valid_from":  input.ValidFrom,
+			"valid_until": input.ValidUntil,
+			"reason":      input.Reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyChanged(input.UserID)
+	return binding, nil
+}
+
+// RevokeRole ถอนบทบาทออกจากผู้ใช้
+func (s *AssignmentService) RevokeRole(userID uint, roleID uint, actorID *uint, reason string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// ห้ามถอนบทบาทสุดท้ายที่ทำให้ยังมีผู้จัดการบทบาทเหลืออยู่
+		if err := EnsureRoleManagerRemains(tx); err != nil {
+			return err
+		}
+
+		return RecordAudit(tx, actorID, "role.revoked", "user", userID, map[string]interface{}{
+			"role_id": roleID,
+			"reason":  reason,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyChanged(userID)
+	return nil
+}
+
+// GetAssignments ดึงการกำหนดบทบาททั้งหมดของผู้ใช้ รวมถึงที่ยังไม่เริ่มหรือหมดอายุแล้วแต่ยังไม่ถูกลบ
+func (s *AssignmentService) GetAssignments(userID uint) ([]models.UserRole, error) {
+	var bindings []models.UserRole
+	if err := s.db.Preload("Role").Where("user_id = ?", userID).Find(&bindings).Error; err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
+
+// SweepExpired ลบการกำหนดบทบาทที่หมดอายุแล้ว และบันทึกการลบแต่ละรายการลง audit log
+func (s *AssignmentService) SweepExpired(now time.Time) (int, error) {
+	var expired []models.UserRole
+	if err := s.db.Where("valid_until IS NOT NULL AND valid_until <= ?", now).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, binding := range expired {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// ตรวจสอบ valid_until ซ้ำ เผื่อมีการต่ออายุระหว่างที่กำลัง sweep
+			result := tx.Where("user_id = ? AND role_id = ? AND valid_until <= ?", binding.UserID, binding.RoleID, now).
+				Delete(&models.UserRole{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			for _, hook := range s.expiryHooks {
+				if err := hook(tx, binding); err != nil {
+					return err
+				}
+			}
+
+			err := RecordAudit(tx, nil, "role.expired", "user", binding.UserID, map[string]interface{}{
+				"role_id":     binding.RoleID,
+				"valid_until": binding.ValidUntil,
+				"granted_by":  binding.GrantedBy,
+				"reason":      binding.Reason,
+			})
+			if err != nil {
+				return err
+			}
+
+			removed++
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+		s.notifyChanged(binding.UserID)
+	}
+
+	return removed, nil
+}
+
+// StartExpirySweeper รัน SweepExpired เป็นระยะจนกว่า context จะถูกยกเลิก
+func (s *AssignmentService) StartExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := s.SweepExpired(time.Now())
+			if err != nil {
+				log.Printf("Failed to sweep expired role assignments: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("Removed %d expired role assignments", removed)
+			}
+		}
+	}
+}
+
+// activeRoleIDs คืน subquery ของ role_id ที่การกำหนดให้ผู้ใช้ยังมีผล ณ เวลาที่ระบุ
+func activeRoleIDs(db *gorm.DB, userID uint, now time.Time) *gorm.DB {
+	return db.Model(&models.UserRole{}).Select("role_id").
+		Where("user_id = ?", userID).
+		Where("valid_from IS NULL OR valid_from <= ?", now).
+		Where("valid_until IS NULL OR valid_until > ?", now)
+}
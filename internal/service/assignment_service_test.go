@@ -0,0 +1,195 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type AssignmentServiceTestSuite struct {
+	suite.Suite
+	mock              sqlmock.Sqlmock
+	assignmentService *AssignmentService
+}
+
+func (s *AssignmentServiceTestSuite) SetupTest() {
+	// สร้าง mock ของฐานข้อมูล
+	db, mock, err := sqlmock.New()
+	s.NoError(err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	s.NoError(err)
+	s.mock = mock
+
+	s.assignmentService = NewAssignmentService(gormDB)
+}
+
+func (s *AssignmentServiceTestSuite) AfterTest(_, _ string) {
+	// ตรวจสอบว่ามีการเรียก expect ทั้งหมดหรือไม่
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+// expectSoDRules คาดหวังการค้นหากฎแบ่งแยกหน้าที่ที่เกี่ยวกับบทบาทที่จะกำหนด
+func (s *AssignmentServiceTestSuite) expectSoDRules(rows *sqlmock.Rows) {
+	s.mock.ExpectQuery(`SELECT \* FROM "sod_rules" WHERE id IN \(SELECT rule_id FROM "sod_rule_roles" WHERE role_id IN \(\$1\)\)`).
+		WillReturnRows(rows)
+}
+
+func TestAssignmentServiceSuite(t *testing.T) {
+	suite.Run(t, new(AssignmentServiceTestSuite))
+}
+
+func (s *AssignmentServiceTestSuite) TestAssignRole_ExpiredWindow() {
+	// valid_until ที่ผ่านไปแล้วต้องถูกปฏิเสธโดยไม่แตะฐานข้อมูล
+	past := time.Now().Add(-time.Hour)
+	binding, err := s.assignmentService.AssignRole(AssignRoleInput{
+		UserID:     1,
+		RoleID:     2,
+		ValidUntil: &past,
+	})
+
+	s.ErrorIs(err, ErrInvalidValidityWindow)
+	s.Nil(binding)
+}
+
+func (s *AssignmentServiceTestSuite) TestAssignRole_UntilBeforeFrom() {
+	from := time.Now().Add(2 * time.Hour)
+	until := time.Now().Add(time.Hour)
+	binding, err := s.assignmentService.AssignRole(AssignRoleInput{
+		UserID:     1,
+		RoleID:     2,
+		ValidFrom:  &from,
+		ValidUntil: &until,
+	})
+
+	s.ErrorIs(err, ErrInvalidValidityWindow)
+	s.Nil(binding)
+}
+
+func (s *AssignmentServiceTestSuite) TestAssignRole_Success() {
+	until := time.Now().Add(2 * time.Hour)
+	grantedBy := uint(9)
+
+	// upsert การกำหนดบทบาทและบันทึก audit log ใน transaction เดียวกัน
+	s.mock.ExpectBegin()
+	s.expectSoDRules(sqlmock.NewRows([]string{"id", "name", "kind", "max_allowed"}))
+	s.mock.ExpectExec(`INSERT INTO "user_roles" .* ON CONFLICT \("user_id","role_id"\) DO UPDATE SET "valid_from"="excluded"\."valid_from","valid_until"="excluded"\."valid_until","granted_by"="excluded"\."granted_by","reason"="excluded"\."reason"`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(grantedBy, "role.assigned", "user", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectCommit()
+
+	binding, err := s.assignmentService.AssignRole(AssignRoleInput{
+		UserID:     1,
+		RoleID:     2,
+		ValidUntil: &until,
+		GrantedBy:  &grantedBy,
+		Reason:     "on-call",
+	})
+
+	s.NoError(err)
+	s.Equal(uint(2), binding.RoleID)
+	s.Equal("on-call", binding.Reason)
+}
+
+func (s *AssignmentServiceTestSuite) TestAssignRole_SoDViolation() {
+	// ผู้ใช้ถือ payment-approver (3) อยู่แล้ว จึงรับ payment-creator (2) ไม่ได้
+	s.mock.ExpectBegin()
+	s.expectSoDRules(sqlmock.NewRows([]string{"id", "name", "kind", "max_allowed"}).
+		AddRow(1, "payments", models.SoDMutuallyExclusive, 1))
+	s.mock.ExpectQuery(`SELECT \* FROM "sod_rule_roles" WHERE "sod_rule_roles"\."rule_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"rule_id", "role_id"}).AddRow(1, 2).AddRow(1, 3))
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" IN \(\$1,\$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "payment-creator").AddRow(3, "payment-approver"))
+	s.mock.ExpectQuery(`SELECT "role_id" FROM "user_roles" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(3))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectRollback()
+
+	binding, err := s.assignmentService.AssignRole(AssignRoleInput{UserID: 1, RoleID: 2})
+
+	var violation *SoDViolationError
+	s.ErrorAs(err, &violation)
+	s.Equal("payments", violation.Violation.RuleName)
+	s.ElementsMatch([]uint{2, 3}, violation.Violation.RoleIDs)
+	s.Nil(binding)
+}
+
+func (s *AssignmentServiceTestSuite) TestRevokeRole_LastRoleManager() {
+	// ถอนบทบาทแล้วไม่เหลือผู้ใช้ที่มี roles:write ต้อง rollback
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`DELETE FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2`).
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE id IN \(SELECT "user_id" FROM "user_roles" WHERE role_id IN \(SELECT role_permissions\.role_id FROM "role_permissions" JOIN permissions`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_roles" WHERE role_id IN \(SELECT role_permissions\.role_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectRollback()
+
+	actorID := uint(1)
+	err := s.assignmentService.RevokeRole(1, 1, &actorID, "")
+
+	s.ErrorIs(err, ErrLastRoleManager)
+}
+
+func (s *AssignmentServiceTestSuite) TestRevokeRole_TenantManagersDoNotCount() {
+	// ผู้ใช้ของ tenant ที่มี roles:write ผ่านบทบาทของ tenant จัดการบทบาทระดับ global ไม่ได้ จึงไม่นับ
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`DELETE FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2`).
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE id IN \(SELECT "user_id" FROM "user_roles" WHERE role_id IN \(SELECT role_permissions\.role_id FROM "role_permissions" JOIN permissions .* JOIN roles ON roles\.id = role_permissions\.role_id WHERE \(permissions\.resource = \$1 AND permissions\.action = \$2\) AND roles\.organization_id IS NULL\) .* AND organization_id IS NULL`).
+		WithArgs("roles", "write", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_roles" WHERE role_id IN \(SELECT role_permissions\.role_id .* roles\.organization_id IS NULL\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectRollback()
+
+	actorID := uint(1)
+	err := s.assignmentService.RevokeRole(1, 1, &actorID, "")
+
+	s.ErrorIs(err, ErrLastRoleManager)
+}
+
+func (s *AssignmentServiceTestSuite) TestSweepExpired() {
+	now := time.Now()
+	expiredAt := now.Add(-time.Minute)
+
+	// ค้นหาการกำหนดบทบาทที่หมดอายุ
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE valid_until IS NOT NULL AND valid_until <= \$1`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "valid_from", "valid_until", "granted_by", "reason", "created_at"}).
+			AddRow(1, 2, nil, expiredAt, 9, "contract", now.Add(-time.Hour)))
+
+	// ลบและบันทึกการลบลง audit log
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(`DELETE FROM "user_roles" WHERE user_id = \$1 AND role_id = \$2 AND valid_until <= \$3`).
+		WithArgs(1, 2, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(nil, "role.expired", "user", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	s.mock.ExpectCommit()
+
+	removed, err := s.assignmentService.SweepExpired(now)
+
+	s.NoError(err)
+	s.Equal(1, removed)
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecordAudit บันทึกเหตุการณ์ลง audit log (ควรเรียกภายใน transaction เดียวกับการเปลี่ยนแปลง)
+func RecordAudit(tx *gorm.DB, actorID *uint, action string, targetType string, targetID uint, details interface{}) error {
+	entry := models.AuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Details = string(data)
+	}
+
+	return tx.Create(&entry).Error
+}
@@ -8,6 +8,7 @@ import (
 // เพื่อให้สามารถทำ mock ในการทดสอบได้
 type AuthServiceInterface interface {
 	GetUserByID(userID uint) (*models.User, error)
+	GetGroupRoles(userID uint) ([]models.Role, error)
 	HasPermission(userID uint, resource string, action string) (bool, error)
 	Login(req *LoginRequest) (*LoginResponse, error)
 }
@@ -2,8 +2,11 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/pkg/adminapi"
 	"github.com/yourusername/auth-api/pkg/jwt"
 	"gorm.io/gorm"
 )
@@ -11,6 +14,12 @@ import (
 type AuthService struct {
 	db         *gorm.DB
 	jwtService *jwt.JWTService
+	cache      *PermissionCache
+	// embedPermissions ให้ Login แนบบทบาทและสิทธิ์ไปกับ token
+	embedPermissions bool
+	// defaultAudiences และ clientAudiences กำหนด aud ของ token ที่ออกตอน login
+	defaultAudiences []string
+	clientAudiences  map[string][]string
 }
 
 func NewAuthService(db *gorm.DB, jwtService *jwt.JWTService) *AuthService {
@@ -20,15 +29,32 @@ func NewAuthService(db *gorm.DB, jwtService *jwt.JWTService) *AuthService {
 	}
 }
 
-// LoginRequest สำหรับรับข้อมูล login
-type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+// UseCache ให้ GetUserByID, GetGroupRoles และ HasPermission อ่านบทบาทและสิทธิ์ผ่านแคช (nil = ไม่ใช้แคช)
+func (s *AuthService) UseCache(cache *PermissionCache) {
+	s.cache = cache
 }
 
+// ErrUnknownClient เกิดเมื่อ login ระบุ client_id ที่ไม่ได้ตั้งค่าไว้
+var ErrUnknownClient = errors.New("unknown client_id")
+
+// SetAudiences กำหนด aud ของ token: defaults สำหรับ login ที่ไม่ระบุ client_id และ clients ตาม client_id
+func (s *AuthService) SetAudiences(defaults []string, clients map[string][]string) {
+	s.defaultAudiences = defaults
+	s.clientAudiences = clients
+}
+
+// JWKS คืน public key ที่ใช้ตรวจ token ที่ service นี้ออก
+func (s *AuthService) JWKS() jwt.JSONWebKeySet {
+	return s.jwtService.JWKS()
+}
+
+// LoginRequest สำหรับรับข้อมูล login (ใช้ร่วมกับ pkg/adminclient)
+type LoginRequest = adminapi.LoginRequest
+
 // LoginResponse สำหรับส่งผลลัพธ์ login
 type LoginResponse struct {
 	AccessToken string                 `json:"access_token"`
+	Scope       string                 `json:"scope,omitempty"` // scope ที่ได้รับจริง (เฉพาะเมื่อขอ scope)
 	User        map[string]interface{} `json:"user"`
 }
 
@@ -37,7 +63,7 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 	var user models.User
 
 	// ค้นหาผู้ใช้จาก username
-	result := s.db.Where("username = ?", req.Username).Preload("Roles.Permissions").First(&user)
+	result := s.db.Where("username = ?", req.Username).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid username or password")
@@ -50,43 +76,259 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("invalid username or password")
 	}
 
+	audiences := s.defaultAudiences
+	if req.ClientID != "" {
+		var ok bool
+		if audiences, ok = s.clientAudiences[req.ClientID]; !ok {
+			return nil, ErrUnknownClient
+		}
+	}
+
+	if err := s.loadActiveRoles(&user); err != nil {
+		return nil, err
+	}
+
 	// สร้าง token
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	opts := []jwt.TokenOption{jwt.WithTenantID(user.OrganizationID), jwt.WithAudience(audiences...)}
+	var scopes []string
+	if s.embedPermissions || req.Scope != "" {
+		groupRoles, err := s.GetGroupRoles(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		roles := MergeRoles(user.Roles, groupRoles)
+
+		if req.Scope != "" {
+			if scopes, err = grantScopes(roles, req.Scope); err != nil {
+				return nil, err
+			}
+			opts = append(opts, jwt.WithScope(scopes))
+		}
+		if s.embedPermissions {
+			opts = append(opts, embeddedPermissions(roles, scopes))
+		}
+	}
+	token, err := s.jwtService.GenerateToken(user.ID, user.Email, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LoginResponse{
 		AccessToken: token,
+		Scope:       strings.Join(scopes, " "),
 		User:        user.ToResponse(),
 	}, nil
 }
 
-// GetUserByID ดึงข้อมูลผู้ใช้จาก ID
+// GetUserByID ดึงข้อมูลผู้ใช้จาก ID พร้อมบทบาทที่การกำหนดยังมีผลอยู่
 func (s *AuthService) GetUserByID(userID uint) (*models.User, error) {
+	if s.cache != nil {
+		entry, err := s.cache.user(s.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		user := entry.user
+		if user.Roles, err = s.cache.rolesByID(s.db, entry.direct); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
 	var user models.User
-	result := s.db.Preload("Roles.Permissions").First(&user, userID)
+	result := s.db.First(&user, userID)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if err := s.loadActiveRoles(&user); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
-// HasPermission ตรวจสอบว่าผู้ใช้มีสิทธิ์หรือไม่
+// loadActiveRoles โหลดบทบาทและสิทธิ์ของผู้ใช้ โดยข้ามการกำหนดบทบาทที่หมดอายุหรือยังไม่เริ่มมีผล
+func (s *AuthService) loadActiveRoles(user *models.User) error {
+	return s.db.Preload("Permissions").
+		Where("id IN (?)", activeRoleIDs(s.db, user.ID, time.Now())).
+		Find(&user.Roles).Error
+}
+
+// HasPermission ตรวจสอบว่าผู้ใช้มีสิทธิ์หรือไม่ ทั้งจากบทบาทโดยตรงที่ยังไม่หมดอายุและบทบาทที่ได้รับผ่านกลุ่ม
+// query EXISTS เดียว (permissionCheckSQL) ใช้เฉพาะเมื่อปิดแคช (authz.cacheTTL = 0) เท่านั้น
+// เมื่อใช้แคช (ค่าเริ่มต้น) จะตรวจจากบทบาทที่แคชไว้ และเมื่อแคชพลาดจะโหลดผู้ใช้และบทบาททั้งหมดมาเก็บในแคชแทน
+// เพื่อให้การตรวจสิทธิ์อื่นของผู้ใช้คนเดิมในช่วง TTL ไม่ต้องเข้าฐานข้อมูลอีก
 func (s *AuthService) HasPermission(userID uint, resource string, action string) (bool, error) {
-	var user models.User
-	result := s.db.Preload("Roles.Permissions").First(&user, userID)
-	if result.Error != nil {
-		return false, result.Error
+	if s.cache != nil {
+		return s.hasPermissionInRoles(userID, resource, action)
+	}
+	return s.hasPermissionQuery(userID, resource, action)
+}
+
+// permissionCheckSQL ตรวจในคำสั่งเดียวว่าผู้ใช้มีอยู่หรือไม่ และมีบทบาทโดยตรงที่ยังมีผล
+// หรือบทบาทจากกลุ่ม (รวมกลุ่มแม่ทุกระดับ) ที่ให้สิทธิ์ resource:action หรือไม่
+// UNION ใน recursive CTE ตัดกลุ่มที่เคยพบแล้ว จึงไม่วนซ้ำแม้ข้อมูลเป็นวงจร
+const permissionCheckSQL = `SELECT EXISTS (SELECT 1 FROM users WHERE id = @user) AS user_exists,
+EXISTS (
+	SELECT 1 FROM permissions p
+	JOIN role_permissions rp ON rp.permission_id = p.id
+	WHERE p.resource = @resource AND p.action = @action AND (
+		rp.role_id IN (
+			SELECT ur.role_id FROM user_roles ur
+			WHERE ur.user_id = @user
+			AND (ur.valid_from IS NULL OR ur.valid_from <= @now)
+			AND (ur.valid_until IS NULL OR ur.valid_until > @now)
+		)
+		OR rp.role_id IN (
+			WITH RECURSIVE member_groups(id) AS (
+				SELECT gm.group_id FROM group_members gm WHERE gm.user_id = @user
+				UNION
+				SELECT g.parent_id FROM groups g JOIN member_groups mg ON g.id = mg.id WHERE g.parent_id IS NOT NULL
+			)
+			SELECT gr.role_id FROM group_roles gr JOIN member_groups mg ON gr.group_id = mg.id
+		)
+	)
+) AS allowed`
+
+// hasPermissionQuery ตรวจสอบสิทธิ์ด้วย permissionCheckSQL โดยไม่โหลดผู้ใช้ บทบาท หรือสิทธิ์ขึ้นมา
+func (s *AuthService) hasPermissionQuery(userID uint, resource string, action string) (bool, error) {
+	var result struct {
+		UserExists bool
+		Allowed    bool
+	}
+	err := s.db.Raw(permissionCheckSQL, map[string]interface{}{
+		"user":     userID,
+		"resource": resource,
+		"action":   action,
+		"now":      time.Now(),
+	}).Scan(&result).Error
+	if err != nil {
+		return false, err
+	}
+	if !result.UserExists {
+		return false, gorm.ErrRecordNotFound
+	}
+	return result.Allowed, nil
+}
+
+// hasPermissionInRoles ตรวจสอบสิทธิ์จากบทบาทของผู้ใช้ที่โหลดผ่าน GetUserByID และ GetGroupRoles
+func (s *AuthService) hasPermissionInRoles(userID uint, resource string, action string) (bool, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if rolesGrant(user.Roles, resource, action) {
+		return true, nil
+	}
+
+	// ตรวจสอบบทบาทจากกลุ่มเฉพาะเมื่อบทบาทโดยตรงไม่มีสิทธิ์
+	groupRoles, err := s.GetGroupRoles(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return rolesGrant(groupRoles, resource, action), nil
+}
+
+// GetGroupRoles ดึงบทบาทที่ผู้ใช้ได้รับผ่านกลุ่ม รวมถึงบทบาทของกลุ่มแม่ทุกระดับ
+func (s *AuthService) GetGroupRoles(userID uint) ([]models.Role, error) {
+	if s.cache != nil {
+		entry, err := s.cache.user(s.db, userID)
+		if err != nil {
+			return nil, err
+		}
+		return s.cache.rolesByID(s.db, entry.group)
+	}
+
+	var groupIDs []uint
+	if err := s.db.Table("group_members").Where("user_id = ?", userID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	groupIDs, err := ancestorGroups(s.db, groupIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, role := range user.Roles {
+	var roles []models.Role
+	roleIDs := s.db.Table("group_roles").Select("role_id").Where("group_id IN ?", groupIDs)
+	if err := s.db.Preload("Permissions").Where("id IN (?)", roleIDs).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// GetEffectiveRoles ดึงบทบาททั้งหมดที่มีผลกับผู้ใช้ (บทบาทโดยตรงและจากกลุ่ม) โดยไม่ซ้ำกัน
+func (s *AuthService) GetEffectiveRoles(userID uint) ([]models.Role, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupRoles, err := s.GetGroupRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return MergeRoles(user.Roles, groupRoles), nil
+}
+
+// ancestorGroups เพิ่มกลุ่มแม่ทุกระดับของกลุ่มที่ระบุ (ป้องกันการวนซ้ำกรณีข้อมูลเป็นวงจร)
+func ancestorGroups(db *gorm.DB, groupIDs []uint) ([]uint, error) {
+	visited := make(map[uint]bool)
+	var all []uint
+	frontier := groupIDs
+
+	for len(frontier) > 0 {
+		for _, id := range frontier {
+			if !visited[id] {
+				visited[id] = true
+				all = append(all, id)
+			}
+		}
+
+		var parentIDs []uint
+		if err := db.Model(&models.Group{}).Where("id IN ? AND parent_id IS NOT NULL", frontier).Pluck("parent_id", &parentIDs).Error; err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, id := range parentIDs {
+			if !visited[id] {
+				frontier = append(frontier, id)
+			}
+		}
+	}
+
+	return all, nil
+}
+
+// MergeRoles รวมบทบาทหลายชุดเข้าด้วยกันโดยตัดบทบาทที่ซ้ำออก
+func MergeRoles(roleSets ...[]models.Role) []models.Role {
+	seen := make(map[uint]bool)
+	var merged []models.Role
+	for _, roles := range roleSets {
+		for _, role := range roles {
+			if !seen[role.ID] {
+				seen[role.ID] = true
+				merged = append(merged, role)
+			}
+		}
+	}
+	return merged
+}
+
+// rolesGrant ตรวจสอบว่ามีบทบาทใดในรายการที่ให้สิทธิ์ resource:action
+func rolesGrant(roles []models.Role, resource string, action string) bool {
+	for _, role := range roles {
 		for _, perm := range role.Permissions {
 			if perm.Resource == resource && perm.Action == action {
-				return true, nil
+				return true
 			}
 		}
 	}
-
-	return false, nil
+	return false
 }
@@ -0,0 +1,209 @@
+// internal/service/auth_service_bench_test.go
+package service
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ข้อมูลทดสอบประสิทธิภาพ: ผู้ใช้ 100k คน แต่ละคนมีบทบาทโดยตรง 2 บทบาท (บางรายการหมดอายุแล้วหรือยังไม่เริ่ม)
+// และเป็นสมาชิกหนึ่งกลุ่มในสายกลุ่มซ้อนกันลึก 3 ระดับ ซึ่งแต่ละกลุ่มถือหนึ่งบทบาท
+const (
+	benchUsers       = 100000
+	benchRoles       = 40
+	benchGroups      = 120
+	benchResources   = 25
+	benchSchema      = "authz_bench"
+	benchSampleUsers = 500
+)
+
+var benchActions = []string{"read", "write", "delete", "approve"}
+
+var (
+	benchOnce sync.Once
+	benchDB   *gorm.DB
+	benchErr  error
+)
+
+// openBenchDB เชื่อมต่อฐานข้อมูลทดสอบ (ตัวแปร TEST_DB_* เหมือน integration test) และสร้างข้อมูลใน schema แยก
+// ข้ามการทดสอบเมื่อ SKIP_INTEGRATION_TESTS=true หรือเชื่อมต่อไม่ได้
+func openBenchDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	if os.Getenv("SKIP_INTEGRATION_TESTS") == "true" {
+		tb.Skip("Skipping database benchmark")
+	}
+
+	benchOnce.Do(func() {
+		benchDB, benchErr = seedBenchDB()
+	})
+	if benchErr != nil {
+		tb.Skipf("Benchmark database unavailable: %v", benchErr)
+	}
+	return benchDB
+}
+
+func benchEnv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func seedBenchDB() (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		benchEnv("TEST_DB_HOST", "localhost"),
+		benchEnv("TEST_DB_PORT", "5432"),
+		benchEnv("TEST_DB_USER", "postgres"),
+		benchEnv("TEST_DB_PASSWORD", "postgres"),
+		benchEnv("TEST_DB_NAME", "auth_api_test"),
+	)
+	gormConfig := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	// สร้าง schema ใหม่ทุกครั้ง เพื่อไม่ให้ชนกับข้อมูลของ integration test
+	admin, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := admin.Exec("DROP SCHEMA IF EXISTS " + benchSchema + " CASCADE").Error; err != nil {
+		return nil, err
+	}
+	if err := admin.Exec("CREATE SCHEMA " + benchSchema).Error; err != nil {
+		return nil, err
+	}
+	if sqlDB, err := admin.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+benchSchema), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SetupJoinTable(&models.User{}, "Roles", &models.UserRole{}); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&models.Organization{}, &models.User{}, &models.Role{}, &models.Permission{}, &models.Group{}, &models.UserRole{}); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		// index เดียวกับที่ database.MigrateDB สร้าง (import package database จากที่นี่ไม่ได้เพราะเป็นวงจร)
+		"CREATE INDEX idx_group_members_user_id ON group_members (user_id)",
+		fmt.Sprintf(`INSERT INTO permissions (id, resource, action, created_at, updated_at)
+			SELECT r * %d + a + 1, 'resource_' || r, (ARRAY['read','write','delete','approve'])[a + 1], now(), now()
+			FROM generate_series(0, %d) r, generate_series(0, 3) a`, len(benchActions), benchResources-1),
+		fmt.Sprintf(`INSERT INTO roles (id, name, created_at, updated_at)
+			SELECT i, 'role_' || i, now(), now() FROM generate_series(1, %d) i`, benchRoles),
+		// แต่ละบทบาทได้สิทธิ์ 8 รายการกระจายกันไป
+		fmt.Sprintf(`INSERT INTO role_permissions (role_id, permission_id)
+			SELECT DISTINCT r, (r * 13 + k * 7) %% %d + 1 FROM generate_series(1, %d) r, generate_series(0, 7) k`,
+			benchResources*len(benchActions), benchRoles),
+		// กลุ่มเรียงเป็นสายลึก 3 ระดับ: 1 <- 2 <- 3, 4 <- 5 <- 6, ...
+		fmt.Sprintf(`INSERT INTO groups (id, name, parent_id, created_at, updated_at)
+			SELECT i, 'group_' || i, CASE WHEN i %% 3 = 1 THEN NULL ELSE i - 1 END, now(), now()
+			FROM generate_series(1, %d) i`, benchGroups),
+		fmt.Sprintf(`INSERT INTO group_roles (group_id, role_id)
+			SELECT i, (i * 11) %% %d + 1 FROM generate_series(1, %d) i`, benchRoles, benchGroups),
+		fmt.Sprintf(`INSERT INTO users (id, username, email, password, created_at, updated_at)
+			SELECT i, 'user_' || i, 'user_' || i || '@example.com', 'x', now(), now()
+			FROM generate_series(1, %d) i`, benchUsers),
+		fmt.Sprintf(`INSERT INTO user_roles (user_id, role_id, valid_from, valid_until, created_at)
+			SELECT i, i %% %d + 1, NULL,
+				CASE WHEN i %% 10 = 0 THEN now() - interval '1 day' END, now()
+			FROM generate_series(1, %d) i`, benchRoles, benchUsers),
+		fmt.Sprintf(`INSERT INTO user_roles (user_id, role_id, valid_from, valid_until, created_at)
+			SELECT i, (i * 7 + 3) %% %d + 1,
+				CASE WHEN i %% 15 = 0 THEN now() + interval '1 day' END, NULL, now()
+			FROM generate_series(1, %d) i
+			ON CONFLICT DO NOTHING`, benchRoles, benchUsers),
+		fmt.Sprintf(`INSERT INTO group_members (group_id, user_id)
+			SELECT i %% %d + 1, i FROM generate_series(1, %d) i WHERE i %% 4 <> 0`, benchGroups, benchUsers),
+		"ANALYZE",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// benchCheck คืนผู้ใช้และสิทธิ์ที่ใช้ตรวจในรอบที่ i (กระจายทั่วทั้งชุดข้อมูลแต่ทำซ้ำได้)
+func benchCheck(i int) (uint, string, string) {
+	userID := uint((i*7919)%benchUsers + 1)
+	resource := fmt.Sprintf("resource_%d", (i*31)%benchResources)
+	action := benchActions[(i*17)%len(benchActions)]
+	return userID, resource, action
+}
+
+func BenchmarkHasPermission(b *testing.B) {
+	db := openBenchDB(b)
+
+	b.Run("LoadedRoles", func(b *testing.B) {
+		authService := NewAuthService(db, nil)
+		for i := 0; i < b.N; i++ {
+			userID, resource, action := benchCheck(i)
+			if _, err := authService.hasPermissionInRoles(userID, resource, action); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("SingleQuery", func(b *testing.B) {
+		authService := NewAuthService(db, nil)
+		for i := 0; i < b.N; i++ {
+			userID, resource, action := benchCheck(i)
+			if _, err := authService.hasPermissionQuery(userID, resource, action); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// วนตรวจผู้ใช้ชุดเล็กซ้ำๆ เพื่อวัดกรณีที่แคชถูกใช้งาน
+	b.Run("Cached", func(b *testing.B) {
+		authService := NewAuthService(db, nil)
+		authService.UseCache(NewPermissionCache(time.Hour))
+		for i := 0; i < b.N; i++ {
+			userID, resource, action := benchCheck(i % benchSampleUsers)
+			if _, err := authService.HasPermission(userID, resource, action); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestHasPermission_QueryMatchesLoadedRoles ผลของคำสั่งเดียวต้องตรงกับการโหลดบทบาทขึ้นมาตรวจ
+// ครอบคลุมบทบาทที่หมดอายุ บทบาทที่ยังไม่เริ่ม และบทบาทจากกลุ่มแม่
+func TestHasPermission_QueryMatchesLoadedRoles(t *testing.T) {
+	db := openBenchDB(t)
+	authService := NewAuthService(db, nil)
+
+	allowed := 0
+	for i := 0; i < benchSampleUsers; i++ {
+		for _, action := range benchActions {
+			userID, resource, _ := benchCheck(i)
+
+			expected, err := authService.hasPermissionInRoles(userID, resource, action)
+			require.NoError(t, err)
+			actual, err := authService.hasPermissionQuery(userID, resource, action)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual, "user %d %s:%s", userID, resource, action)
+			if actual {
+				allowed++
+			}
+		}
+	}
+	assert.Positive(t, allowed, "sample should include allowed checks")
+
+	_, err := authService.hasPermissionQuery(benchUsers+1, "resource_0", "read")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -2,6 +2,7 @@
 package service
 
 import (
+	"database/sql/driver"
 	"errors"
 	"testing"
 	"time"
@@ -52,6 +53,13 @@ func (s *AuthServiceTestSuite) AfterTest(_, _ string) {
 	s.NoError(s.mock.ExpectationsWereMet())
 }
 
+// expectActiveRoles mock การดึงบทบาทของผู้ใช้ที่การกำหนดยังไม่หมดอายุ
+func (s *AuthServiceTestSuite) expectActiveRoles(userID int, rows *sqlmock.Rows) {
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE id IN \(SELECT "role_id" FROM "user_roles" WHERE user_id = \$1 AND \(valid_from IS NULL OR valid_from <= \$2\) AND \(valid_until IS NULL OR valid_until > \$3\)\)`).
+		WithArgs(userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(rows)
+}
+
 func TestAuthServiceSuite(t *testing.T) {
 	suite.Run(t, new(AuthServiceTestSuite))
 }
@@ -89,12 +97,6 @@ func (s *AuthServiceTestSuite) TestLogin_InvalidCredentials() {
 
 	// ไม่ต้อง mock การโหลด roles เพราะจะไม่มีการเรียกถ้ารหัสผ่านไม่ถูกต้อง
 
-	// แม้จะตรวจสอบรหัสผ่านไม่ผ่าน โค้ดจริงยังพยายามโหลด user_roles
-	// (พฤติกรรมของ GORM ที่มีการ Preload("Roles.Permissions"))
-	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"\."user_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}))
-
 	// ทดสอบ login ด้วยรหัสผ่านที่ไม่ถูกต้อง
 	loginReq := &LoginRequest{
 		Username: "testuser",
@@ -120,17 +122,9 @@ func (s *AuthServiceTestSuite) TestLogin_Success() {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "full_name", "created_at", "updated_at"}).
 			AddRow(1, "testuser", "test@example.com", string(hashedPassword), "Test User", time.Now(), time.Now()))
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล user_roles
-	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"\."user_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).
-			AddRow(1, 1))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล roles
-	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
-			AddRow(1, "admin", "Administrator", time.Now(), time.Now()))
+	// Mock การดึงบทบาทที่การกำหนดให้ผู้ใช้ยังมีผลอยู่
+	s.expectActiveRoles(1, sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
+		AddRow(1, "admin", "Administrator", time.Now(), time.Now()))
 
 	// Mock เมื่อ GORM พยายามดึงข้อมูล role_permissions
 	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
@@ -162,6 +156,101 @@ func (s *AuthServiceTestSuite) TestLogin_Success() {
 	s.Equal("testuser", response.User["username"])
 }
 
+func (s *AuthServiceTestSuite) TestLogin_EmbedsPermissions() {
+	s.authService.EmbedPermissions(true)
+	s.expectLoginWithRoles()
+
+	response, err := s.authService.Login(&LoginRequest{Username: "testuser", Password: "correctpassword"})
+	s.NoError(err)
+
+	claims, err := s.jwtService.ValidateToken(response.AccessToken)
+	s.NoError(err)
+	s.Equal([]string{"editor"}, claims.Roles)
+	s.Equal([]string{"users:read", "users:write"}, claims.Permissions)
+	s.NotEmpty(claims.PermissionsVersion)
+	s.True(claims.HasPermission("users", "write"))
+	s.False(claims.HasPermission("users", "delete"))
+}
+
+// expectLoginWithRoles mock การ login ของผู้ใช้ที่มีบทบาท editor (users:read, users:write) และไม่ได้อยู่ในกลุ่มใด
+func (s *AuthServiceTestSuite) expectLoginWithRoles() {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.MinCost)
+	s.NoError(err)
+
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs("testuser", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password"}).
+			AddRow(1, "testuser", "test@example.com", string(hashedPassword)))
+	s.expectActiveRoles(1, sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "editor"))
+	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(1, 2).AddRow(1, 1))
+	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" IN \(\$1,\$2\)`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).
+			AddRow(2, "users", "write").
+			AddRow(1, "users", "read"))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+}
+
+func (s *AuthServiceTestSuite) TestLogin_ScopedToken() {
+	s.expectLoginWithRoles()
+
+	// reports:read ไม่ใช่สิทธิ์ของผู้ใช้ จึงถูกตัดออกจาก scope ที่ได้รับ
+	response, err := s.authService.Login(&LoginRequest{
+		Username: "testuser",
+		Password: "correctpassword",
+		Scope:    "users:read reports:read users:read",
+	})
+	s.NoError(err)
+	s.Equal("users:read", response.Scope)
+
+	claims, err := s.jwtService.ValidateToken(response.AccessToken)
+	s.NoError(err)
+	s.Equal("users:read", claims.Scope)
+	s.True(claims.ScopeCovers("users", "read"))
+	s.False(claims.ScopeCovers("users", "write"))
+}
+
+func (s *AuthServiceTestSuite) TestLogin_ScopeNotHeld() {
+	s.expectLoginWithRoles()
+
+	_, err := s.authService.Login(&LoginRequest{
+		Username: "testuser",
+		Password: "correctpassword",
+		Scope:    "reports:read",
+	})
+	s.ErrorIs(err, ErrInvalidScope)
+}
+
+func (s *AuthServiceTestSuite) TestLogin_ClientAudiences() {
+	s.authService.SetAudiences([]string{"auth-api"}, map[string][]string{
+		"reporting-dashboard": {"auth-api", "reporting"},
+	})
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.MinCost)
+	s.NoError(err)
+	expectUser := func() {
+		s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+			WithArgs("testuser", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password"}).
+				AddRow(1, "testuser", "test@example.com", string(hashedPassword)))
+	}
+
+	expectUser()
+	s.expectActiveRoles(1, sqlmock.NewRows([]string{"id", "name"}))
+	response, err := s.authService.Login(&LoginRequest{Username: "testuser", Password: "correctpassword", ClientID: "reporting-dashboard"})
+	s.NoError(err)
+	claims, err := s.jwtService.ValidateToken(response.AccessToken)
+	s.NoError(err)
+	s.Equal([]string{"auth-api", "reporting"}, []string(claims.Audience))
+
+	expectUser()
+	_, err = s.authService.Login(&LoginRequest{Username: "testuser", Password: "correctpassword", ClientID: "unknown"})
+	s.ErrorIs(err, ErrUnknownClient)
+}
+
 func (s *AuthServiceTestSuite) TestGetUserByID_Success() {
 	// Mock การค้นหาผู้ใช้จาก ID
 	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
@@ -169,17 +258,9 @@ func (s *AuthServiceTestSuite) TestGetUserByID_Success() {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "full_name", "created_at", "updated_at"}).
 			AddRow(1, "testuser", "test@example.com", "hashedpassword", "Test User", time.Now(), time.Now()))
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล user_roles
-	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"\."user_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).
-			AddRow(1, 1))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล roles
-	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
-			AddRow(1, "admin", "Administrator", time.Now(), time.Now()))
+	// Mock การดึงบทบาทที่การกำหนดให้ผู้ใช้ยังมีผลอยู่
+	s.expectActiveRoles(1, sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
+		AddRow(1, "admin", "Administrator", time.Now(), time.Now()))
 
 	// Mock เมื่อ GORM พยายามดึงข้อมูล role_permissions
 	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
@@ -225,77 +306,81 @@ func (s *AuthServiceTestSuite) TestGetUserByID_NotFound() {
 	s.Equal(gorm.ErrRecordNotFound, err)
 }
 
-func (s *AuthServiceTestSuite) TestHasPermission_Success() {
-	// Mock การค้นหาผู้ใช้จาก ID
-	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "full_name", "created_at", "updated_at"}).
-			AddRow(1, "testuser", "test@example.com", "hashedpassword", "Test User", time.Now(), time.Now()))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล user_roles
-	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"\."user_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).
-			AddRow(1, 1))
+// expectPermissionCheck mock query เดียวที่ HasPermission ใช้ตรวจสอบสิทธิ์เมื่อไม่มีแคช
+func (s *AuthServiceTestSuite) expectPermissionCheck(userID int, resource string, action string) *sqlmock.ExpectedQuery {
+	return s.mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM users WHERE id = \$1\) AS user_exists,.*WITH RECURSIVE member_groups`).
+		WithArgs(userID, resource, action, userID, sqlmock.AnyArg(), sqlmock.AnyArg(), userID)
+}
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล roles
-	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
-			AddRow(1, "admin", "Administrator", time.Now(), time.Now()))
+// recentTime ตรงกับเวลาที่ห่างจากปัจจุบันไม่เกินหนึ่งนาที
+type recentTime struct{}
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล role_permissions
-	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).
-			AddRow(1, 1).
-			AddRow(1, 2))
+func (recentTime) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && time.Since(t) >= 0 && time.Since(t) < time.Minute
+}
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล permissions
-	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" IN \(\$1,\$2\)`).
-		WithArgs(1, 2).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action", "description", "created_at", "updated_at"}).
-			AddRow(1, "users", "read", "Can read users", time.Now(), time.Now()).
-			AddRow(2, "users", "write", "Can write users", time.Now(), time.Now()))
+func (s *AuthServiceTestSuite) TestHasPermission_QueryBindsCurrentTime() {
+	// เมื่อปิดแคช ช่วงเวลาของการกำหนดบทบาทโดยตรงถูกตรวจกับเวลาปัจจุบันภายใน query เดียวกัน
+	s.mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM users WHERE id = \$1\) AS user_exists,.*`+
+		`AND \(ur\.valid_from IS NULL OR ur\.valid_from <= \$5\).*AND \(ur\.valid_until IS NULL OR ur\.valid_until > \$6\)`).
+		WithArgs(1, "users", "read", 1, recentTime{}, recentTime{}, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_exists", "allowed"}).AddRow(true, true))
 
-	// ทดสอบการตรวจสอบสิทธิ์ที่มี
 	hasPermission, err := s.authService.HasPermission(1, "users", "read")
 
-	// ตรวจสอบผลลัพธ์
 	s.NoError(err)
 	s.True(hasPermission)
 }
 
-func (s *AuthServiceTestSuite) TestHasPermission_NoPermission() {
-	// Mock การค้นหาผู้ใช้จาก ID
-	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "full_name", "created_at", "updated_at"}).
-			AddRow(1, "testuser", "test@example.com", "hashedpassword", "Test User", time.Now(), time.Now()))
+func (s *AuthServiceTestSuite) TestHasPermission_CachedDoesNotUseExistsQuery() {
+	// เมื่อใช้แคช การพลาดครั้งแรกโหลดผู้ใช้และบทบาทมาเก็บ ไม่ใช้ permissionCheckSQL และการตรวจครั้งต่อไปไม่เข้าฐานข้อมูล
+	s.authService.UseCache(NewPermissionCache(time.Minute))
 
-	// Mock เมื่อ GORM พยายามดึงข้อมูล user_roles
-	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"\."user_id" = \$1`).
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "alice"))
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1`).
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).
-			AddRow(1, 2))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล roles
-	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).AddRow(1, 2))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE id IN \(\$1\)`).
 		WithArgs(2).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
-			AddRow(2, "viewer", "Viewer", time.Now(), time.Now()))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล role_permissions
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "viewer"))
 	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
 		WithArgs(2).
-		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).
-			AddRow(2, 1))
-
-	// Mock เมื่อ GORM พยายามดึงข้อมูล permissions
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(2, 5))
 	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action", "description", "created_at", "updated_at"}).
-			AddRow(1, "users", "read", "Can read users", time.Now(), time.Now()))
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(5, "users", "read"))
+
+	hasPermission, err := s.authService.HasPermission(1, "users", "read")
+	s.NoError(err)
+	s.True(hasPermission)
+
+	hasPermission, err = s.authService.HasPermission(1, "users", "read")
+	s.NoError(err)
+	s.True(hasPermission)
+}
+
+func (s *AuthServiceTestSuite) TestHasPermission_Success() {
+	// ตรวจสอบสิทธิ์ด้วย query เดียว ไม่โหลดผู้ใช้ บทบาท หรือสิทธิ์
+	s.expectPermissionCheck(1, "users", "read").
+		WillReturnRows(sqlmock.NewRows([]string{"user_exists", "allowed"}).AddRow(true, true))
+
+	// ทดสอบการตรวจสอบสิทธิ์ที่มี
+	hasPermission, err := s.authService.HasPermission(1, "users", "read")
+
+	// ตรวจสอบผลลัพธ์
+	s.NoError(err)
+	s.True(hasPermission)
+}
+
+func (s *AuthServiceTestSuite) TestHasPermission_NoPermission() {
+	s.expectPermissionCheck(1, "users", "write").
+		WillReturnRows(sqlmock.NewRows([]string{"user_exists", "allowed"}).AddRow(true, false))
 
 	// ทดสอบการตรวจสอบสิทธิ์ที่ไม่มี
 	hasPermission, err := s.authService.HasPermission(1, "users", "write")
@@ -306,10 +391,9 @@ func (s *AuthServiceTestSuite) TestHasPermission_NoPermission() {
 }
 
 func (s *AuthServiceTestSuite) TestHasPermission_UserNotFound() {
-	// Mock การค้นหาผู้ใช้ที่ไม่พบในฐานข้อมูล
-	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
-		WithArgs(999, 1).
-		WillReturnError(gorm.ErrRecordNotFound)
+	// ผู้ใช้ที่ไม่มีอยู่ได้ ErrRecordNotFound เหมือนการโหลดผู้ใช้
+	s.expectPermissionCheck(999, "users", "read").
+		WillReturnRows(sqlmock.NewRows([]string{"user_exists", "allowed"}).AddRow(false, false))
 
 	// ทดสอบการตรวจสอบสิทธิ์กับผู้ใช้ที่ไม่มีอยู่
 	hasPermission, err := s.authService.HasPermission(999, "users", "read")
@@ -321,9 +405,8 @@ func (s *AuthServiceTestSuite) TestHasPermission_UserNotFound() {
 }
 
 func (s *AuthServiceTestSuite) TestHasPermission_DatabaseError() {
-	// Mock การค้นหาผู้ใช้ที่เกิด error
-	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
-		WithArgs(1, 1).
+	// Mock การตรวจสอบสิทธิ์ที่เกิด error
+	s.expectPermissionCheck(1, "users", "read").
 		WillReturnError(errors.New("database connection error"))
 
 	// ทดสอบการตรวจสอบสิทธิ์ที่เกิด error จากฐานข้อมูล
@@ -334,3 +417,158 @@ func (s *AuthServiceTestSuite) TestHasPermission_DatabaseError() {
 	s.False(hasPermission)
 	s.Equal("database connection error", err.Error())
 }
+
+func (s *AuthServiceTestSuite) TestHasPermission_ViaNestedGroupFromCache() {
+	s.authService.UseCache(NewPermissionCache(time.Minute))
+
+	// Mock การค้นหาผู้ใช้จาก ID (ผู้ใช้ไม่มีบทบาทโดยตรง)
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "testuser"))
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}))
+
+	// ผู้ใช้เป็นสมาชิกกลุ่ม 5 ซึ่งอยู่ใต้กลุ่ม 3
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}).AddRow(5))
+	s.mock.ExpectQuery(`SELECT "parent_id" FROM "groups" WHERE id IN \(\$1\) AND parent_id IS NOT NULL`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(3))
+	s.mock.ExpectQuery(`SELECT "parent_id" FROM "groups" WHERE id IN \(\$1\) AND parent_id IS NOT NULL`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id"}))
+
+	// บทบาทมาจากกลุ่มแม่ (กลุ่ม 3)
+	s.mock.ExpectQuery(`SELECT "role_id" FROM "group_roles" WHERE group_id IN \(\$1,\$2\)`).
+		WithArgs(5, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(4))
+	s.expectCachedRole(4, 2, "users", "write")
+
+	// ทดสอบการตรวจสอบสิทธิ์ที่ได้รับผ่านกลุ่มแม่
+	hasPermission, err := s.authService.HasPermission(1, "users", "write")
+
+	// ตรวจสอบผลลัพธ์
+	s.NoError(err)
+	s.True(hasPermission)
+}
+
+func (s *AuthServiceTestSuite) TestExplainPermission_ExpiredAssignment() {
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "password", "full_name", "created_at", "updated_at"}).
+			AddRow(1, "testuser", "test@example.com", "hashedpassword", "Test User", time.Now(), time.Now()))
+
+	// การกำหนดบทบาททั้งหมดของผู้ใช้ รวมถึงที่หมดอายุแล้ว
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "valid_from", "valid_until"}).
+			AddRow(1, 2, nil, time.Now().Add(-time.Hour)))
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"\."id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "editor"))
+	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(2, 1).AddRow(2, 2))
+	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" IN \(\$1,\$2\)`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).
+			AddRow(1, "users", "read").
+			AddRow(2, "users", "write"))
+
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+
+	explanation, err := s.authService.ExplainPermission(1, "users", "write")
+
+	s.NoError(err)
+	s.False(explanation.Allowed)
+	s.Contains(explanation.Reason, "not active")
+	s.Require().Len(explanation.Roles, 1)
+	s.False(explanation.Roles[0].Active)
+	s.True(explanation.Roles[0].Matched)
+	s.Contains(explanation.Roles[0].InactiveReason, "expired")
+	s.Equal([]PermissionTrace{
+		{Permission: "users:read", Matched: false, Reason: "action differs"},
+		{Permission: "users:write", Matched: true, Reason: "resource and action match"},
+	}, explanation.Roles[0].Permissions)
+}
+
+// expectCachedRole mock การโหลดบทบาทพร้อมสิทธิ์หนึ่งรายการเข้าแคช
+func (s *AuthServiceTestSuite) expectCachedRole(roleID int, permissionID int, resource string, action string) {
+	s.mock.ExpectQuery(`SELECT \* FROM "roles" WHERE id IN \(\$1\)`).
+		WithArgs(roleID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(roleID, "editor"))
+	s.mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"\."role_id" = \$1`).
+		WithArgs(roleID).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(roleID, permissionID))
+	s.mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"\."id" = \$1`).
+		WithArgs(permissionID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource", "action"}).AddRow(permissionID, resource, action))
+}
+
+func (s *AuthServiceTestSuite) TestHasPermission_CachedUntilRoleInvalidated() {
+	cache := NewPermissionCache(time.Minute)
+	s.authService.UseCache(cache)
+
+	// ครั้งแรกโหลดผู้ใช้ การกำหนดบทบาท กลุ่ม และบทบาทเข้าแคช
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "testuser"))
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).AddRow(1, 7))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+	s.expectCachedRole(7, 1, "documents", "read")
+
+	allowed, err := s.authService.HasPermission(1, "documents", "read")
+	s.NoError(err)
+	s.True(allowed)
+
+	// ครั้งที่สองไม่มีการ query ฐานข้อมูล
+	allowed, err = s.authService.HasPermission(1, "documents", "read")
+	s.NoError(err)
+	s.True(allowed)
+
+	// หลังสิทธิ์ของบทบาทเปลี่ยน โหลดเฉพาะบทบาทนั้นใหม่ ส่วนการกำหนดบทบาทของผู้ใช้ยังมาจากแคช
+	cache.InvalidateRole(7)
+	s.expectCachedRole(7, 2, "documents", "write")
+
+	allowed, err = s.authService.HasPermission(1, "documents", "read")
+	s.NoError(err)
+	s.False(allowed)
+
+	stats := cache.Stats()
+	s.Equal(uint64(1), stats.Invalidations)
+	s.Equal(uint64(3), stats.Misses) // ผู้ใช้ 1 ครั้ง และบทบาท 2 ครั้ง
+	s.Equal(uint64(4), stats.Hits)   // ผู้ใช้ 3 ครั้ง (รวมการตรวจบทบาทจากกลุ่มเมื่อไม่มีสิทธิ์) และบทบาท 1 ครั้ง
+}
+
+func (s *AuthServiceTestSuite) TestPermissionCache_ExpiresAtAssignmentBoundary() {
+	now := time.Now()
+	validUntil := now.Add(10 * time.Second)
+	validFrom := now.Add(30 * time.Second)
+
+	s.mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(1, "testuser"))
+	s.mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id", "valid_from", "valid_until"}).
+			AddRow(1, 7, nil, validUntil).
+			AddRow(1, 8, validFrom, nil))
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+
+	entry, err := loadCachedUser(s.DB, 1, now, time.Minute)
+	s.NoError(err)
+
+	// บทบาทที่ยังไม่เริ่มมีผลไม่ถูกนับ และแคชหมดอายุพร้อมการกำหนดบทบาทที่หมดอายุก่อน TTL
+	s.Equal([]uint{7}, entry.direct)
+	s.WithinDuration(validUntil, entry.expiresAt, time.Millisecond)
+}
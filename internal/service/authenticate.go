@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+// AuthenticationError error ของการยืนยันตัวตนที่ส่งข้อความกลับให้ client ได้ตรงๆ (HTTP 401 / gRPC Unauthenticated)
+type AuthenticationError struct {
+	Message string
+}
+
+func (e *AuthenticationError) Error() string {
+	return e.Message
+}
+
+// Principal ผู้ใช้ที่ยืนยันตัวตนแล้ว พร้อมบทบาทที่มีผลจริง (บทบาทโดยตรงและบทบาทที่ได้รับผ่านกลุ่ม)
+type Principal struct {
+	Claims *jwt.Claims
+	User   *models.User
+	Roles  []models.Role
+}
+
+// BearerToken อ่าน token จาก header Authorization รูปแบบ "Bearer <token>"
+func BearerToken(authHeader string) (string, error) {
+	if authHeader == "" {
+		return "", &AuthenticationError{Message: "Authorization header is required"}
+	}
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", &AuthenticationError{Message: "Authorization header format must be Bearer <token>"}
+	}
+	return parts[1], nil
+}
+
+// ValidateAccessToken ตรวจ token และถ้าระบุ audiences จะยอมรับเฉพาะ token ที่ออกให้ audience ใด audience หนึ่งในรายการ
+func ValidateAccessToken(jwtService *jwt.JWTService, token string, audiences []string) (*jwt.Claims, error) {
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, &AuthenticationError{Message: "Invalid or expired token"}
+	}
+	if len(audiences) > 0 && !claims.HasAudience(audiences...) {
+		return nil, &AuthenticationError{Message: "Token audience is not accepted"}
+	}
+	return claims, nil
+}
+
+// Authenticate โหลดผู้ใช้ของ token ตรวจว่า tenant ตรงกัน และรวมบทบาทที่มีผล
+// ใช้ร่วมกันระหว่าง AuthMiddleware และ ext_authz เพื่อให้ทั้งสองทางตัดสินเหมือนกันเสมอ
+func Authenticate(authService AuthServiceInterface, claims *jwt.Claims) (*Principal, error) {
+	user, err := authService.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, &AuthenticationError{Message: "User not found"}
+	}
+
+	// token ต้องออกให้กับ tenant เดียวกับที่ผู้ใช้สังกัดอยู่ในปัจจุบัน
+	if !models.SameOrganization(claims.TenantID, user.OrganizationID) {
+		return nil, &AuthenticationError{Message: "Token tenant does not match user"}
+	}
+
+	groupRoles, err := authService.GetGroupRoles(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load group roles: %w", err)
+	}
+
+	return &Principal{
+		Claims: claims,
+		User:   user,
+		Roles:  MergeRoles(user.Roles, groupRoles),
+	}, nil
+}
+
+// RoleNames คืนชื่อบทบาทเรียงและไม่ซ้ำ (บทบาท global และบทบาทของ tenant อาจมีชื่อเดียวกัน)
+func RoleNames(roles []models.Role) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, role := range roles {
+		if !seen[role.Name] {
+			seen[role.Name] = true
+			names = append(names, role.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// PermissionCacheChannel ช่อง LISTEN/NOTIFY ที่ใช้แจ้งการ invalidate แคชสิทธิ์ระหว่าง instance
+const PermissionCacheChannel = "permission_cache"
+
+// maxNotifyPayload ขนาด payload สูงสุดที่ส่ง (PostgreSQL จำกัด payload ของ NOTIFY ไว้ไม่ถึง 8000 ไบต์)
+// การแจ้งที่ใหญ่กว่านี้ (เช่นสมาชิกกลุ่มจำนวนมาก) จะถูกส่งเป็นการล้างแคชทั้งหมดแทน
+const maxNotifyPayload = 7900
+
+// maxListenBackoff ระยะรอสูงสุดก่อนเชื่อมต่อ LISTEN ใหม่
+const maxListenBackoff = time.Minute
+
+// cacheInvalidation ข้อความที่ส่งผ่าน NOTIFY
+type cacheInvalidation struct {
+	Origin string `json:"origin"`
+	Scope  string `json:"scope"`
+	IDs    []uint `json:"ids,omitempty"`
+}
+
+// CacheSync กระจายการ invalidate แคชสิทธิ์ไปยังทุก instance ของ API ผ่าน PostgreSQL LISTEN/NOTIFY
+// instance ที่แก้ไขข้อมูลส่ง NOTIFY หลัง commit และทุก instance ฟังช่องเดียวกันผ่านการเชื่อมต่อแยกของตนเอง
+type CacheSync struct {
+	db     *gorm.DB
+	dsn    string
+	cache  *PermissionCache
+	origin string // ใช้ข้ามข้อความที่ instance นี้ส่งเอง
+}
+
+func NewCacheSync(db *gorm.DB, dsn string, cache *PermissionCache) (*CacheSync, error) {
+	origin, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheSync{
+		db:     db,
+		dsn:    dsn,
+		cache:  cache,
+		origin: origin,
+	}, nil
+}
+
+// Publish แจ้ง instance อื่นให้ invalidate ตามขอบเขตที่ระบุ (ใช้เป็น InvalidationHook ของ PermissionCache)
+// ถ้าส่งไม่สำเร็จ instance อื่นจะเห็นการเปลี่ยนแปลงเมื่อแคชครบ TTL
+func (s *CacheSync) Publish(scope string, ids []uint) {
+	payload, err := json.Marshal(cacheInvalidation{Origin: s.origin, Scope: scope, IDs: ids})
+	if err == nil && len(payload) > maxNotifyPayload {
+		payload, err = json.Marshal(cacheInvalidation{Origin: s.origin, Scope: InvalidateScopeAll})
+	}
+	if err != nil {
+		log.Printf("Failed to encode permission cache invalidation: %v", err)
+		return
+	}
+
+	if err := s.db.Exec("SELECT pg_notify(?, ?)", PermissionCacheChannel, string(payload)).Error; err != nil {
+		log.Printf("Failed to publish permission cache invalidation: %v", err)
+	}
+}
+
+// StartListener ฟังการแจ้งจาก instance อื่นจนกว่า context จะถูกยกเลิก
+// เมื่อการเชื่อมต่อหลุดจะเชื่อมต่อใหม่โดยรอนานขึ้นเรื่อยๆ (เริ่มที่ retryInterval สูงสุด maxListenBackoff)
+func (s *CacheSync) StartListener(ctx context.Context, retryInterval time.Duration) {
+	backoff := retryInterval
+	for {
+		err := s.listen(ctx, func() { backoff = retryInterval })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Permission cache listener disconnected: %v (retrying in %s)", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxListenBackoff)
+	}
+}
+
+// listen เชื่อมต่อ LISTEN แล้วรับการแจ้งจนกว่าการเชื่อมต่อจะหลุด connected ถูกเรียกเมื่อเริ่มฟังได้แล้ว
+func (s *CacheSync) listen(ctx context.Context, connected func()) error {
+	conn, err := pgx.Connect(ctx, s.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+PermissionCacheChannel); err != nil {
+		return err
+	}
+
+	// ระหว่างที่ไม่ได้ฟัง (ก่อนเริ่มหรือระหว่างเชื่อมต่อใหม่) อาจพลาดการแจ้งไป จึงล้างแคชทั้งหมดหลังเริ่มฟังแล้ว
+	s.cache.Apply(InvalidateScopeAll, nil)
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		s.handle(notification.Payload)
+	}
+}
+
+// handle นำการแจ้งหนึ่งรายการไปใช้กับแคชของ instance นี้ ข้อความที่อ่านไม่ได้จะล้างแคชทั้งหมดเพื่อความปลอดภัย
+func (s *CacheSync) handle(payload string) {
+	var message cacheInvalidation
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		log.Printf("Invalid permission cache invalidation %q: %v", payload, err)
+		s.cache.Apply(InvalidateScopeAll, nil)
+		return
+	}
+	if message.Origin == s.origin {
+		return
+	}
+	s.cache.Apply(message.Scope, message.IDs)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCacheSync_PublishesLocalInvalidations(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	cache := NewPermissionCache(time.Minute)
+	cacheSync, err := NewCacheSync(db, "", cache)
+	require.NoError(t, err)
+	cache.OnInvalidate(cacheSync.Publish)
+
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(PermissionCacheChannel, `{"origin":"`+cacheSync.origin+`","scope":"role","ids":[3]}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	cache.InvalidateRole(3)
+
+	// รายการที่ใหญ่เกินขีดจำกัดของ NOTIFY ถูกส่งเป็นการล้างแคชทั้งหมด
+	userIDs := make([]uint, 2000)
+	for i := range userIDs {
+		userIDs[i] = uint(i + 1)
+	}
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(PermissionCacheChannel, `{"origin":"`+cacheSync.origin+`","scope":"all"}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	cache.InvalidateUser(userIDs...)
+
+	// การแจ้งที่ได้รับจาก instance อื่นไม่ถูกส่งต่อ
+	cache.Apply(InvalidateScopeUser, []uint{1})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCacheSync_HandleAppliesRemoteInvalidations(t *testing.T) {
+	cache := NewPermissionCache(time.Minute)
+	cacheSync, err := NewCacheSync(nil, "", cache)
+	require.NoError(t, err)
+
+	fill := func() {
+		cache.users[1] = &cachedUser{expiresAt: time.Now().Add(time.Minute)}
+		cache.users[2] = &cachedUser{expiresAt: time.Now().Add(time.Minute)}
+	}
+
+	// ข้อความที่ instance นี้ส่งเองถูกข้าม
+	fill()
+	cacheSync.handle(`{"origin":"` + cacheSync.origin + `","scope":"user","ids":[1]}`)
+	assert.Len(t, cache.users, 2)
+
+	cacheSync.handle(`{"origin":"other","scope":"user","ids":[1]}`)
+	assert.Len(t, cache.users, 1)
+	assert.Contains(t, cache.users, uint(2))
+
+	// ข้อความที่อ่านไม่ได้ล้างแคชทั้งหมด
+	fill()
+	cacheSync.handle(strings.Repeat("x", 10))
+	assert.Empty(t, cache.users)
+}
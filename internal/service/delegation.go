@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// DelegationError error ที่คืนเมื่อผู้ให้สิทธิ์พยายามให้สิทธิ์ที่ตนเองไม่มี
+type DelegationError struct {
+	MissingPermissions []string
+}
+
+func (e *DelegationError) Error() string {
+	return fmt.Sprintf("cannot grant permissions you do not hold: %s", strings.Join(e.MissingPermissions, ", "))
+}
+
+// CheckCanGrantRole ตรวจสอบว่าผู้ให้สิทธิ์กำหนดบทบาทนี้ให้ผู้อื่นได้หรือไม่
+// ได้เมื่อสิทธิ์ของบทบาทเป็นส่วนหนึ่งของสิทธิ์ที่ผู้ให้มีอยู่ หรือบทบาทถูกกำหนดให้มอบต่อได้โดยบทบาทที่ผู้ให้ถืออยู่
+func CheckCanGrantRole(db *gorm.DB, grantorID uint, roleID uint) error {
+	heldRoleIDs, err := effectiveRoleIDs(db, grantorID, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(heldRoleIDs) > 0 {
+		var delegations int64
+		err := db.Table("role_delegations").
+			Where("role_id = ? AND grantor_role_id IN ?", roleID, heldRoleIDs).
+			Count(&delegations).Error
+		if err != nil {
+			return err
+		}
+		if delegations > 0 {
+			return nil
+		}
+	}
+
+	var role models.Role
+	if err := db.Preload("Permissions").First(&role, roleID).Error; err != nil {
+		return err
+	}
+
+	return checkHeldPermissions(db, heldRoleIDs, role.Permissions)
+}
+
+// CheckCanGrantPermissions ตรวจสอบว่าผู้ให้สิทธิ์มีสิทธิ์ทั้งหมดที่จะเพิ่มให้บทบาท
+func CheckCanGrantPermissions(db *gorm.DB, grantorID uint, permissions []models.Permission) error {
+	heldRoleIDs, err := effectiveRoleIDs(db, grantorID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return checkHeldPermissions(db, heldRoleIDs, permissions)
+}
+
+// checkHeldPermissions คืน DelegationError ที่ระบุสิทธิ์ที่ขาด ถ้าบทบาทที่ถืออยู่ไม่ครอบคลุมสิทธิ์ที่ต้องการ
+func checkHeldPermissions(db *gorm.DB, heldRoleIDs []uint, required []models.Permission) error {
+	if len(required) == 0 {
+		return nil
+	}
+
+	held := make(map[uint]bool)
+	if len(heldRoleIDs) > 0 {
+		var heldPermissionIDs []uint
+		err := db.Table("role_permissions").
+			Where("role_id IN ?", heldRoleIDs).
+			Pluck("permission_id", &heldPermissionIDs).Error
+		if err != nil {
+			return err
+		}
+		for _, id := range heldPermissionIDs {
+			held[id] = true
+		}
+	}
+
+	var missing []string
+	for _, perm := range required {
+		if !held[perm.ID] {
+			missing = append(missing, perm.Key())
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	return &DelegationError{MissingPermissions: missing}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type DelegationTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *gorm.DB
+}
+
+func (s *DelegationTestSuite) SetupTest() {
+	// สร้าง mock ของฐานข้อมูล
+	db, mock, err := sqlmock.New()
+	s.NoError(err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	s.NoError(err)
+	s.mock = mock
+	s.db = gormDB
+}
+
+func (s *DelegationTestSuite) AfterTest(_, _ string) {
+	// ตรวจสอบว่ามีการเรียก expect ทั้งหมดหรือไม่
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+// expectHeldRoles คาดหวังการดึงบทบาทที่ผู้ให้สิทธิ์ถืออยู่ (ไม่มีกลุ่ม)
+func (s *DelegationTestSuite) expectHeldRoles(rows *sqlmock.Rows) {
+	s.mock.ExpectQuery(`SELECT "role_id" FROM "user_roles" WHERE user_id = \$1`).
+		WillReturnRows(rows)
+	s.mock.ExpectQuery(`SELECT "group_id" FROM "group_members" WHERE user_id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id"}))
+}
+
+func TestDelegationSuite(t *testing.T) {
+	suite.Run(t, new(DelegationTestSuite))
+}
+
+func (s *DelegationTestSuite) TestCheckCanGrantPermissions_Missing() {
+	s.expectHeldRoles(sqlmock.NewRows([]string{"role_id"}).AddRow(4))
+	s.mock.ExpectQuery(`SELECT "permission_id" FROM "role_permissions" WHERE role_id IN \(\$1\)`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"permission_id"}).AddRow(1))
+
+	err := CheckCanGrantPermissions(s.db, 7, []models.Permission{
+		{ID: 1, Resource: "users", Action: "read"},
+		{ID: 2, Resource: "roles", Action: "write"},
+	})
+
+	var delegationErr *DelegationError
+	s.ErrorAs(err, &delegationErr)
+	s.Equal([]string{"roles:write"}, delegationErr.MissingPermissions)
+}
+
+func (s *DelegationTestSuite) TestCheckCanGrantPermissions_Held() {
+	s.expectHeldRoles(sqlmock.NewRows([]string{"role_id"}).AddRow(4))
+	s.mock.ExpectQuery(`SELECT "permission_id" FROM "role_permissions" WHERE role_id IN \(\$1\)`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"permission_id"}).AddRow(1).AddRow(2))
+
+	err := CheckCanGrantPermissions(s.db, 7, []models.Permission{
+		{ID: 1, Resource: "users", Action: "read"},
+		{ID: 2, Resource: "roles", Action: "write"},
+	})
+
+	s.NoError(err)
+}
+
+func (s *DelegationTestSuite) TestCheckCanGrantRole_Delegable() {
+	// บทบาทที่มอบต่อได้โดยบทบาทที่ถืออยู่ ไม่ต้องตรวจสิทธิ์รายข้อ
+	s.expectHeldRoles(sqlmock.NewRows([]string{"role_id"}).AddRow(4))
+	s.mock.ExpectQuery(`SELECT count\(\*\) FROM "role_delegations" WHERE role_id = \$1 AND grantor_role_id IN \(\$2\)`).
+		WithArgs(1, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	s.NoError(CheckCanGrantRole(s.db, 7, 1))
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+)
+
+// ที่มาของบทบาทในผลการอธิบาย
+const (
+	RoleSourceDirect = "direct"
+	RoleSourceGroup  = "group"
+)
+
+// PermissionExplainer บริการที่อธิบายได้ว่าทำไมผู้ใช้จึงได้หรือไม่ได้รับสิทธิ์
+// แยกจาก AuthServiceInterface เพื่อให้ middleware ใช้เมื่อบริการรองรับเท่านั้น
+type PermissionExplainer interface {
+	ExplainPermission(userID uint, resource string, action string) (*PermissionExplanation, error)
+}
+
+// PermissionExplanation ผลการประเมินสิทธิ์แบบละเอียด
+type PermissionExplanation struct {
+	UserID         uint        `json:"user_id"`
+	OrganizationID *uint       `json:"organization_id"`
+	Resource       string      `json:"resource"`
+	Action         string      `json:"action"`
+	Allowed        bool        `json:"allowed"`
+	Reason         string      `json:"reason"`
+	Roles          []RoleTrace `json:"roles"`
+}
+
+// RoleTrace บทบาทหนึ่งที่ถูกพิจารณา และผลของสิทธิ์แต่ละข้อในบทบาทนั้น
+type RoleTrace struct {
+	RoleID         uint              `json:"role_id"`
+	RoleName       string            `json:"role_name"`
+	Source         string            `json:"source"`
+	Active         bool              `json:"active"`
+	InactiveReason string            `json:"inactive_reason,omitempty"`
+	Matched        bool              `json:"matched"`
+	Permissions    []PermissionTrace `json:"permissions"`
+}
+
+// PermissionTrace ผลการเทียบสิทธิ์หนึ่งข้อกับ resource:action ที่ขอ
+type PermissionTrace struct {
+	Permission string `json:"permission"`
+	Matched    bool   `json:"matched"`
+	Reason     string `json:"reason"`
+}
+
+// ExplainPermission ประเมินสิทธิ์แบบเดียวกับ HasPermission แต่คืนรายละเอียดทุกขั้นตอน
+// รวมถึงการกำหนดบทบาทที่หมดอายุหรือยังไม่เริ่มมีผล เพื่อให้เห็นว่าทำไมจึงไม่ได้รับสิทธิ์
+func (s *AuthService) ExplainPermission(userID uint, resource string, action string) (*PermissionExplanation, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var bindings []models.UserRole
+	if err := s.db.Preload("Role.Permissions").Where("user_id = ?", userID).Find(&bindings).Error; err != nil {
+		return nil, err
+	}
+
+	groupRoles, err := s.GetGroupRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &PermissionExplanation{
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
+		Resource:       resource,
+		Action:         action,
+		Roles:          []RoleTrace{},
+	}
+
+	now := time.Now()
+	for _, binding := range bindings {
+		if binding.Role == nil {
+			continue
+		}
+		trace := traceRole(*binding.Role, RoleSourceDirect, resource, action)
+		if !binding.IsActive(now) {
+			trace.Active = false
+			trace.InactiveReason = inactiveReason(binding, now)
+		}
+		explanation.add(trace)
+	}
+	for _, role := range groupRoles {
+		explanation.add(traceRole(role, RoleSourceGroup, resource, action))
+	}
+
+	switch {
+	case explanation.Allowed:
+		// Reason ถูกตั้งโดยบทบาทแรกที่ให้สิทธิ์แล้ว
+	case len(explanation.Roles) == 0:
+		explanation.Reason = "user has no roles"
+	case explanation.hasInactiveMatch():
+		explanation.Reason = fmt.Sprintf("%s:%s is only granted by role assignments that are not active", resource, action)
+	default:
+		explanation.Reason = fmt.Sprintf("no role grants %s:%s", resource, action)
+	}
+
+	return explanation, nil
+}
+
+// add เพิ่มบทบาทในผล และบันทึกเหตุผลที่อนุญาตจากบทบาทแรกที่ให้สิทธิ์
+func (e *PermissionExplanation) add(trace RoleTrace) {
+	e.Roles = append(e.Roles, trace)
+	if trace.Matched && trace.Active && !e.Allowed {
+		e.Allowed = true
+		e.Reason = fmt.Sprintf("granted by %s role %q", trace.Source, trace.RoleName)
+	}
+}
+
+func (e *PermissionExplanation) hasInactiveMatch() bool {
+	for _, trace := range e.Roles {
+		if trace.Matched && !trace.Active {
+			return true
+		}
+	}
+	return false
+}
+
+// traceRole เทียบสิทธิ์ทุกข้อของบทบาทกับ resource:action ที่ขอ
+func traceRole(role models.Role, source string, resource string, action string) RoleTrace {
+	trace := RoleTrace{
+		RoleID:      role.ID,
+		RoleName:    role.Name,
+		Source:      source,
+		Active:      true,
+		Permissions: []PermissionTrace{},
+	}
+
+	for _, perm := range role.Permissions {
+		permTrace := PermissionTrace{Permission: perm.Key()}
+		switch {
+		case perm.Resource == resource && perm.Action == action:
+			permTrace.Matched = true
+			permTrace.Reason = "resource and action match"
+			trace.Matched = true
+		case perm.Resource != resource && perm.Action != action:
+			permTrace.Reason = "resource and action differ"
+		case perm.Resource != resource:
+			permTrace.Reason = "resource differs"
+		default:
+			permTrace.Reason = "action differs"
+		}
+		trace.Permissions = append(trace.Permissions, permTrace)
+	}
+
+	return trace
+}
+
+func inactiveReason(binding models.UserRole, now time.Time) string {
+	if binding.ValidFrom != nil && binding.ValidFrom.After(now) {
+		return "assignment starts at " + binding.ValidFrom.Format(time.RFC3339)
+	}
+	return "assignment expired at " + binding.ValidUntil.Format(time.RFC3339)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HolderTypeUser ชนิดของผู้ถือสิทธิ์ที่ FindPermissionHolders คืน บัญชีบริการไม่มีบทบาทจึงไม่เคยเป็นผู้ถือสิทธิ์
+const HolderTypeUser = "user"
+
+// PermissionHolder ผู้ใช้ที่มีสิทธิ์ resource:action ที่มีผลอยู่ พร้อมเส้นทางทุกเส้นที่ให้สิทธิ์นั้น
+type PermissionHolder struct {
+	Type           string      `json:"type"`
+	ID             uint        `json:"id"`
+	Username       string      `json:"username"`
+	OrganizationID *uint       `json:"organization_id"`
+	Paths          []GrantPath `json:"paths"`
+}
+
+// GrantPath เส้นทางหนึ่งที่ให้สิทธิ์: บทบาทโดยตรง หรือบทบาทของกลุ่ม (ผ่านกลุ่มแม่ได้หลายระดับ)
+type GrantPath struct {
+	Source   string `json:"source"`
+	RoleID   uint   `json:"role_id"`
+	RoleName string `json:"role_name"`
+	// GroupIDs เรียงจากกลุ่มที่ผู้ใช้เป็นสมาชิกขึ้นไปจนถึงกลุ่มที่ถือบทบาท
+	GroupIDs []uint `json:"group_ids,omitempty"`
+}
+
+// holderRow แถวผลลัพธ์จากการ join ผู้ใช้กับบทบาทหรือกลุ่ม
+type holderRow struct {
+	UserID         uint
+	Username       string
+	OrganizationID *uint
+	RoleID         uint
+	RoleName       string
+	GroupID        uint
+}
+
+// groupGrant เส้นทางจากกลุ่มที่ถือบทบาทลงมาถึงกลุ่มปัจจุบัน
+type groupGrant struct {
+	roleID   uint
+	roleName string
+	chain    []uint // กลุ่มที่ถือบทบาทอยู่ตัวแรก
+}
+
+// FindPermissionHolders หาผู้ใช้ทุกคนที่มีสิทธิ์ resource:action ที่มีผลอยู่ ทั้งจากบทบาทโดยตรงและผ่านกลุ่ม
+// คำนวณด้วยการ join ในฐานข้อมูล จึงไม่ต้องโหลดผู้ใช้ทั้งหมด organizationID ไม่เป็น nil จะจำกัดเฉพาะผู้ใช้ใน tenant นั้น
+func FindPermissionHolders(db *gorm.DB, resource string, action string, organizationID *uint) ([]PermissionHolder, error) {
+	holders := make(map[uint]*PermissionHolder)
+	addPath := func(row holderRow, path GrantPath) {
+		holder, ok := holders[row.UserID]
+		if !ok {
+			holder = &PermissionHolder{Type: HolderTypeUser, ID: row.UserID, Username: row.Username, OrganizationID: row.OrganizationID}
+			holders[row.UserID] = holder
+		}
+		holder.Paths = append(holder.Paths, path)
+	}
+
+	now := time.Now()
+	var direct []holderRow
+	query := db.Table("user_roles").
+		Select("users.id AS user_id, users.username, users.organization_id, roles.id AS role_id, roles.name AS role_name").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.resource = ? AND permissions.action = ?", resource, action).
+		Where("user_roles.valid_from IS NULL OR user_roles.valid_from <= ?", now).
+		Where("user_roles.valid_until IS NULL OR user_roles.valid_until > ?", now)
+	if organizationID != nil {
+		query = query.Where("users.organization_id = ?", *organizationID)
+	}
+	if err := query.Order("users.id, roles.id").Scan(&direct).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range direct {
+		addPath(row, GrantPath{Source: RoleSourceDirect, RoleID: row.RoleID, RoleName: row.RoleName})
+	}
+
+	grants, err := groupGrants(db, resource, action)
+	if err != nil {
+		return nil, err
+	}
+	if len(grants) > 0 {
+		groupIDs := make([]uint, 0, len(grants))
+		for id := range grants {
+			groupIDs = append(groupIDs, id)
+		}
+		sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+
+		var members []holderRow
+		query := db.Table("group_members").
+			Select("users.id AS user_id, users.username, users.organization_id, group_members.group_id").
+			Joins("JOIN users ON users.id = group_members.user_id").
+			Where("group_members.group_id IN ?", groupIDs)
+		if organizationID != nil {
+			query = query.Where("users.organization_id = ?", *organizationID)
+		}
+		if err := query.Order("users.id, group_members.group_id").Scan(&members).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range members {
+			for _, grant := range grants[row.GroupID] {
+				addPath(row, GrantPath{
+					Source:   RoleSourceGroup,
+					RoleID:   grant.roleID,
+					RoleName: grant.roleName,
+					GroupIDs: reversedIDs(grant.chain),
+				})
+			}
+		}
+	}
+
+	result := make([]PermissionHolder, 0, len(holders))
+	for _, holder := range holders {
+		result = append(result, *holder)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// groupGrants คืนกลุ่มทุกกลุ่มที่ได้รับสิทธิ์ (รวมกลุ่มย่อยของกลุ่มที่ถือบทบาท) พร้อมเส้นทางจากกลุ่มที่ถือบทบาท
+func groupGrants(db *gorm.DB, resource string, action string) (map[uint][]groupGrant, error) {
+	var holding []holderRow
+	err := db.Table("group_roles").
+		Select("group_roles.group_id, roles.id AS role_id, roles.name AS role_name").
+		Joins("JOIN roles ON roles.id = group_roles.role_id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("permissions.resource = ? AND permissions.action = ?", resource, action).
+		Scan(&holding).Error
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make(map[uint][]groupGrant)
+	frontier := make(map[uint][]groupGrant)
+	for _, row := range holding {
+		grant := groupGrant{roleID: row.RoleID, roleName: row.RoleName, chain: []uint{row.GroupID}}
+		grants[row.GroupID] = append(grants[row.GroupID], grant)
+		frontier[row.GroupID] = append(frontier[row.GroupID], grant)
+	}
+
+	// ไล่ลงไปยังกลุ่มย่อยทีละระดับ สมาชิกของกลุ่มย่อยได้รับบทบาทของกลุ่มแม่ด้วย
+	for len(frontier) > 0 {
+		parentIDs := make([]uint, 0, len(frontier))
+		for id := range frontier {
+			parentIDs = append(parentIDs, id)
+		}
+		sort.Slice(parentIDs, func(i, j int) bool { return parentIDs[i] < parentIDs[j] })
+
+		var children []struct {
+			ID       uint
+			ParentID uint
+		}
+		if err := db.Table("groups").Select("id, parent_id").Where("parent_id IN ?", parentIDs).Scan(&children).Error; err != nil {
+			return nil, err
+		}
+
+		next := make(map[uint][]groupGrant)
+		for _, child := range children {
+			for _, grant := range frontier[child.ParentID] {
+				if containsID(grant.chain, child.ID) {
+					continue // ข้อมูลเป็นวงจร
+				}
+				chain := append(append([]uint{}, grant.chain...), child.ID)
+				inherited := groupGrant{roleID: grant.roleID, roleName: grant.roleName, chain: chain}
+				grants[child.ID] = append(grants[child.ID], inherited)
+				next[child.ID] = append(next[child.ID], inherited)
+			}
+		}
+		frontier = next
+	}
+
+	return grants, nil
+}
+
+func reversedIDs(ids []uint) []uint {
+	reversed := make([]uint, len(ids))
+	for i, id := range ids {
+		reversed[len(ids)-1-i] = id
+	}
+	return reversed
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type HoldersTestSuite struct {
+	suite.Suite
+	mock sqlmock.Sqlmock
+	db   *gorm.DB
+}
+
+func (s *HoldersTestSuite) SetupTest() {
+	// สร้าง mock ของฐานข้อมูล
+	db, mock, err := sqlmock.New()
+	s.NoError(err)
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	s.NoError(err)
+	s.mock = mock
+	s.db = gormDB
+}
+
+func (s *HoldersTestSuite) AfterTest(_, _ string) {
+	// ตรวจสอบว่ามีการเรียก expect ทั้งหมดหรือไม่
+	s.NoError(s.mock.ExpectationsWereMet())
+}
+
+func TestHoldersSuite(t *testing.T) {
+	suite.Run(t, new(HoldersTestSuite))
+}
+
+func (s *HoldersTestSuite) TestFindPermissionHolders_DirectAndNestedGroup() {
+	// ผู้ใช้ 1 ได้สิทธิ์จากบทบาทโดยตรง
+	s.mock.ExpectQuery(`SELECT users.id AS user_id, .* FROM "user_roles" JOIN users .* WHERE \(permissions.resource = \$1 AND permissions.action = \$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "organization_id", "role_id", "role_name"}).
+			AddRow(1, "admin", nil, 1, "admin"))
+
+	// กลุ่ม 10 ถือบทบาท editor และมีกลุ่มย่อย 11 ซึ่งผู้ใช้ 2 เป็นสมาชิก
+	s.mock.ExpectQuery(`SELECT group_roles.group_id, .* FROM "group_roles" JOIN roles`).
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "role_id", "role_name"}).AddRow(10, 2, "editor"))
+	s.mock.ExpectQuery(`SELECT id, parent_id FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(11, 10))
+	s.mock.ExpectQuery(`SELECT id, parent_id FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}))
+	s.mock.ExpectQuery(`SELECT users.id AS user_id, .* FROM "group_members" JOIN users .* WHERE group_members.group_id IN \(\$1,\$2\)`).
+		WithArgs(10, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "organization_id", "group_id"}).AddRow(2, "alice", nil, 11))
+
+	holders, err := FindPermissionHolders(s.db, "users", "write", nil)
+
+	s.NoError(err)
+	s.Require().Len(holders, 2)
+	s.Equal(uint(1), holders[0].ID)
+	s.Equal([]GrantPath{{Source: RoleSourceDirect, RoleID: 1, RoleName: "admin"}}, holders[0].Paths)
+	s.Equal(uint(2), holders[1].ID)
+	s.Equal([]GrantPath{{Source: RoleSourceGroup, RoleID: 2, RoleName: "editor", GroupIDs: []uint{11, 10}}}, holders[1].Paths)
+}
+
+func (s *HoldersTestSuite) TestFindPermissionHolders_GroupInheritance() {
+	organizationID := uint(3)
+
+	// ผู้ใช้ 5 มีบทบาทโดยตรงด้วย จึงมีหลายเส้นทาง
+	s.mock.ExpectQuery(`SELECT users.id AS user_id, .* FROM "user_roles" JOIN users .* AND users.organization_id = \$5`).
+		WithArgs("reports", "read", sqlmock.AnyArg(), sqlmock.AnyArg(), organizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "organization_id", "role_id", "role_name"}).
+			AddRow(5, "bob", organizationID, 4, "reporter"))
+
+	// กลุ่ม 20 ถือบทบาท viewer และ 21 ถือ analyst โดย 20 -> 21 -> 22 ผู้ใช้ 5 อยู่ใน 22 ผู้ใช้ 6 อยู่ใน 20
+	s.mock.ExpectQuery(`SELECT group_roles.group_id, .* FROM "group_roles" JOIN roles`).
+		WithArgs("reports", "read").
+		WillReturnRows(sqlmock.NewRows([]string{"group_id", "role_id", "role_name"}).
+			AddRow(20, 6, "viewer").
+			AddRow(21, 7, "analyst"))
+	s.mock.ExpectQuery(`SELECT id, parent_id FROM "groups" WHERE parent_id IN \(\$1,\$2\)`).
+		WithArgs(20, 21).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(21, 20).AddRow(22, 21))
+	s.mock.ExpectQuery(`SELECT id, parent_id FROM "groups" WHERE parent_id IN \(\$1,\$2\)`).
+		WithArgs(21, 22).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(22, 21))
+	// ข้อมูลเป็นวงจร 22 -> 20 ต้องไม่ไล่ซ้ำไม่สิ้นสุด
+	s.mock.ExpectQuery(`SELECT id, parent_id FROM "groups" WHERE parent_id IN \(\$1\)`).
+		WithArgs(22).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(20, 22))
+	s.mock.ExpectQuery(`SELECT users.id AS user_id, .* FROM "group_members" JOIN users .* WHERE group_members.group_id IN \(\$1,\$2,\$3\) AND users.organization_id = \$4`).
+		WithArgs(20, 21, 22, organizationID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "organization_id", "group_id"}).
+			AddRow(5, "bob", organizationID, 22).
+			AddRow(6, "carol", organizationID, 20))
+
+	holders, err := FindPermissionHolders(s.db, "reports", "read", &organizationID)
+
+	s.NoError(err)
+	s.Require().Len(holders, 2)
+	s.Equal(PermissionHolder{
+		Type: HolderTypeUser, ID: 5, Username: "bob", OrganizationID: &organizationID,
+		Paths: []GrantPath{
+			{Source: RoleSourceDirect, RoleID: 4, RoleName: "reporter"},
+			{Source: RoleSourceGroup, RoleID: 7, RoleName: "analyst", GroupIDs: []uint{22, 21}},
+			{Source: RoleSourceGroup, RoleID: 6, RoleName: "viewer", GroupIDs: []uint{22, 21, 20}},
+		},
+	}, holders[0])
+	s.Equal(PermissionHolder{
+		Type: HolderTypeUser, ID: 6, Username: "carol", OrganizationID: &organizationID,
+		Paths: []GrantPath{{Source: RoleSourceGroup, RoleID: 6, RoleName: "viewer", GroupIDs: []uint{20}}},
+	}, holders[1])
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// PermissionCache แคชบทบาทที่มีผลของผู้ใช้แต่ละคน และสิทธิ์ของแต่ละบทบาทในหน่วยความจำ
+// ทุกการเปลี่ยนแปลงบทบาท สิทธิ์ หรือการกำหนดบทบาทต้องเรียก Invalidate* หลัง commit
+// ส่วน TTL เป็นเพียงตาข่ายกันพลาดกรณีที่มีการแก้ไขฐานข้อมูลจากช่องทางอื่น
+// เมธอดทั้งหมดเรียกบน nil ได้ (ไม่มีแคช)
+type PermissionCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	users map[uint]*cachedUser
+	roles map[uint]*cachedRole
+	// epoch เพิ่มขึ้นทุกครั้งที่ invalidate ค่าที่โหลดมาก่อนหน้านั้นจะไม่ถูกเก็บ
+	// เพื่อไม่ให้การโหลดที่อ่านข้อมูลเก่าระหว่างการแก้ไขเขียนทับการ invalidate
+	epoch uint64
+	hooks []InvalidationHook
+
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+// cachedUser ผู้ใช้ (ไม่รวม Roles) พร้อม ID ของบทบาทโดยตรงที่มีผลอยู่และบทบาทที่ได้รับผ่านกลุ่ม
+type cachedUser struct {
+	user      models.User
+	direct    []uint
+	group     []uint
+	expiresAt time.Time
+}
+
+// cachedRole บทบาทพร้อมสิทธิ์
+type cachedRole struct {
+	role      models.Role
+	expiresAt time.Time
+}
+
+// PermissionCacheStats สถิติของแคช
+type PermissionCacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	TTL           string  `json:"ttl"`
+	Users         int     `json:"users"`
+	Roles         int     `json:"roles"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Invalidations uint64  `json:"invalidations"`
+}
+
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		ttl:   ttl,
+		users: make(map[uint]*cachedUser),
+		roles: make(map[uint]*cachedRole),
+	}
+}
+
+// ขอบเขตของการ invalidate
+const (
+	InvalidateScopeUser       = "user"
+	InvalidateScopeRole       = "role"
+	InvalidateScopePermission = "permission"
+	InvalidateScopeAll        = "all"
+)
+
+// InvalidationHook ถูกเรียกหลังการ invalidate ที่เกิดใน instance นี้ (เช่นเพื่อแจ้ง instance อื่น)
+type InvalidationHook func(scope string, ids []uint)
+
+// OnInvalidate ลงทะเบียน hook ที่จะถูกเรียกหลังการ invalidate ผ่านเมธอด Invalidate* (ไม่รวม Apply)
+func (c *PermissionCache) OnInvalidate(hook InvalidationHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook)
+}
+
+// InvalidateUser ลบบทบาทที่แคชไว้ของผู้ใช้ (ใช้เมื่อการกำหนดบทบาท การเป็นสมาชิกกลุ่ม หรือข้อมูลผู้ใช้เปลี่ยน)
+func (c *PermissionCache) InvalidateUser(userIDs ...uint) {
+	c.invalidate(InvalidateScopeUser, userIDs, true)
+}
+
+// InvalidateRole ลบสิทธิ์ที่แคชไว้ของบทบาท (ใช้เมื่อบทบาทหรือสิทธิ์ของบทบาทเปลี่ยน)
+// ผู้ใช้ที่ถือบทบาทไม่ต้องถูกลบ เพราะแคชของผู้ใช้เก็บเพียง ID ของบทบาท
+func (c *PermissionCache) InvalidateRole(roleIDs ...uint) {
+	c.invalidate(InvalidateScopeRole, roleIDs, true)
+}
+
+// InvalidatePermission ลบบทบาทที่แคชไว้ทุกบทบาทที่มีสิทธิ์นี้ (ใช้เมื่อสิทธิ์ถูกแก้ไขหรือลบ)
+func (c *PermissionCache) InvalidatePermission(permissionID uint) {
+	c.invalidate(InvalidateScopePermission, []uint{permissionID}, true)
+}
+
+// InvalidateAll ล้างแคชทั้งหมด
+func (c *PermissionCache) InvalidateAll() {
+	c.invalidate(InvalidateScopeAll, nil, true)
+}
+
+// Apply invalidate ตามที่ได้รับแจ้งจาก instance อื่น โดยไม่เรียก hook ซ้ำ ขอบเขตที่ไม่รู้จักจะล้างแคชทั้งหมด
+func (c *PermissionCache) Apply(scope string, ids []uint) {
+	c.invalidate(scope, ids, false)
+}
+
+func (c *PermissionCache) invalidate(scope string, ids []uint, notify bool) {
+	if c == nil {
+		return
+	}
+	if scope != InvalidateScopeAll && len(ids) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	switch scope {
+	case InvalidateScopeUser:
+		for _, id := range ids {
+			delete(c.users, id)
+		}
+	case InvalidateScopeRole:
+		for _, id := range ids {
+			delete(c.roles, id)
+		}
+	case InvalidateScopePermission:
+		for id, entry := range c.roles {
+			for _, perm := range entry.role.Permissions {
+				if containsID(ids, perm.ID) {
+					delete(c.roles, id)
+					break
+				}
+			}
+		}
+	default:
+		scope = InvalidateScopeAll
+		c.users = make(map[uint]*cachedUser)
+		c.roles = make(map[uint]*cachedRole)
+	}
+	c.epoch++
+	hooks := c.hooks
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+
+	if notify {
+		for _, hook := range hooks {
+			hook(scope, ids)
+		}
+	}
+}
+
+// Stats คืนสถิติของแคช
+func (c *PermissionCache) Stats() PermissionCacheStats {
+	if c == nil {
+		return PermissionCacheStats{}
+	}
+	c.mu.Lock()
+	stats := PermissionCacheStats{
+		Enabled: true,
+		TTL:     c.ttl.String(),
+		Users:   len(c.users),
+		Roles:   len(c.roles),
+	}
+	c.mu.Unlock()
+
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	stats.Invalidations = c.invalidations.Load()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// user คืนผู้ใช้ที่แคชไว้ โหลดจากฐานข้อมูลถ้ายังไม่มีหรือหมดอายุ
+func (c *PermissionCache) user(db *gorm.DB, userID uint) (*cachedUser, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.users[userID]
+	epoch := c.epoch
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		c.hits.Add(1)
+		return entry, nil
+	}
+	c.misses.Add(1)
+
+	entry, err := loadCachedUser(db, userID, now, c.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.epoch == epoch {
+		c.users[userID] = entry
+	}
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// rolesByID คืนบทบาทพร้อมสิทธิ์ตามลำดับของ roleIDs โหลดเฉพาะบทบาทที่ยังไม่มีในแคช
+// บทบาทที่ไม่มีอยู่แล้ว (เช่นถูกลบ) จะถูกข้ามไป
+func (c *PermissionCache) rolesByID(db *gorm.DB, roleIDs []uint) ([]models.Role, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	found := make(map[uint]*cachedRole, len(roleIDs))
+	var missing []uint
+	c.mu.Lock()
+	for _, id := range roleIDs {
+		if entry, ok := c.roles[id]; ok && now.Before(entry.expiresAt) {
+			found[id] = entry
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	epoch := c.epoch
+	c.mu.Unlock()
+	c.hits.Add(uint64(len(roleIDs) - len(missing)))
+
+	if len(missing) > 0 {
+		c.misses.Add(uint64(len(missing)))
+		var roles []models.Role
+		if err := db.Preload("Permissions").Where("id IN ?", missing).Find(&roles).Error; err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		for _, role := range roles {
+			entry := &cachedRole{role: role, expiresAt: now.Add(c.ttl)}
+			found[role.ID] = entry
+			if c.epoch == epoch {
+				c.roles[role.ID] = entry
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	roles := make([]models.Role, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if entry, ok := found[id]; ok {
+			roles = append(roles, entry.role)
+		}
+	}
+	return roles, nil
+}
+
+// loadCachedUser โหลดผู้ใช้และ ID ของบทบาทที่มีผล ณ now
+// แคชหมดอายุเมื่อครบ TTL หรือเมื่อการกำหนดบทบาทใดเริ่มมีผลหรือหมดอายุ แล้วแต่อย่างใดถึงก่อน
+func loadCachedUser(db *gorm.DB, userID uint, now time.Time, ttl time.Duration) (*cachedUser, error) {
+	entry := &cachedUser{expiresAt: now.Add(ttl)}
+	if err := db.First(&entry.user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var bindings []models.UserRole
+	if err := db.Where("user_id = ?", userID).Find(&bindings).Error; err != nil {
+		return nil, err
+	}
+	for _, binding := range bindings {
+		if binding.IsActive(now) {
+			entry.direct = append(entry.direct, binding.RoleID)
+		}
+		for _, boundary := range []*time.Time{binding.ValidFrom, binding.ValidUntil} {
+			if boundary != nil && boundary.After(now) && boundary.Before(entry.expiresAt) {
+				entry.expiresAt = *boundary
+			}
+		}
+	}
+
+	group, err := groupRoleIDs(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	entry.group = group
+	return entry, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// DeleteRole ลบบทบาทพร้อมข้อมูลทุกตารางที่อ้างถึงบทบาทนั้น (การกำหนดให้ผู้ใช้ บทบาทของกลุ่ม สิทธิ์ กฎ SoD และการมอบสิทธิ์ให้บทบาท) และปฏิเสธคำขอบทบาทที่ยังรออนุมัติ
+// ต้องเรียกภายใน transaction คืน ID ของผู้ใช้ที่ถือบทบาทอยู่ก่อนลบ ทั้งโดยตรงและผ่านกลุ่ม (รวมกลุ่มย่อย) เพื่อ invalidate แคช
+// ผู้เรียกที่ต้องการ audit log ของการถอนบทบาทแต่ละคนต้องถอนผ่าน AssignmentService ก่อนเรียกฟังก์ชันนี้
+func DeleteRole(tx *gorm.DB, role *models.Role) ([]uint, error) {
+	var holderIDs []uint
+	if err := tx.Model(&models.UserRole{}).Where("role_id = ?", role.ID).Pluck("user_id", &holderIDs).Error; err != nil {
+		return nil, err
+	}
+
+	var groupIDs []uint
+	if err := tx.Table("group_roles").Where("role_id = ?", role.ID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(groupIDs) > 0 {
+		groupIDs, err := descendantGroups(tx, groupIDs)
+		if err != nil {
+			return nil, err
+		}
+		var memberIDs []uint
+		if err := tx.Table("group_members").Where("group_id IN ?", groupIDs).Pluck("user_id", &memberIDs).Error; err != nil {
+			return nil, err
+		}
+		holderIDs = append(holderIDs, memberIDs...)
+	}
+
+	for _, table := range []string{"user_roles", "group_roles", "sod_rule_roles"} {
+		if err := tx.Table(table).Where("role_id = ?", role.ID).Delete(nil).Error; err != nil {
+			return nil, err
+		}
+	}
+	// คำขอที่ยังรออนุมัติจะอนุมัติไม่ได้อีก คำขอที่ตัดสินแล้วเก็บไว้เป็นประวัติ (access_requests ไม่มี foreign key ไปยังบทบาท)
+	err := tx.Model(&models.AccessRequest{}).
+		Where("role_id = ? AND status = ?", role.ID, models.AccessRequestPending).
+		Updates(map[string]interface{}{
+			"status":        models.AccessRequestDenied,
+			"decision_note": "role deleted",
+			"decided_at":    time.Now(),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM role_delegations WHERE role_id = ? OR grantor_role_id = ?", role.ID, role.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Select("Permissions").Delete(role).Error; err != nil {
+		return nil, err
+	}
+	return holderIDs, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrLastRoleManager คืนเมื่อการดำเนินการจะทำให้ไม่เหลือผู้ใช้ที่จัดการบทบาทได้ (roles:write) ซึ่งจะล็อกทุกคนออกจากระบบ
+var ErrLastRoleManager = errors.New("operation would leave no active user able to manage roles")
+
+// EnsureRoleManagerRemains ตรวจสอบว่ายังมีผู้ใช้ระดับ global อย่างน้อยหนึ่งคนที่มีสิทธิ์ roles:write ที่มีผลอยู่
+// นับเฉพาะบทบาทระดับ global และผู้ใช้ที่ไม่อยู่ใน tenant เพราะผู้ดูแล tenant จัดการบทบาทระดับ global ไม่ได้
+// เรียกใน transaction หลังการลบหรือถอนสิทธิ์ เพื่อให้ rollback ได้ถ้าละเมิดเงื่อนไข
+func EnsureRoleManagerRemains(db *gorm.DB) error {
+	now := time.Now()
+	managerRoleIDs := db.Table("role_permissions").
+		Select("role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("permissions.resource = ? AND permissions.action = ?", "roles", "write").
+		Where("roles.organization_id IS NULL")
+
+	var directManagers int64
+	directUserIDs := db.Model(&models.UserRole{}).Select("user_id").
+		Where("role_id IN (?)", managerRoleIDs).
+		Where("valid_from IS NULL OR valid_from <= ?", now).
+		Where("valid_until IS NULL OR valid_until > ?", now)
+	if err := db.Model(&models.User{}).Where("id IN (?) AND organization_id IS NULL", directUserIDs).Count(&directManagers).Error; err != nil {
+		return err
+	}
+	if directManagers > 0 {
+		return nil
+	}
+
+	// ผู้ใช้ที่ได้รับสิทธิ์ผ่านกลุ่ม (รวมกลุ่มย่อยของกลุ่มที่มีบทบาทนั้น)
+	var groupIDs []uint
+	if err := db.Table("group_roles").Where("role_id IN (?)", managerRoleIDs).Pluck("group_id", &groupIDs).Error; err != nil {
+		return err
+	}
+	if len(groupIDs) == 0 {
+		return ErrLastRoleManager
+	}
+
+	groupIDs, err := descendantGroups(db, groupIDs)
+	if err != nil {
+		return err
+	}
+
+	var groupManagers int64
+	memberIDs := db.Table("group_members").Select("user_id").Where("group_id IN ?", groupIDs)
+	if err := db.Model(&models.User{}).Where("id IN (?) AND organization_id IS NULL", memberIDs).Count(&groupManagers).Error; err != nil {
+		return err
+	}
+	if groupManagers == 0 {
+		return ErrLastRoleManager
+	}
+
+	return nil
+}
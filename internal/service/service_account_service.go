@@ -0,0 +1,93 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidServiceCredentials = errors.New("invalid service credentials")
+
+// ServiceAccountAuthenticator ยืนยันตัวตนของบริการจาก client_id และ client_secret
+type ServiceAccountAuthenticator interface {
+	Authenticate(clientID string, clientSecret string) (*models.ServiceAccount, error)
+}
+
+// ServiceAccountService จัดการบัญชีบริการและยืนยันตัวตนของบริการที่เรียก API การตัดสินสิทธิ์
+type ServiceAccountService struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountService(db *gorm.DB) *ServiceAccountService {
+	return &ServiceAccountService{
+		db: db,
+	}
+}
+
+// Create สร้างบัญชีบริการใหม่ และคืน client_secret แบบ plain text ซึ่งแสดงได้ครั้งเดียวเท่านั้น
+func (s *ServiceAccountService) Create(name string, description string) (*models.ServiceAccount, string, error) {
+	clientID, err := randomHex(12)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	account := &models.ServiceAccount{
+		Name:        name,
+		Description: description,
+		ClientID:    "svc_" + clientID,
+		SecretHash:  hashSecret(secret),
+	}
+	if err := s.db.Create(account).Error; err != nil {
+		return nil, "", err
+	}
+
+	return account, secret, nil
+}
+
+// Authenticate ตรวจสอบ client_id และ client_secret
+func (s *ServiceAccountService) Authenticate(clientID string, clientSecret string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	if err := s.db.Where("client_id = ?", clientID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidServiceCredentials
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(clientSecret)), []byte(account.SecretHash)) != 1 {
+		return nil, ErrInvalidServiceCredentials
+	}
+
+	// บันทึกเวลาใช้งานล่าสุดเพื่อช่วยหาบัญชีที่ไม่ได้ใช้แล้ว (ไม่ถือเป็น error ถ้าบันทึกไม่สำเร็จ)
+	now := time.Now()
+	s.db.Model(&account).UpdateColumn("last_used_at", now)
+	account.LastUsedAt = &now
+
+	return &account, nil
+}
+
+// ตรวจสอบว่า ServiceAccountService เข้ากันได้กับ ServiceAccountAuthenticator
+var _ ServiceAccountAuthenticator = (*ServiceAccountService)(nil)
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// ประเภทการเปลี่ยนแปลงที่จำลองได้
+const (
+	SimulateAddPermission    = "add_permission"
+	SimulateRemovePermission = "remove_permission"
+	SimulateDeleteRole       = "delete_role"
+	SimulateAssignRole       = "assign_role"
+	SimulateRevokeRole       = "revoke_role"
+)
+
+var ErrInvalidSimulation = errors.New("invalid simulation")
+
+// SimulatedChange การเปลี่ยนแปลงที่เสนอหนึ่งรายการ
+// add_permission/remove_permission ใช้ RoleID กับ Permission (resource:action)
+// delete_role ใช้ RoleID ส่วน assign_role/revoke_role ใช้ RoleID กับ UserID
+type SimulatedChange struct {
+	Type       string `json:"type" binding:"required"`
+	RoleID     uint   `json:"role_id" binding:"required"`
+	UserID     uint   `json:"user_id,omitempty"`
+	Permission string `json:"permission,omitempty"`
+}
+
+// AccessDelta สิทธิ์ที่มีผลของผู้ใช้หนึ่งคนที่จะได้รับเพิ่มหรือสูญเสียไป
+type AccessDelta struct {
+	UserID         uint     `json:"user_id"`
+	Username       string   `json:"username"`
+	OrganizationID *uint    `json:"organization_id"`
+	Gained         []string `json:"gained"`
+	Lost           []string `json:"lost"`
+}
+
+// SimulationResult ผลการจำลอง เฉพาะผู้ใช้ที่สิทธิ์ที่มีผลเปลี่ยนไป
+type SimulationResult struct {
+	Changes  []SimulatedChange `json:"changes"`
+	Affected []AccessDelta     `json:"affected"`
+}
+
+// accessState บทบาทของผู้ใช้ที่เกี่ยวข้องและสิทธิ์ของบทบาท ณ จุดหนึ่ง ใช้ประเมินการเปลี่ยนแปลงในหน่วยความจำ
+type accessState struct {
+	direct      map[uint][]uint          // บทบาทโดยตรงที่มีผลอยู่
+	group       map[uint][]uint          // บทบาทที่ได้รับผ่านกลุ่ม
+	permissions map[uint]map[string]bool // สิทธิ์ (resource:action) ของแต่ละบทบาท
+}
+
+// SimulateChanges ประเมินว่าผู้ใช้คนใดจะได้รับหรือสูญเสียสิทธิ์ใดหากนำการเปลี่ยนแปลงไปใช้ โดยไม่บันทึกสิ่งใดลงฐานข้อมูล
+// organizationID ไม่เป็น nil จะรายงานเฉพาะผู้ใช้ใน tenant นั้น
+func SimulateChanges(db *gorm.DB, changes []SimulatedChange, organizationID *uint) (*SimulationResult, error) {
+	if err := validateSimulation(db, changes, organizationID); err != nil {
+		return nil, err
+	}
+
+	before, users, err := loadAccessState(db, changes, organizationID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	after := before.apply(changes)
+
+	result := &SimulationResult{Changes: changes, Affected: []AccessDelta{}}
+	for _, user := range users {
+		gained, lost := diffKeys(before.effective(user.ID), after.effective(user.ID))
+		if len(gained) == 0 && len(lost) == 0 {
+			continue
+		}
+		result.Affected = append(result.Affected, AccessDelta{
+			UserID:         user.ID,
+			Username:       user.Username,
+			OrganizationID: user.OrganizationID,
+			Gained:         gained,
+			Lost:           lost,
+		})
+	}
+	return result, nil
+}
+
+// validateSimulation ตรวจสอบว่าการเปลี่ยนแปลงทุกรายการครบถ้วนและอ้างถึงบทบาท ผู้ใช้ และสิทธิ์ที่มีอยู่จริง
+// organizationID ไม่เป็น nil จะหาเฉพาะบทบาทระดับ global หรือของ tenant นั้นและผู้ใช้ใน tenant นั้น
+// เพื่อไม่ให้ผู้ดูแล tenant ใช้การจำลองตรวจว่ามี ID ของ tenant อื่นอยู่หรือไม่
+func validateSimulation(db *gorm.DB, changes []SimulatedChange, organizationID *uint) error {
+	roles := db.Model(&models.Role{})
+	users := db.Model(&models.User{})
+	if organizationID != nil {
+		roles = roles.Where("roles.organization_id IS NULL OR roles.organization_id = ?", *organizationID)
+		users = users.Where("users.organization_id = ?", *organizationID)
+	}
+
+	for i, change := range changes {
+		var role models.Role
+		if err := roles.Session(&gorm.Session{}).Select("id").First(&role, change.RoleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: change %d: role %d not found", ErrInvalidSimulation, i, change.RoleID)
+			}
+			return err
+		}
+
+		switch change.Type {
+		case SimulateAddPermission, SimulateRemovePermission:
+			resource, action, _ := strings.Cut(change.Permission, ":")
+			var count int64
+			err := db.Model(&models.Permission{}).
+				Where("resource = ? AND action = ?", resource, action).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("%w: change %d: permission %q not found", ErrInvalidSimulation, i, change.Permission)
+			}
+		case SimulateAssignRole, SimulateRevokeRole:
+			var user models.User
+			if err := users.Session(&gorm.Session{}).Select("id").First(&user, change.UserID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: change %d: user %d not found", ErrInvalidSimulation, i, change.UserID)
+				}
+				return err
+			}
+		case SimulateDeleteRole:
+		default:
+			return fmt.Errorf("%w: change %d: unknown type %q", ErrInvalidSimulation, i, change.Type)
+		}
+	}
+	return nil
+}
+
+// loadAccessState โหลดบทบาทของผู้ใช้ที่อาจได้รับผลกระทบ (ผู้ถือบทบาทที่ถูกเปลี่ยนและผู้ใช้ที่ถูกกำหนดบทบาท)
+// และสิทธิ์ของบทบาททั้งหมดที่ผู้ใช้เหล่านั้นถืออยู่
+func loadAccessState(db *gorm.DB, changes []SimulatedChange, organizationID *uint, now time.Time) (*accessState, []models.User, error) {
+	var candidateIDs []uint
+	for _, change := range changes {
+		switch change.Type {
+		case SimulateAssignRole, SimulateRevokeRole:
+			candidateIDs = append(candidateIDs, change.UserID)
+		default:
+			holderIDs, err := roleHolderIDs(db, change.RoleID, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			candidateIDs = append(candidateIDs, holderIDs...)
+		}
+	}
+
+	var users []models.User
+	if len(candidateIDs) > 0 {
+		query := db.Select("id, username, organization_id").Where("id IN ?", candidateIDs)
+		if organizationID != nil {
+			query = query.Where("organization_id = ?", *organizationID)
+		}
+		if err := query.Order("id").Find(&users).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	state := &accessState{
+		direct:      make(map[uint][]uint, len(users)),
+		group:       make(map[uint][]uint, len(users)),
+		permissions: make(map[uint]map[string]bool),
+	}
+	roleIDs := make(map[uint]bool)
+	for _, change := range changes {
+		roleIDs[change.RoleID] = true
+	}
+	for _, user := range users {
+		var direct []uint
+		if err := activeRoleIDs(db, user.ID, now).Pluck("role_id", &direct).Error; err != nil {
+			return nil, nil, err
+		}
+		group, err := groupRoleIDs(db, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		state.direct[user.ID] = direct
+		state.group[user.ID] = group
+		for _, id := range append(direct, group...) {
+			roleIDs[id] = true
+		}
+	}
+
+	ids := make([]uint, 0, len(roleIDs))
+	for id := range roleIDs {
+		ids = append(ids, id)
+	}
+	var roles []models.Role
+	if err := db.Preload("Permissions").Where("id IN ?", ids).Find(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, role := range roles {
+		state.permissions[role.ID] = heldPermissionKeys(role.Permissions)
+	}
+
+	return state, users, nil
+}
+
+// apply คืนสถานะใหม่หลังนำการเปลี่ยนแปลงไปใช้ตามลำดับ โดยไม่แก้ไขสถานะเดิม
+func (s *accessState) apply(changes []SimulatedChange) *accessState {
+	next := &accessState{
+		direct:      make(map[uint][]uint, len(s.direct)),
+		group:       make(map[uint][]uint, len(s.group)),
+		permissions: make(map[uint]map[string]bool, len(s.permissions)),
+	}
+	for userID, roleIDs := range s.direct {
+		next.direct[userID] = append([]uint{}, roleIDs...)
+	}
+	for userID, roleIDs := range s.group {
+		next.group[userID] = append([]uint{}, roleIDs...)
+	}
+	for roleID, keys := range s.permissions {
+		copied := make(map[string]bool, len(keys))
+		for key := range keys {
+			copied[key] = true
+		}
+		next.permissions[roleID] = copied
+	}
+
+	for _, change := range changes {
+		switch change.Type {
+		case SimulateAddPermission:
+			if next.permissions[change.RoleID] == nil {
+				next.permissions[change.RoleID] = make(map[string]bool)
+			}
+			next.permissions[change.RoleID][change.Permission] = true
+		case SimulateRemovePermission:
+			delete(next.permissions[change.RoleID], change.Permission)
+		case SimulateDeleteRole:
+			delete(next.permissions, change.RoleID)
+			for userID := range next.direct {
+				next.direct[userID] = withoutID(next.direct[userID], change.RoleID)
+				next.group[userID] = withoutID(next.group[userID], change.RoleID)
+			}
+		case SimulateAssignRole:
+			// บทบาทที่ถูกลบไปแล้วในการเปลี่ยนแปลงก่อนหน้าไม่มีสิทธิ์ใดเหลือ
+			if _, exists := next.permissions[change.RoleID]; exists && !containsID(next.direct[change.UserID], change.RoleID) {
+				next.direct[change.UserID] = append(next.direct[change.UserID], change.RoleID)
+			}
+		case SimulateRevokeRole:
+			next.direct[change.UserID] = withoutID(next.direct[change.UserID], change.RoleID)
+		}
+	}
+	return next
+}
+
+// effective คืนสิทธิ์ที่มีผลของผู้ใช้จากบทบาทโดยตรงและบทบาทจากกลุ่ม
+func (s *accessState) effective(userID uint) map[string]bool {
+	keys := make(map[string]bool)
+	for _, roleID := range append(append([]uint{}, s.direct[userID]...), s.group[userID]...) {
+		for key := range s.permissions[roleID] {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// groupRoleIDs คืน ID ของบทบาทที่ผู้ใช้ได้รับผ่านกลุ่ม รวมถึงกลุ่มแม่ทุกระดับ
+func groupRoleIDs(db *gorm.DB, userID uint) ([]uint, error) {
+	var groupIDs []uint
+	if err := db.Table("group_members").Where("user_id = ?", userID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	groupIDs, err := ancestorGroups(db, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var roleIDs []uint
+	if err := db.Table("group_roles").Where("group_id IN ?", groupIDs).Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	return roleIDs, nil
+}
+
+func heldPermissionKeys(permissions []models.Permission) map[string]bool {
+	keys := make(map[string]bool, len(permissions))
+	for _, perm := range permissions {
+		keys[perm.Key()] = true
+	}
+	return keys
+}
+
+// diffKeys คืนสิทธิ์ที่มีใน after แต่ไม่มีใน before (gained) และกลับกัน (lost) เรียงตามตัวอักษร
+func diffKeys(before, after map[string]bool) ([]string, []string) {
+	gained := []string{}
+	lost := []string{}
+	for key := range after {
+		if !before[key] {
+			gained = append(gained, key)
+		}
+	}
+	for key := range before {
+		if !after[key] {
+			lost = append(lost, key)
+		}
+	}
+	sort.Strings(gained)
+	sort.Strings(lost)
+	return gained, lost
+}
+
+func withoutID(ids []uint, id uint) []uint {
+	var kept []uint
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
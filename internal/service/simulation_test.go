@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAccessState_Apply(t *testing.T) {
+	// ผู้ใช้ 1 ถือ editor (2) โดยตรง ผู้ใช้ 2 ได้ editor ผ่านกลุ่มและถือ viewer (3) โดยตรง
+	before := &accessState{
+		direct: map[uint][]uint{1: {2}, 2: {3}},
+		group:  map[uint][]uint{1: nil, 2: {2}},
+		permissions: map[uint]map[string]bool{
+			2: {"users:read": true, "users:write": true},
+			3: {"users:read": true},
+		},
+	}
+
+	after := before.apply([]SimulatedChange{
+		{Type: SimulateRemovePermission, RoleID: 2, Permission: "users:write"},
+		{Type: SimulateAssignRole, RoleID: 3, UserID: 1},
+	})
+	gained, lost := diffKeys(before.effective(1), after.effective(1))
+	assert.Empty(t, gained)
+	assert.Equal(t, []string{"users:write"}, lost)
+
+	// สถานะเดิมต้องไม่ถูกแก้ไข
+	assert.True(t, before.permissions[2]["users:write"])
+	assert.Equal(t, []uint{2}, before.direct[1])
+
+	// ลบบทบาท viewer ผู้ใช้ 2 ยังได้ users:read จาก editor ผ่านกลุ่ม
+	after = before.apply([]SimulatedChange{{Type: SimulateDeleteRole, RoleID: 3}})
+	gained, lost = diffKeys(before.effective(2), after.effective(2))
+	assert.Empty(t, gained)
+	assert.Empty(t, lost)
+
+	// ถอนบทบาทโดยตรงไม่กระทบบทบาทเดียวกันที่ได้ผ่านกลุ่ม
+	after = before.apply([]SimulatedChange{
+		{Type: SimulateAssignRole, RoleID: 2, UserID: 2},
+		{Type: SimulateRevokeRole, RoleID: 2, UserID: 2},
+	})
+	gained, lost = diffKeys(before.effective(2), after.effective(2))
+	assert.Empty(t, gained)
+	assert.Empty(t, lost)
+}
+
+func TestValidateSimulation_ScopedToTenant(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+	tenantID := uint(3)
+
+	// บทบาทหาได้เฉพาะระดับ global หรือของ tenant ผู้ใช้หาได้เฉพาะใน tenant ผู้ใช้ของ tenant อื่นจึงเหมือนไม่มีอยู่
+	mock.ExpectQuery(`SELECT "id" FROM "roles" WHERE \(roles\.organization_id IS NULL OR roles\.organization_id = \$1\) AND "roles"\."id" = \$2`).
+		WithArgs(tenantID, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`SELECT "id" FROM "users" WHERE users\.organization_id = \$1 AND "users"\."id" = \$2`).
+		WithArgs(tenantID, 5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err = validateSimulation(db, []SimulatedChange{{Type: SimulateAssignRole, RoleID: 2, UserID: 5}}, &tenantID)
+	assert.ErrorIs(t, err, ErrInvalidSimulation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
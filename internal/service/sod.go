@@ -0,0 +1,323 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// SoDViolation รายละเอียดการละเมิดกฎแบ่งแยกหน้าที่หนึ่งรายการ
+type SoDViolation struct {
+	RuleID   uint   `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	Kind     string `json:"kind"`
+	// UserID และ RoleIDs ใช้กับกฎ mutually_exclusive: ผู้ใช้ที่ถือบทบาทในชุดเกินกำหนด
+	UserID  uint   `json:"user_id,omitempty"`
+	RoleIDs []uint `json:"role_ids,omitempty"`
+	// RoleID และ UserIDs ใช้กับกฎ max_cardinality: บทบาทที่มีผู้ถือเกินกำหนด
+	RoleID  uint   `json:"role_id,omitempty"`
+	UserIDs []uint `json:"user_ids,omitempty"`
+}
+
+// SoDViolationError error ที่คืนเมื่อการกำหนดบทบาทจะทำให้ละเมิดกฎแบ่งแยกหน้าที่
+type SoDViolationError struct {
+	Violation SoDViolation
+}
+
+func (e *SoDViolationError) Error() string {
+	v := e.Violation
+	if v.Kind == models.SoDMaxCardinality {
+		return fmt.Sprintf("separation of duties rule %q violated: role %d would have %d holders", v.RuleName, v.RoleID, len(v.UserIDs))
+	}
+	return fmt.Sprintf("separation of duties rule %q violated: user %d would hold conflicting roles %v", v.RuleName, v.UserID, v.RoleIDs)
+}
+
+// CheckSoD ตรวจสอบว่าการให้บทบาทตาม grants (userID → roleIDs ที่จะได้รับเพิ่ม) ไม่ละเมิดกฎแบ่งแยกหน้าที่
+// ควรเรียกใน transaction เดียวกับการกำหนดบทบาท
+func CheckSoD(db *gorm.DB, grants map[uint][]uint) error {
+	var addedRoleIDs []uint
+	for _, roleIDs := range grants {
+		addedRoleIDs = append(addedRoleIDs, roleIDs...)
+	}
+	if len(addedRoleIDs) == 0 {
+		return nil
+	}
+
+	var rules []models.SoDRule
+	ruleIDs := db.Table("sod_rule_roles").Select("rule_id").Where("role_id IN ?", addedRoleIDs)
+	if err := db.Preload("Roles").Where("id IN (?)", ruleIDs).Find(&rules).Error; err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		switch rule.Kind {
+		case models.SoDMutuallyExclusive:
+			for _, userID := range sortedGrantees(grants) {
+				held, err := effectiveRoleIDs(db, userID, now)
+				if err != nil {
+					return err
+				}
+				if violation, ok := checkMutualExclusion(rule, append(held, grants[userID]...), userID); ok {
+					return &SoDViolationError{Violation: violation}
+				}
+			}
+		case models.SoDMaxCardinality:
+			for _, roleID := range rule.RoleIDs() {
+				var newHolders []uint
+				for userID, roleIDs := range grants {
+					if containsID(roleIDs, roleID) {
+						newHolders = append(newHolders, userID)
+					}
+				}
+				if len(newHolders) == 0 {
+					continue
+				}
+
+				holders, err := roleHolderIDs(db, roleID, now)
+				if err != nil {
+					return err
+				}
+				if violation, ok := checkCardinality(rule, roleID, append(holders, newHolders...)); ok {
+					return &SoDViolationError{Violation: violation}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindSoDViolations หาผู้ใช้หรือบทบาทที่ละเมิดกฎอยู่แล้วในปัจจุบัน (ใช้ตรวจสอบเมื่อเพิ่มกฎใหม่)
+func FindSoDViolations(db *gorm.DB, rule models.SoDRule) ([]SoDViolation, error) {
+	now := time.Now()
+	violations := []SoDViolation{}
+
+	switch rule.Kind {
+	case models.SoDMutuallyExclusive:
+		// ตรวจเฉพาะผู้ใช้ที่ถือบทบาทในชุดอย่างน้อยหนึ่งบทบาท
+		candidates := make(map[uint]bool)
+		for _, roleID := range rule.RoleIDs() {
+			holders, err := roleHolderIDs(db, roleID, now)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range holders {
+				candidates[userID] = true
+			}
+		}
+
+		for _, userID := range sortedKeys(candidates) {
+			held, err := effectiveRoleIDs(db, userID, now)
+			if err != nil {
+				return nil, err
+			}
+			if violation, ok := checkMutualExclusion(rule, held, userID); ok {
+				violations = append(violations, violation)
+			}
+		}
+	case models.SoDMaxCardinality:
+		for _, roleID := range rule.RoleIDs() {
+			holders, err := roleHolderIDs(db, roleID, now)
+			if err != nil {
+				return nil, err
+			}
+			if violation, ok := checkCardinality(rule, roleID, holders); ok {
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// GroupRoleIDs คืน ID ของบทบาททั้งหมดที่สมาชิกของกลุ่มจะได้รับ (รวมบทบาทของกลุ่มแม่ทุกระดับ)
+func GroupRoleIDs(db *gorm.DB, groupID uint) ([]uint, error) {
+	groupIDs, err := ancestorGroups(db, []uint{groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	var roleIDs []uint
+	if err := db.Table("group_roles").Where("group_id IN ?", groupIDs).Distinct().Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+	return roleIDs, nil
+}
+
+// GroupMemberIDs คืน ID ของสมาชิกในกลุ่มและกลุ่มย่อยทุกระดับ (ผู้ที่ได้รับบทบาทของกลุ่มนี้)
+func GroupMemberIDs(db *gorm.DB, groupID uint) ([]uint, error) {
+	groupIDs, err := descendantGroups(db, []uint{groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []uint
+	if err := db.Table("group_members").Where("group_id IN ?", groupIDs).Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// GrantToAll สร้าง grants สำหรับ CheckSoD ที่ผู้ใช้ทุกคนได้รับบทบาทชุดเดียวกัน
+func GrantToAll(userIDs []uint, roleIDs []uint) map[uint][]uint {
+	grants := make(map[uint][]uint, len(userIDs))
+	for _, userID := range userIDs {
+		grants[userID] = roleIDs
+	}
+	return grants
+}
+
+// effectiveRoleIDs คืน ID ของบทบาทที่มีผลกับผู้ใช้ ณ เวลาที่ระบุ (บทบาทโดยตรงและจากกลุ่ม)
+func effectiveRoleIDs(db *gorm.DB, userID uint, now time.Time) ([]uint, error) {
+	var roleIDs []uint
+	if err := activeRoleIDs(db, userID, now).Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+
+	groupRoles, err := groupRoleIDs(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(roleIDs, groupRoles...), nil
+}
+
+// roleHolderIDs คืน ID ของผู้ใช้ที่ถือบทบาทนี้อยู่ ทั้งโดยตรงและผ่านกลุ่ม
+func roleHolderIDs(db *gorm.DB, roleID uint, now time.Time) ([]uint, error) {
+	var userIDs []uint
+	err := db.Model(&models.UserRole{}).
+		Where("role_id = ?", roleID).
+		Where("valid_from IS NULL OR valid_from <= ?", now).
+		Where("valid_until IS NULL OR valid_until > ?", now).
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var groupIDs []uint
+	if err := db.Table("group_roles").Where("role_id = ?", roleID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return userIDs, nil
+	}
+
+	groupIDs, err = descendantGroups(db, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberIDs []uint
+	if err := db.Table("group_members").Where("group_id IN ?", groupIDs).Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return append(userIDs, memberIDs...), nil
+}
+
+// descendantGroups เพิ่มกลุ่มย่อยทุกระดับของกลุ่มที่ระบุ (ป้องกันการวนซ้ำกรณีข้อมูลเป็นวงจร)
+func descendantGroups(db *gorm.DB, groupIDs []uint) ([]uint, error) {
+	visited := make(map[uint]bool)
+	var all []uint
+	frontier := groupIDs
+
+	for len(frontier) > 0 {
+		for _, id := range frontier {
+			if !visited[id] {
+				visited[id] = true
+				all = append(all, id)
+			}
+		}
+
+		var childIDs []uint
+		if err := db.Model(&models.Group{}).Where("parent_id IN ?", frontier).Pluck("id", &childIDs).Error; err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, id := range childIDs {
+			if !visited[id] {
+				frontier = append(frontier, id)
+			}
+		}
+	}
+
+	return all, nil
+}
+
+func checkMutualExclusion(rule models.SoDRule, heldRoleIDs []uint, userID uint) (SoDViolation, bool) {
+	held := make(map[uint]bool)
+	for _, roleID := range heldRoleIDs {
+		held[roleID] = true
+	}
+
+	var conflicting []uint
+	for _, roleID := range rule.RoleIDs() {
+		if held[roleID] {
+			conflicting = append(conflicting, roleID)
+		}
+	}
+	if len(conflicting) <= rule.MaxAllowed {
+		return SoDViolation{}, false
+	}
+
+	return SoDViolation{
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Kind:     rule.Kind,
+		UserID:   userID,
+		RoleIDs:  conflicting,
+	}, true
+}
+
+func checkCardinality(rule models.SoDRule, roleID uint, holderIDs []uint) (SoDViolation, bool) {
+	unique := make(map[uint]bool)
+	for _, userID := range holderIDs {
+		unique[userID] = true
+	}
+	if len(unique) <= rule.MaxAllowed {
+		return SoDViolation{}, false
+	}
+
+	return SoDViolation{
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Kind:     rule.Kind,
+		RoleID:   roleID,
+		UserIDs:  sortedKeys(unique),
+	}, true
+}
+
+// sortedGrantees คืน ID ผู้ใช้ใน grants เรียงจากน้อยไปมาก เพื่อให้ error ที่คืนมีลำดับคงที่
+func sortedGrantees(grants map[uint][]uint) []uint {
+	set := make(map[uint]bool, len(grants))
+	for userID := range grants {
+		set[userID] = true
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(set map[uint]bool) []uint {
+	keys := make([]uint, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+// PermissionsVersionSource คืนเวอร์ชันปัจจุบันของชุดสิทธิ์ผู้ใช้ เพื่อตรวจว่า token ที่แนบสิทธิ์มาล้าสมัยหรือไม่
+type PermissionsVersionSource interface {
+	PermissionsVersion(userID uint) (string, error)
+}
+
+// ตรวจสอบว่า AuthService เข้ากันได้กับ PermissionsVersionSource
+var _ PermissionsVersionSource = (*AuthService)(nil)
+
+// EmbedPermissions ให้ Login แนบบทบาทและสิทธิ์ที่มีผลของผู้ใช้ไปกับ token (stateless authorization)
+func (s *AuthService) EmbedPermissions(enabled bool) {
+	s.embedPermissions = enabled
+}
+
+// PermissionsVersion คืนเวอร์ชันของชุดสิทธิ์ที่มีผลของผู้ใช้ในปัจจุบัน คำนวณจากบทบาทที่มีผลทั้งหมดทุกครั้ง
+// จึงควรใช้คู่กับแคชสิทธิ์ (UseCache) ซึ่งทำให้ไม่เรียกฐานข้อมูลตราบที่แคชยังใช้ได้ และการ invalidate แคชทำให้เห็นการเปลี่ยนแปลงทันที
+// ถ้าปิดแคช การตรวจเวอร์ชันจะโหลดบทบาทและสิทธิ์จากฐานข้อมูลทุก request ซึ่งไม่ถูกกว่า RequirePermission
+func (s *AuthService) PermissionsVersion(userID uint) (string, error) {
+	roles, err := s.GetEffectiveRoles(userID)
+	if err != nil {
+		return "", err
+	}
+	_, _, version := tokenPermissions(roles)
+	return version, nil
+}
+
+// ErrInvalidScope เกิดเมื่อ scope ที่ขอมีรูปแบบไม่ถูกต้องหรือไม่มีสิทธิ์ใดที่ผู้ใช้ถืออยู่
+var ErrInvalidScope = errors.New("scope must list resource:action permissions held by the user")
+
+// grantScopes คืน scope ที่ขอ (คั่นด้วยช่องว่าง) เฉพาะที่ผู้ใช้มีสิทธิ์จริง เรียงและไม่ซ้ำ
+// scope ที่ผู้ใช้ไม่มีสิทธิ์จะถูกตัดออก แต่ถ้าไม่เหลือเลยถือว่าขอไม่ถูกต้อง
+func grantScopes(roles []models.Role, requested string) ([]string, error) {
+	_, held, _ := tokenPermissions(roles)
+	seen := make(map[string]bool)
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		if _, _, ok := models.ParsePermissionKey(scope); !ok {
+			return nil, ErrInvalidScope
+		}
+		if !seen[scope] && slices.Contains(held, scope) {
+			seen[scope] = true
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return nil, ErrInvalidScope
+	}
+	sort.Strings(granted)
+	return granted, nil
+}
+
+// embeddedPermissions สร้าง TokenOption ที่แนบบทบาทและสิทธิ์ที่มีผล ถ้าจำกัด scope จะแนบเฉพาะสิทธิ์ใน scope
+// เวอร์ชันคิดจากชุดสิทธิ์เต็มของผู้ใช้เสมอ เพื่อให้เทียบกับ PermissionsVersion ได้
+func embeddedPermissions(roles []models.Role, scopes []string) jwt.TokenOption {
+	roleNames, permissions, version := tokenPermissions(roles)
+	if len(scopes) > 0 {
+		permissions = scopes
+	}
+	return jwt.WithPermissions(roleNames, permissions, version)
+}
+
+// tokenPermissions คืนชื่อบทบาทและสิทธิ์ (resource:action) ที่เรียงและไม่ซ้ำ พร้อมเวอร์ชันของชุดสิทธิ์
+// เวอร์ชันคือ hash ของทั้งสองรายการ จึงเปลี่ยนเมื่อบทบาทหรือสิทธิ์ที่มีผลของผู้ใช้เปลี่ยนเท่านั้น
+func tokenPermissions(roles []models.Role) ([]string, []string, string) {
+	roleNames := make([]string, 0, len(roles))
+	seenRoles := make(map[string]bool)
+	seenPermissions := make(map[string]bool)
+	var permissions []string
+	for _, role := range roles {
+		// บทบาท global และบทบาทของ tenant อาจมีชื่อเดียวกัน
+		if !seenRoles[role.Name] {
+			seenRoles[role.Name] = true
+			roleNames = append(roleNames, role.Name)
+		}
+		for _, perm := range role.Permissions {
+			key := perm.Key()
+			if !seenPermissions[key] {
+				seenPermissions[key] = true
+				permissions = append(permissions, key)
+			}
+		}
+	}
+	sort.Strings(roleNames)
+	sort.Strings(permissions)
+
+	sum := sha256.Sum256([]byte(strings.Join(roleNames, ",") + "|" + strings.Join(permissions, ",")))
+	return roleNames, permissions, hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yourusername/auth-api/internal/models"
+)
+
+func TestTokenPermissions_VersionFollowsEffectivePermissions(t *testing.T) {
+	read := models.Permission{ID: 1, Resource: "users", Action: "read"}
+	write := models.Permission{ID: 2, Resource: "users", Action: "write"}
+	viewer := models.Role{ID: 1, Name: "viewer", Permissions: []models.Permission{read}}
+	editor := models.Role{ID: 2, Name: "editor", Permissions: []models.Permission{write, read}}
+
+	roles, permissions, version := tokenPermissions([]models.Role{viewer, editor})
+	assert.Equal(t, []string{"editor", "viewer"}, roles)
+	assert.Equal(t, []string{"users:read", "users:write"}, permissions)
+
+	// ลำดับของบทบาทไม่มีผลต่อเวอร์ชัน
+	_, _, reordered := tokenPermissions([]models.Role{editor, viewer})
+	assert.Equal(t, version, reordered)
+
+	// สิทธิ์ของบทบาทเปลี่ยน เวอร์ชันต้องเปลี่ยน
+	editor.Permissions = []models.Permission{read}
+	_, _, changed := tokenPermissions([]models.Role{viewer, editor})
+	assert.NotEqual(t, version, changed)
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/auth-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRecorder รับผลการตัดสินสิทธิ์ของ RequirePermission เพื่อสรุปการใช้งานสิทธิ์
+type UsageRecorder interface {
+	RecordUsage(userID uint, resource string, action string, allowed bool)
+}
+
+// usageFlushBatchSize จำนวนแถวต่อคำสั่ง INSERT ของ Flush แถวละ 7 พารามิเตอร์ ต้องไม่เกินขีดจำกัด 65535 ของ Postgres
+const usageFlushBatchSize = 1000
+
+// usageKey แถวสรุปหนึ่งแถว (ผู้ใช้ สิทธิ์ และวัน)
+type usageKey struct {
+	userID   uint
+	resource string
+	action   string
+	day      time.Time
+}
+
+// UsageService สะสมผลการตัดสินสิทธิ์ในหน่วยความจำแล้วบันทึกเป็นยอดรวมรายวันเป็นระยะ
+// เพื่อไม่ให้การตรวจสิทธิ์ทุกครั้งต้องเขียนฐานข้อมูล และออกรายงานสำหรับทบทวนสิทธิ์ที่เกินความจำเป็น
+type UsageService struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	pending map[usageKey]*models.PermissionUsage
+}
+
+func NewUsageService(db *gorm.DB) *UsageService {
+	return &UsageService{
+		db:      db,
+		pending: make(map[usageKey]*models.PermissionUsage),
+	}
+}
+
+// RecordUsage นับผลการตัดสินสิทธิ์หนึ่งครั้ง (ยังไม่บันทึกจนกว่าจะ Flush)
+func (s *UsageService) RecordUsage(userID uint, resource string, action string, allowed bool) {
+	now := time.Now().UTC()
+	key := usageKey{userID: userID, resource: resource, action: action, day: now.Truncate(24 * time.Hour)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, exists := s.pending[key]
+	if !exists {
+		usage = &models.PermissionUsage{UserID: userID, Resource: resource, Action: action, Day: key.day}
+		s.pending[key] = usage
+	}
+	if allowed {
+		usage.Allowed++
+	} else {
+		usage.Denied++
+	}
+	usage.LastSeenAt = now
+}
+
+// Flush บันทึกยอดที่สะสมไว้ลงฐานข้อมูลโดยบวกเพิ่มจากแถวของวันเดียวกัน ถ้าบันทึกไม่สำเร็จยอดจะถูกเก็บไว้ส่งครั้งถัดไป
+func (s *UsageService) Flush() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[usageKey]*models.PermissionUsage)
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usages := make([]models.PermissionUsage, 0, len(pending))
+	for _, usage := range pending {
+		usages = append(usages, *usage)
+	}
+
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "resource"}, {Name: "action"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"allowed":      gorm.Expr("permission_usages.allowed + excluded.allowed"),
+			"denied":       gorm.Expr("permission_usages.denied + excluded.denied"),
+			"last_seen_at": gorm.Expr("GREATEST(permission_usages.last_seen_at, excluded.last_seen_at)"),
+		}),
+	}).CreateInBatches(&usages, usageFlushBatchSize).Error
+	if err != nil {
+		s.restore(pending)
+		return err
+	}
+	return nil
+}
+
+// restore คืนยอดที่บันทึกไม่สำเร็จกลับเข้าไปรวมกับยอดที่สะสมระหว่างนั้น
+func (s *UsageService) restore(pending map[usageKey]*models.PermissionUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, usage := range pending {
+		current, exists := s.pending[key]
+		if !exists {
+			s.pending[key] = usage
+			continue
+		}
+		current.Allowed += usage.Allowed
+		current.Denied += usage.Denied
+		if usage.LastSeenAt.After(current.LastSeenAt) {
+			current.LastSeenAt = usage.LastSeenAt
+		}
+	}
+}
+
+// PurgeBefore ลบยอดรวมรายวันที่เก่ากว่าเวลาที่ระบุ
+func (s *UsageService) PurgeBefore(cutoff time.Time) (int64, error) {
+	result := s.db.Where("day < ?", cutoff.UTC().Truncate(24*time.Hour)).Delete(&models.PermissionUsage{})
+	return result.RowsAffected, result.Error
+}
+
+// StartFlusher รัน Flush เป็นระยะและลบข้อมูลที่เก่ากว่า retention จนกว่า context จะถูกยกเลิก (แล้ว Flush ครั้งสุดท้าย)
+func (s *UsageService) StartFlusher(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Printf("Failed to flush permission usage: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("Failed to flush permission usage: %v", err)
+				continue
+			}
+			if retention > 0 {
+				if _, err := s.PurgeBefore(time.Now().Add(-retention)); err != nil {
+					log.Printf("Failed to purge permission usage: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// RoleUsage สิทธิ์ของบทบาทที่ไม่มีผู้ถือบทบาทคนใดใช้เลยในช่วงเวลาที่รายงาน
+type RoleUsage struct {
+	RoleID         uint     `json:"role_id"`
+	RoleName       string   `json:"role_name"`
+	OrganizationID *uint    `json:"organization_id"`
+	Holders        int      `json:"holders"`
+	Unused         []string `json:"unused"`
+}
+
+// UserUsage สิทธิ์ที่มีผลของผู้ใช้เทียบกับสิทธิ์ที่ใช้จริงในช่วงเวลาที่รายงาน
+type UserUsage struct {
+	UserID   uint     `json:"user_id"`
+	Username string   `json:"username"`
+	Granted  int      `json:"granted"`
+	Used     int      `json:"used"`
+	Unused   []string `json:"unused"`
+}
+
+// DenialTrend จำนวนครั้งที่สิทธิ์หนึ่งถูกปฏิเสธ รวมและรายวัน
+type DenialTrend struct {
+	Resource string       `json:"resource"`
+	Action   string       `json:"action"`
+	Total    int64        `json:"total"`
+	Daily    []DailyCount `json:"daily"`
+}
+
+// DailyCount จำนวนของวันหนึ่ง (YYYY-MM-DD)
+type DailyCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// UnusedRolePermissions รายงานสิทธิ์ของแต่ละบทบาทที่ไม่มีผู้ถือบทบาทคนใดใช้ตั้งแต่ since
+// organizationID ไม่เป็น nil จะพิจารณาเฉพาะบทบาท global กับบทบาทของ tenant นั้น และนับเฉพาะผู้ใช้ใน tenant นั้น
+func (s *UsageService) UnusedRolePermissions(since time.Time, organizationID *uint) ([]RoleUsage, error) {
+	query := s.db.Preload("Permissions").Order("id")
+	if organizationID != nil {
+		query = query.Where("organization_id IS NULL OR organization_id = ?", *organizationID)
+	}
+	var roles []models.Role
+	if err := query.Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	report := []RoleUsage{}
+	now := time.Now()
+	for _, role := range roles {
+		if len(role.Permissions) == 0 {
+			continue
+		}
+
+		holderIDs, err := roleHolderIDs(s.db, role.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		holderIDs, err = s.usersIn(holderIDs, organizationID)
+		if err != nil {
+			return nil, err
+		}
+		used, err := s.usedKeys(holderIDs, since)
+		if err != nil {
+			return nil, err
+		}
+
+		var unused []string
+		for _, perm := range role.Permissions {
+			if !used[perm.Key()] {
+				unused = append(unused, perm.Key())
+			}
+		}
+		if len(unused) == 0 {
+			continue
+		}
+		sort.Strings(unused)
+		report = append(report, RoleUsage{
+			RoleID:         role.ID,
+			RoleName:       role.Name,
+			OrganizationID: role.OrganizationID,
+			Holders:        len(holderIDs),
+			Unused:         unused,
+		})
+	}
+	return report, nil
+}
+
+// overPrivilegedSQL คืนสิทธิ์ที่มีผลของผู้ใช้ทุกคน (บทบาทโดยตรงที่ยังมีผลและบทบาทผ่านกลุ่มรวมกลุ่มแม่)
+// พร้อมผลว่าใช้สิทธิ์นั้นตั้งแต่ since หรือไม่ ในคำสั่งเดียว %s คือเงื่อนไขของ tenant
+const overPrivilegedSQL = `WITH RECURSIVE member_groups(user_id, group_id) AS (
+	SELECT gm.user_id, gm.group_id FROM group_members gm
+	UNION
+	SELECT mg.user_id, g.parent_id FROM groups g JOIN member_groups mg ON g.id = mg.group_id WHERE g.parent_id IS NOT NULL
+),
+effective_roles(user_id, role_id) AS (
+	SELECT ur.user_id, ur.role_id FROM user_roles ur
+	WHERE (ur.valid_from IS NULL OR ur.valid_from <= @now)
+	AND (ur.valid_until IS NULL OR ur.valid_until > @now)
+	UNION
+	SELECT mg.user_id, gr.role_id FROM group_roles gr JOIN member_groups mg ON gr.group_id = mg.group_id
+),
+granted(user_id, resource, action) AS (
+	SELECT DISTINCT er.user_id, p.resource, p.action FROM effective_roles er
+	JOIN role_permissions rp ON rp.role_id = er.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+)
+SELECT u.id AS user_id, u.username, gp.resource, gp.action,
+EXISTS (
+	SELECT 1 FROM permission_usages pu
+	WHERE pu.user_id = gp.user_id AND pu.resource = gp.resource AND pu.action = gp.action
+	AND pu.day >= @since AND pu.allowed > 0
+) AS used
+FROM granted gp JOIN users u ON u.id = gp.user_id
+%s
+ORDER BY u.id, gp.resource, gp.action`
+
+// OverPrivilegedUsers รายงานผู้ใช้ที่ไม่ได้ใช้สิทธิ์ที่มีผลอยู่ตั้งแต่ since เป็นสัดส่วนอย่างน้อย minUnusedRatio (0-1)
+// เรียงจากผู้ที่มีสิทธิ์ไม่ได้ใช้มากที่สุด
+func (s *UsageService) OverPrivilegedUsers(since time.Time, minUnusedRatio float64, organizationID *uint) ([]UserUsage, error) {
+	args := map[string]interface{}{
+		"now":   time.Now(),
+		"since": since.UTC().Truncate(24 * time.Hour),
+	}
+	tenantFilter := ""
+	if organizationID != nil {
+		tenantFilter = "WHERE u.organization_id = @organization"
+		args["organization"] = *organizationID
+	}
+
+	var rows []struct {
+		UserID   uint
+		Username string
+		Resource string
+		Action   string
+		Used     bool
+	}
+	if err := s.db.Raw(fmt.Sprintf(overPrivilegedSQL, tenantFilter), args).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// แถวเรียงตามผู้ใช้ จึงรวมเป็นรายงานของแต่ละคนได้ในรอบเดียว
+	report := []UserUsage{}
+	var current *UserUsage
+	flush := func() {
+		if current == nil || len(current.Unused) == 0 {
+			return
+		}
+		if float64(len(current.Unused))/float64(current.Granted) >= minUnusedRatio {
+			sort.Strings(current.Unused)
+			report = append(report, *current)
+		}
+	}
+	for _, row := range rows {
+		if current == nil || current.UserID != row.UserID {
+			flush()
+			current = &UserUsage{UserID: row.UserID, Username: row.Username}
+		}
+		current.Granted++
+		if row.Used {
+			current.Used++
+		} else {
+			current.Unused = append(current.Unused, row.Resource+":"+row.Action)
+		}
+	}
+	flush()
+
+	sort.SliceStable(report, func(i, j int) bool { return len(report[i].Unused) > len(report[j].Unused) })
+	return report, nil
+}
+
+// DenialTrends รายงานจำนวนการปฏิเสธของแต่ละสิทธิ์รายวันตั้งแต่ since เรียงจากสิทธิ์ที่ถูกปฏิเสธมากที่สุด
+func (s *UsageService) DenialTrends(since time.Time, organizationID *uint) ([]DenialTrend, error) {
+	var rows []struct {
+		Resource string
+		Action   string
+		Day      time.Time
+		Denied   int64
+	}
+	query := s.db.Model(&models.PermissionUsage{}).
+		Select("permission_usages.resource, permission_usages.action, permission_usages.day, SUM(permission_usages.denied) AS denied").
+		Where("permission_usages.day >= ? AND permission_usages.denied > 0", since.UTC().Truncate(24*time.Hour))
+	if organizationID != nil {
+		query = query.Joins("JOIN users ON users.id = permission_usages.user_id").
+			Where("users.organization_id = ?", *organizationID)
+	}
+	err := query.Group("permission_usages.resource, permission_usages.action, permission_usages.day").
+		Order("permission_usages.resource, permission_usages.action, permission_usages.day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := []DenialTrend{}
+	for _, row := range rows {
+		last := len(report) - 1
+		if last < 0 || report[last].Resource != row.Resource || report[last].Action != row.Action {
+			report = append(report, DenialTrend{Resource: row.Resource, Action: row.Action})
+			last++
+		}
+		report[last].Total += row.Denied
+		report[last].Daily = append(report[last].Daily, DailyCount{Day: row.Day.Format("2006-01-02"), Count: row.Denied})
+	}
+
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Total > report[j].Total })
+	return report, nil
+}
+
+// usersIn คัดเฉพาะผู้ใช้ใน tenant ที่ระบุ (nil = ไม่จำกัด) และตัดรายการซ้ำ
+func (s *UsageService) usersIn(userIDs []uint, organizationID *uint) ([]uint, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	query := s.db.Model(&models.User{}).Where("id IN ?", userIDs)
+	if organizationID != nil {
+		query = query.Where("organization_id = ?", *organizationID)
+	}
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// usedKeys คืนสิทธิ์ (resource:action) ที่ผู้ใช้กลุ่มนี้ได้รับอนุญาตให้ใช้อย่างน้อยหนึ่งครั้งตั้งแต่ since
+func (s *UsageService) usedKeys(userIDs []uint, since time.Time) (map[string]bool, error) {
+	used := make(map[string]bool)
+	if len(userIDs) == 0 {
+		return used, nil
+	}
+
+	var usages []models.PermissionUsage
+	err := s.db.Distinct("resource", "action").
+		Where("user_id IN ? AND day >= ? AND allowed > 0", userIDs, since.UTC().Truncate(24*time.Hour)).
+		Find(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, usage := range usages {
+		used[usage.Resource+":"+usage.Action] = true
+	}
+	return used, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestUsageService_FlushRollsUpDecisions(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	usage := NewUsageService(db)
+	usage.RecordUsage(1, "users", "read", true)
+	usage.RecordUsage(1, "users", "read", true)
+	usage.RecordUsage(1, "users", "read", false)
+
+	// ผลการตัดสินของผู้ใช้ สิทธิ์ และวันเดียวกันรวมเป็นแถวเดียว
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "permission_usages" .* ON CONFLICT \("user_id","resource","action","day"\) DO UPDATE SET .*permission_usages.allowed \+ excluded.allowed`).
+		WithArgs(1, "users", "read", sqlmock.AnyArg(), 2, 1, sqlmock.AnyArg()).
+		WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	assert.Error(t, usage.Flush())
+
+	// บันทึกไม่สำเร็จ ยอดเดิมถูกรวมกับยอดใหม่และส่งอีกครั้ง
+	usage.RecordUsage(1, "users", "read", true)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "permission_usages"`).
+		WithArgs(1, "users", "read", sqlmock.AnyArg(), 3, 1, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, usage.Flush())
+	assert.NoError(t, usage.Flush()) // ไม่มียอดค้าง ไม่เรียกฐานข้อมูล
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsageService_FlushInBatches(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	// ยอดค้างมากกว่าหนึ่ง batch ต้องแบ่งเป็นหลายคำสั่ง ไม่เช่นนั้นจะเกินขีดจำกัดพารามิเตอร์ของ Postgres
+	usage := NewUsageService(db)
+	for userID := uint(1); userID <= usageFlushBatchSize+1; userID++ {
+		usage.RecordUsage(userID, "users", "read", true)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "permission_usages"`).WillReturnResult(sqlmock.NewResult(0, usageFlushBatchSize))
+	mock.ExpectExec(`INSERT INTO "permission_usages"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, usage.Flush())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsageService_OverPrivilegedUsers(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, PreferSimpleProtocol: true}), &gorm.Config{})
+	require.NoError(t, err)
+
+	// สิทธิ์ที่มีผลและผลการใช้ของผู้ใช้ทุกคนมาจากคำสั่งเดียว ไม่ใช่คำสั่งต่อผู้ใช้
+	mock.ExpectQuery(`WITH RECURSIVE member_groups.*WHERE u.organization_id = \$4 ORDER BY u.id`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 4).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "resource", "action", "used"}).
+			AddRow(1, "alice", "reports", "read", true).
+			AddRow(1, "alice", "reports", "write", false).
+			AddRow(2, "bob", "users", "delete", false).
+			AddRow(2, "bob", "users", "read", false).
+			AddRow(3, "carol", "users", "read", true))
+
+	organizationID := uint(4)
+	report, err := NewUsageService(db).OverPrivilegedUsers(time.Now().AddDate(0, 0, -30), 0.5, &organizationID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []UserUsage{
+		{UserID: 2, Username: "bob", Granted: 2, Used: 0, Unused: []string{"users:delete", "users:read"}},
+		{UserID: 1, Username: "alice", Granted: 2, Used: 1, Unused: []string{"reports:write"}},
+	}, report)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
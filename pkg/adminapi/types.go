@@ -0,0 +1,151 @@
+// Package adminapi รูปแบบ request และ response ของ API จัดการผู้ใช้ บทบาท และสิทธิ์ (/api/users, /api/roles, /api/permissions)
+// handlers ใช้ request เหล่านี้รับข้อมูลโดยตรง และ response ตรงกับ JSON ที่ handlers ส่งออก จึงใช้ร่วมกับ pkg/adminclient ได้
+package adminapi
+
+import "time"
+
+// การแบ่งหน้าของ endpoint ที่คืนรายการ: ส่ง page หรือ per_page เพื่อแบ่งหน้า (ไม่ส่ง = คืนทั้งหมด)
+// เมื่อแบ่งหน้า จำนวนรายการทั้งหมดอยู่ใน header X-Total-Count
+const (
+	PageParam        = "page"
+	PerPageParam     = "per_page"
+	TotalCountHeader = "X-Total-Count"
+	DefaultPerPage   = 50
+	MaxPerPage       = 500
+)
+
+// ErrorCodeAlreadyExists ค่า field code ของ response ที่ผิดพลาดเพราะข้อมูลซ้ำกับที่มีอยู่ เช่น username, email, ชื่อบทบาท หรือสิทธิ์
+// ตรวจด้วย code แทนข้อความใน field error ซึ่งอาจเปลี่ยนได้
+const ErrorCodeAlreadyExists = "already_exists"
+
+// LoginRequest ข้อมูลสำหรับ POST /api/login
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Scope    string `json:"scope"`     // สิทธิ์ resource:action ที่ต้องการให้ token ใช้ได้ คั่นด้วยช่องว่าง (ว่าง = ทุกสิทธิ์ที่มี)
+	ClientID string `json:"client_id"` // แอปที่จะใช้ token กำหนด aud ของ token (ว่าง = audience เริ่มต้น)
+}
+
+// LoginResponse ผลลัพธ์ของ POST /api/login
+type LoginResponse struct {
+	AccessToken string `json:"access_token"`
+	Scope       string `json:"scope,omitempty"` // scope ที่ได้รับจริง (เฉพาะเมื่อขอ scope)
+	User        User   `json:"user"`
+}
+
+// User ข้อมูลผู้ใช้ที่ไม่มีข้อมูล sensitive
+type User struct {
+	ID             uint   `json:"id"`
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	FullName       string `json:"full_name"`
+	OrganizationID *uint  `json:"organization_id"` // nil = ผู้ใช้ระดับ global
+	System         bool   `json:"system"`
+	Roles          []Role `json:"roles"`
+}
+
+// CreateUserRequest ข้อมูลสำหรับ POST /api/users (ผู้ดูแล tenant สร้างได้เฉพาะใน tenant ของตนเอง)
+type CreateUserRequest struct {
+	Username       string `json:"username" binding:"required"`
+	Email          string `json:"email" binding:"required"`
+	Password       string `json:"password" binding:"required"`
+	FullName       string `json:"full_name"`
+	OrganizationID *uint  `json:"organization_id"`
+}
+
+// UpdateUserRequest ข้อมูลสำหรับ PUT /api/users/:id ค่าว่างหมายถึงไม่เปลี่ยน
+type UpdateUserRequest struct {
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	FullName       string `json:"full_name"`
+	OrganizationID *uint  `json:"organization_id"`
+}
+
+// AssignRoleRequest ข้อมูลสำหรับ POST /api/users/:id/roles
+type AssignRoleRequest struct {
+	RoleID     uint       `json:"role_id" binding:"required"`
+	ValidFrom  *time.Time `json:"valid_from"`  // nil = มีผลทันที
+	ValidUntil *time.Time `json:"valid_until"` // nil = ไม่มีวันหมดอายุ
+	Reason     string     `json:"reason"`
+}
+
+// RoleAssignment การกำหนดบทบาทให้ผู้ใช้ พร้อมช่วงเวลาที่มีผล ผู้ให้สิทธิ์ และเหตุผล
+type RoleAssignment struct {
+	UserID     uint       `json:"user_id"`
+	RoleID     uint       `json:"role_id"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	GrantedBy  *uint      `json:"granted_by"`
+	Reason     string     `json:"reason"`
+	Role       *Role      `json:"role,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AssignRoleResponse ผลลัพธ์ของ POST /api/users/:id/roles
+type AssignRoleResponse struct {
+	Message    string         `json:"message"`
+	Assignment RoleAssignment `json:"assignment"`
+}
+
+// Role ข้อมูลบทบาท
+type Role struct {
+	ID             uint         `json:"id"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	OrganizationID *uint        `json:"organization_id"` // nil = บทบาทระดับ global
+	System         bool         `json:"system"`
+	Permissions    []Permission `json:"permissions,omitempty"`
+	DelegableBy    []Role       `json:"delegable_by,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// PermissionRef อ้างถึงสิทธิ์ที่มีอยู่แล้วด้วย ID
+type PermissionRef struct {
+	ID uint `json:"id"`
+}
+
+// CreateRoleRequest ข้อมูลสำหรับ POST /api/roles สิทธิ์ที่แนบต้องเป็นสิทธิ์ที่ผู้สร้างมีอยู่แล้ว
+type CreateRoleRequest struct {
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	OrganizationID *uint           `json:"organization_id"`
+	Permissions    []PermissionRef `json:"permissions"`
+}
+
+// UpdateRoleRequest ข้อมูลสำหรับ PUT /api/roles/:id ค่าว่างหมายถึงไม่เปลี่ยน
+type UpdateRoleRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AddRolePermissionRequest ข้อมูลสำหรับ POST /api/roles/:id/permissions
+type AddRolePermissionRequest struct {
+	PermissionID uint `json:"permission_id" binding:"required"`
+}
+
+// Permission ข้อมูลสิทธิ์
+type Permission struct {
+	ID          uint      `json:"id"`
+	Resource    string    `json:"resource"`
+	Action      string    `json:"action"`
+	Description string    `json:"description"`
+	System      bool      `json:"system"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreatePermissionRequest ข้อมูลสำหรับ POST /api/permissions
+type CreatePermissionRequest struct {
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+// UpdatePermissionRequest ข้อมูลสำหรับ PUT /api/permissions/:id ค่าว่างหมายถึงไม่เปลี่ยน
+type UpdatePermissionRequest struct {
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
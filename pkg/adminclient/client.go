@@ -0,0 +1,195 @@
+// Package adminclient client สำหรับ API จัดการผู้ใช้ บทบาท และสิทธิ์ของ auth-api (/api/users, /api/roles, /api/permissions)
+// ใช้ request และ response จาก pkg/adminapi ชุดเดียวกับ handlers ของ auth-api
+// client เข้าสู่ระบบเองเมื่อยังไม่มี token หรือ token ใกล้หมดอายุ และเข้าสู่ระบบใหม่หนึ่งครั้งเมื่อ token ถูกปฏิเสธ
+// (เช่น สิทธิ์ที่แนบมากับ token ล้าสมัย) ใช้พร้อมกันจากหลาย goroutine ได้
+package adminclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v4"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+// DefaultRefreshBefore ระยะเวลาก่อน token หมดอายุที่ client เข้าสู่ระบบใหม่
+const DefaultRefreshBefore = time.Minute
+
+// Config การตั้งค่า Client ต้องระบุ Username และ Password หรือ Token อย่างใดอย่างหนึ่ง
+type Config struct {
+	BaseURL string // เช่น https://auth.example.com
+
+	// Username และ Password ใช้เข้าสู่ระบบ (POST /api/login) ส่วน Scope และ ClientID ส่งไปพร้อมกันถ้าระบุ
+	Username string
+	Password string
+	Scope    string
+	ClientID string
+
+	// Token ใช้ token ที่มีอยู่แล้ว ถ้าไม่ได้ระบุ Username จะเข้าสู่ระบบใหม่ไม่ได้เมื่อ token หมดอายุ
+	Token string
+
+	RefreshBefore time.Duration // 0 = DefaultRefreshBefore
+	HTTPClient    *http.Client  // nil = client ที่มี timeout 10 วินาที
+}
+
+// Client เรียก API จัดการผู้ใช้ บทบาท และสิทธิ์
+type Client struct {
+	config  Config
+	baseURL string
+	client  *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time // ศูนย์ = ไม่ทราบเวลาหมดอายุ
+}
+
+func NewClient(config Config) (*Client, error) {
+	if config.BaseURL == "" {
+		return nil, errors.New("BaseURL is required")
+	}
+	if config.Token == "" && (config.Username == "" || config.Password == "") {
+		return nil, errors.New("Username and Password or Token is required")
+	}
+	if config.RefreshBefore <= 0 {
+		config.RefreshBefore = DefaultRefreshBefore
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	c := &Client{
+		config:  config,
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		client:  client,
+	}
+	if config.Token != "" {
+		c.setToken(config.Token)
+	}
+	return c, nil
+}
+
+// Login เข้าสู่ระบบด้วย Username และ Password แล้วใช้ token ที่ได้กับคำขอถัดไป
+// ปกติไม่ต้องเรียกเอง เพราะ client เข้าสู่ระบบเมื่อจำเป็น
+func (c *Client) Login(ctx context.Context) (*adminapi.LoginResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.login(ctx)
+}
+
+func (c *Client) login(ctx context.Context) (*adminapi.LoginResponse, error) {
+	if c.config.Username == "" {
+		return nil, ErrNoCredentials
+	}
+
+	var resp adminapi.LoginResponse
+	_, err := c.send(ctx, http.MethodPost, "/api/login", nil, adminapi.LoginRequest{
+		Username: c.config.Username,
+		Password: c.config.Password,
+		Scope:    c.config.Scope,
+		ClientID: c.config.ClientID,
+	}, "", &resp)
+	if err != nil {
+		return nil, err
+	}
+	c.setToken(resp.AccessToken)
+	return &resp, nil
+}
+
+// setToken เก็บ token และเวลาหมดอายุ อ่าน exp โดยไม่ตรวจลายเซ็น เพราะใช้เพียงกำหนดเวลาเข้าสู่ระบบใหม่
+// ส่วนการตรวจ token เป็นหน้าที่ของ auth-api
+func (c *Client) setToken(token string) {
+	c.token = token
+	c.expiresAt = time.Time{}
+
+	claims := &jwt.Claims{}
+	if _, _, err := jwtlib.NewParser().ParseUnverified(token, claims); err == nil && claims.ExpiresAt != nil {
+		c.expiresAt = claims.ExpiresAt.Time
+	}
+}
+
+// accessToken คืน token ที่ใช้ได้ เข้าสู่ระบบใหม่เมื่อยังไม่มี token, token ใกล้หมดอายุ หรือ token คือ rejected ที่ถูกปฏิเสธ
+// ถ้า goroutine อื่นเข้าสู่ระบบใหม่ไปแล้ว token จะไม่ใช่ rejected จึงไม่เข้าสู่ระบบซ้ำ
+func (c *Client) accessToken(ctx context.Context, rejected string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiring := !c.expiresAt.IsZero() && time.Until(c.expiresAt) < c.config.RefreshBefore
+	if c.token != "" && c.token != rejected && (!expiring || c.config.Username == "") {
+		return c.token, nil
+	}
+	if _, err := c.login(ctx); err != nil {
+		return "", err
+	}
+	return c.token, nil
+}
+
+// do ส่งคำขอพร้อม token ถ้า token ถูกปฏิเสธ (หมดอายุก่อนกำหนด หรือสิทธิ์ที่แนบมาล้าสมัย) จะเข้าสู่ระบบใหม่แล้วส่งอีกครั้ง
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any) (http.Header, error) {
+	token, err := c.accessToken(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := c.send(ctx, method, path, query, body, token, out)
+	if errors.Is(err, ErrUnauthorized) && c.config.Username != "" {
+		if token, err = c.accessToken(ctx, token); err != nil {
+			return nil, err
+		}
+		return c.send(ctx, method, path, query, body, token, out)
+	}
+	return header, err
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body any, token string, out any) (http.Header, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, decodeError(resp)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("decoding %s %s: %w", method, path, err)
+		}
+	}
+	return resp.Header, nil
+}
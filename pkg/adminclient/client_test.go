@@ -0,0 +1,189 @@
+package adminclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/pkg/adminapi"
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+// stubServer จำลอง API ของ auth-api เฉพาะส่วนที่ใช้ทดสอบการเข้าสู่ระบบ การแบ่งหน้า และข้อผิดพลาด
+type stubServer struct {
+	*httptest.Server
+	jwtService *jwt.JWTService
+	users      []adminapi.User
+	logins     atomic.Int32
+	rejectNext atomic.Bool // ปฏิเสธ token ของคำขอถัดไปหนึ่งครั้ง เหมือน token ที่สิทธิ์ล้าสมัย
+}
+
+func newStubServer(t *testing.T, tokenDuration time.Duration) *stubServer {
+	t.Helper()
+	server := &stubServer{jwtService: jwt.NewJWTService("test-secret", "auth-api", tokenDuration)}
+	for i := 1; i <= 5; i++ {
+		server.users = append(server.users, adminapi.User{ID: uint(i), Username: fmt.Sprintf("user%d", i)})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		var request adminapi.LoginRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Username != "admin" || request.Password != "secret" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid username or password"})
+			return
+		}
+		server.logins.Add(1)
+		token, _ := server.jwtService.GenerateToken(1, "admin@example.com")
+		writeJSON(w, http.StatusOK, adminapi.LoginResponse{AccessToken: token, User: server.users[0]})
+	})
+	mux.HandleFunc("GET /api/users", server.authorized(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get(adminapi.PageParam))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get(adminapi.PerPageParam))
+		start := min((page-1)*perPage, len(server.users))
+		end := min(start+perPage, len(server.users))
+		w.Header().Set(adminapi.TotalCountHeader, strconv.Itoa(len(server.users)))
+		writeJSON(w, http.StatusOK, server.users[start:end])
+	}))
+	mux.HandleFunc("GET /api/users/{id}", server.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]any{"error": "User not found"})
+	}))
+	mux.HandleFunc("POST /api/permissions", server.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Permission already exists", "code": adminapi.ErrorCodeAlreadyExists})
+	}))
+	mux.HandleFunc("POST /api/roles", server.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusForbidden, map[string]any{
+			"error":               "cannot grant permissions the caller does not hold",
+			"missing_permissions": []string{"billing:write"},
+		})
+	}))
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *stubServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, err := s.jwtService.ValidateToken(token); err != nil || s.rejectNext.Swap(false) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "Invalid or expired token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestClient(t *testing.T, server *stubServer) *Client {
+	t.Helper()
+	client, err := NewClient(Config{BaseURL: server.URL, Username: "admin", Password: "secret"})
+	require.NoError(t, err)
+	return client
+}
+
+func TestClient_Pagination(t *testing.T) {
+	server := newStubServer(t, time.Hour)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	page, err := client.ListUsers(ctx, ListOptions{Page: 2, PerPage: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user3", "user4"}, []string{page.Items[0].Username, page.Items[1].Username})
+	assert.Equal(t, 5, page.Total)
+	assert.True(t, page.HasNext())
+	assert.Equal(t, ListOptions{Page: 3, PerPage: 2}, page.Next())
+
+	users, err := All(ctx, client.ListUsers, ListOptions{PerPage: 2})
+	require.NoError(t, err)
+	assert.Len(t, users, 5)
+
+	// เข้าสู่ระบบครั้งเดียวแล้วใช้ token เดิมต่อ
+	assert.Equal(t, int32(1), server.logins.Load())
+}
+
+func TestClient_RefreshesToken(t *testing.T) {
+	ctx := context.Background()
+
+	// token ที่ถูกปฏิเสธทำให้เข้าสู่ระบบใหม่แล้วส่งคำขอเดิมอีกครั้ง
+	server := newStubServer(t, time.Hour)
+	client := newTestClient(t, server)
+	_, err := client.ListUsers(ctx, ListOptions{})
+	require.NoError(t, err)
+	server.rejectNext.Store(true)
+	_, err = client.ListUsers(ctx, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), server.logins.Load())
+
+	// token ที่ใกล้หมดอายุ (น้อยกว่า RefreshBefore) ถูกเปลี่ยนก่อนส่งคำขอ
+	shortLived := newStubServer(t, 30*time.Second)
+	client = newTestClient(t, shortLived)
+	for i := 0; i < 2; i++ {
+		_, err = client.ListUsers(ctx, ListOptions{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), shortLived.logins.Load())
+
+	// token ที่ระบุเองโดยไม่มีบัญชีผู้ใช้เข้าสู่ระบบใหม่ไม่ได้
+	token, err := server.jwtService.GenerateToken(1, "admin@example.com")
+	require.NoError(t, err)
+	client, err = NewClient(Config{BaseURL: server.URL, Token: token})
+	require.NoError(t, err)
+	server.rejectNext.Store(true)
+	_, err = client.ListUsers(ctx, ListOptions{})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.Login(ctx)
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := newStubServer(t, time.Hour)
+	client := newTestClient(t, server)
+	ctx := context.Background()
+
+	_, err := client.GetUser(ctx, 99)
+	assert.ErrorIs(t, err, ErrNotFound)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "User not found", apiErr.Message)
+
+	_, err = client.CreatePermission(ctx, adminapi.CreatePermissionRequest{Resource: "users", Action: "read"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	_, err = client.CreateRole(ctx, adminapi.CreateRoleRequest{Name: "billing"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.NotErrorIs(t, err, ErrAlreadyExists)
+	require.True(t, errors.As(err, &apiErr))
+	assert.JSONEq(t, `["billing:write"]`, string(apiErr.Details["missing_permissions"]))
+
+	wrongPassword, err := NewClient(Config{BaseURL: server.URL, Username: "admin", Password: "wrong"})
+	require.NoError(t, err)
+	_, err = wrongPassword.ListUsers(ctx, ListOptions{})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestError_AlreadyExistsFollowsCode(t *testing.T) {
+	// ใช้ code ไม่ใช่ข้อความ ข้อความที่ลงท้ายด้วย already exists แต่ไม่มี code ไม่ถือเป็นข้อมูลซ้ำ
+	withoutCode := &Error{StatusCode: http.StatusBadRequest, Message: "Role name already exists"}
+	assert.ErrorIs(t, withoutCode, ErrBadRequest)
+	assert.NotErrorIs(t, withoutCode, ErrAlreadyExists)
+
+	withCode := &Error{StatusCode: http.StatusBadRequest, Message: "Duplicate", Code: adminapi.ErrorCodeAlreadyExists}
+	assert.ErrorIs(t, withCode, ErrBadRequest)
+	assert.ErrorIs(t, withCode, ErrAlreadyExists)
+}
@@ -0,0 +1,84 @@
+package adminclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// ประเภทของข้อผิดพลาดตาม status code ที่ auth-api ตอบ ใช้ตรวจด้วย errors.Is เช่น errors.Is(err, adminclient.ErrNotFound)
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	// ErrAlreadyExists ข้อมูลซ้ำกับที่มีอยู่ เช่น username, email, ชื่อบทบาท หรือสิทธิ์ (ตอบเป็น 400 พร้อม code already_exists)
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrNoCredentials เกิดเมื่อต้องเข้าสู่ระบบแต่ไม่ได้ตั้งค่า Username และ Password
+	ErrNoCredentials = errors.New("username and password are not configured")
+)
+
+// Error ข้อผิดพลาดที่ auth-api ตอบกลับ
+type Error struct {
+	StatusCode int
+	Message    string                     // ข้อความใน field error ของ response
+	Code       string                     // field code ของ response เช่น adminapi.ErrorCodeAlreadyExists (ว่างถ้าไม่มี)
+	Details    map[string]json.RawMessage // field อื่นใน response เช่น missing_permissions หรือ violation
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("auth-api: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Unwrap คืนประเภทของข้อผิดพลาดเพื่อให้ errors.Is ใช้ได้
+func (e *Error) Unwrap() []error {
+	var kinds []error
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		kinds = append(kinds, ErrBadRequest)
+	case http.StatusUnauthorized:
+		kinds = append(kinds, ErrUnauthorized)
+	case http.StatusForbidden:
+		kinds = append(kinds, ErrForbidden)
+	case http.StatusNotFound:
+		kinds = append(kinds, ErrNotFound)
+	case http.StatusConflict:
+		kinds = append(kinds, ErrConflict)
+	}
+	if e.Code == adminapi.ErrorCodeAlreadyExists {
+		kinds = append(kinds, ErrAlreadyExists)
+	}
+	return kinds
+}
+
+// decodeError อ่าน response ที่ผิดพลาดในรูปแบบ {"error": "...", "code": "...", ...} ถ้าอ่านไม่ได้ใช้ข้อความตาม status code
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return apiErr
+	}
+	if raw, ok := body["error"]; ok {
+		var message string
+		if json.Unmarshal(raw, &message) == nil {
+			apiErr.Message = message
+		}
+		delete(body, "error")
+	}
+	if raw, ok := body["code"]; ok {
+		var code string
+		if json.Unmarshal(raw, &code) == nil {
+			apiErr.Code = code
+		}
+		delete(body, "code")
+	}
+	if len(body) > 0 {
+		apiErr.Details = body
+	}
+	return apiErr
+}
@@ -0,0 +1,76 @@
+package adminclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// ListOptions หน้าที่ต้องการ ค่าศูนย์หมายถึงหน้าแรกและ adminapi.DefaultPerPage รายการต่อหน้า
+type ListOptions struct {
+	Page    int
+	PerPage int
+}
+
+// Page รายการหนึ่งหน้า พร้อมจำนวนรายการทั้งหมดจาก header X-Total-Count
+type Page[T any] struct {
+	Items   []T
+	Page    int
+	PerPage int
+	Total   int
+}
+
+// HasNext ตรวจว่ายังมีหน้าถัดไปหรือไม่
+func (p *Page[T]) HasNext() bool {
+	return p.Page*p.PerPage < p.Total
+}
+
+// Next คืน ListOptions ของหน้าถัดไป
+func (p *Page[T]) Next() ListOptions {
+	return ListOptions{Page: p.Page + 1, PerPage: p.PerPage}
+}
+
+// All ดึงทุกหน้าตั้งแต่หน้าใน opts แล้วรวมเป็นรายการเดียว เช่น adminclient.All(ctx, client.ListUsers, adminclient.ListOptions{})
+func All[T any](ctx context.Context, list func(context.Context, ListOptions) (*Page[T], error), opts ListOptions) ([]T, error) {
+	var items []T
+	for {
+		page, err := list(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		// หยุดเมื่อหน้าว่างด้วย เผื่อรายการถูกลบระหว่างดึง
+		if !page.HasNext() || len(page.Items) == 0 {
+			return items, nil
+		}
+		opts = page.Next()
+	}
+}
+
+func list[T any](ctx context.Context, c *Client, path string, opts ListOptions) (*Page[T], error) {
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+	if opts.PerPage <= 0 {
+		opts.PerPage = adminapi.DefaultPerPage
+	}
+	query := url.Values{
+		adminapi.PageParam:    {strconv.Itoa(opts.Page)},
+		adminapi.PerPageParam: {strconv.Itoa(opts.PerPage)},
+	}
+
+	var items []T
+	header, err := c.do(ctx, http.MethodGet, path, query, nil, &items)
+	if err != nil {
+		return nil, err
+	}
+	total, err := strconv.Atoi(header.Get(adminapi.TotalCountHeader))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: invalid %s header", path, adminapi.TotalCountHeader)
+	}
+	return &Page[T]{Items: items, Page: opts.Page, PerPage: opts.PerPage, Total: total}, nil
+}
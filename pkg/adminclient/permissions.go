@@ -0,0 +1,44 @@
+package adminclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// ListPermissions รับรายการสิทธิ์หนึ่งหน้า
+func (c *Client) ListPermissions(ctx context.Context, opts ListOptions) (*Page[adminapi.Permission], error) {
+	return list[adminapi.Permission](ctx, c, "/api/permissions", opts)
+}
+
+func (c *Client) GetPermission(ctx context.Context, id uint) (*adminapi.Permission, error) {
+	var permission adminapi.Permission
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/permissions/%d", id), nil, nil, &permission); err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// CreatePermission สร้างสิทธิ์ใหม่ สิทธิ์ที่ซ้ำกับที่มีอยู่คืน ErrAlreadyExists
+func (c *Client) CreatePermission(ctx context.Context, request adminapi.CreatePermissionRequest) (*adminapi.Permission, error) {
+	var permission adminapi.Permission
+	if _, err := c.do(ctx, http.MethodPost, "/api/permissions", nil, request, &permission); err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (c *Client) UpdatePermission(ctx context.Context, id uint, request adminapi.UpdatePermissionRequest) (*adminapi.Permission, error) {
+	var permission adminapi.Permission
+	if _, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/permissions/%d", id), nil, request, &permission); err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (c *Client) DeletePermission(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/permissions/%d", id), nil, nil, nil)
+	return err
+}
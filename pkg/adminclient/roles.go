@@ -0,0 +1,55 @@
+package adminclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// ListRoles รับรายการบทบาทหนึ่งหน้า พร้อมสิทธิ์ของแต่ละบทบาท
+func (c *Client) ListRoles(ctx context.Context, opts ListOptions) (*Page[adminapi.Role], error) {
+	return list[adminapi.Role](ctx, c, "/api/roles", opts)
+}
+
+func (c *Client) GetRole(ctx context.Context, id uint) (*adminapi.Role, error) {
+	var role adminapi.Role
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/roles/%d", id), nil, nil, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) CreateRole(ctx context.Context, request adminapi.CreateRoleRequest) (*adminapi.Role, error) {
+	var role adminapi.Role
+	if _, err := c.do(ctx, http.MethodPost, "/api/roles", nil, request, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) UpdateRole(ctx context.Context, id uint, request adminapi.UpdateRoleRequest) (*adminapi.Role, error) {
+	var role adminapi.Role
+	if _, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/roles/%d", id), nil, request, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (c *Client) DeleteRole(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/roles/%d", id), nil, nil, nil)
+	return err
+}
+
+// AddPermissionToRole เพิ่มสิทธิ์ให้บทบาท ผู้เรียกเพิ่มได้เฉพาะสิทธิ์ที่ตนเองมีอยู่
+func (c *Client) AddPermissionToRole(ctx context.Context, roleID uint, permissionID uint) error {
+	request := adminapi.AddRolePermissionRequest{PermissionID: permissionID}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/roles/%d/permissions", roleID), nil, request, nil)
+	return err
+}
+
+func (c *Client) RemovePermissionFromRole(ctx context.Context, roleID uint, permissionID uint) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/roles/%d/permissions/%d", roleID, permissionID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,72 @@
+package adminclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/yourusername/auth-api/pkg/adminapi"
+)
+
+// ListUsers รับรายการผู้ใช้หนึ่งหน้า (ผู้ดูแล tenant เห็นเฉพาะผู้ใช้ใน tenant ของตนเอง)
+func (c *Client) ListUsers(ctx context.Context, opts ListOptions) (*Page[adminapi.User], error) {
+	return list[adminapi.User](ctx, c, "/api/users", opts)
+}
+
+func (c *Client) GetUser(ctx context.Context, id uint) (*adminapi.User, error) {
+	var user adminapi.User
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/users/%d", id), nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, request adminapi.CreateUserRequest) (*adminapi.User, error) {
+	var user adminapi.User
+	if _, err := c.do(ctx, http.MethodPost, "/api/users", nil, request, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id uint, request adminapi.UpdateUserRequest) (*adminapi.User, error) {
+	var user adminapi.User
+	if _, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/users/%d", id), nil, request, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/users/%d", id), nil, nil, nil)
+	return err
+}
+
+// ListUserRoles รับรายการการกำหนดบทบาทของผู้ใช้ รวมการกำหนดที่หมดอายุหรือยังไม่เริ่ม
+func (c *Client) ListUserRoles(ctx context.Context, userID uint) ([]adminapi.RoleAssignment, error) {
+	var assignments []adminapi.RoleAssignment
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/users/%d/roles", userID), nil, nil, &assignments); err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// AssignRole กำหนดบทบาทให้ผู้ใช้ ผู้เรียกกำหนดได้เฉพาะบทบาทที่สิทธิ์ไม่เกินสิทธิ์ของตนเองหรือบทบาทที่มอบต่อได้
+func (c *Client) AssignRole(ctx context.Context, userID uint, request adminapi.AssignRoleRequest) (*adminapi.RoleAssignment, error) {
+	var resp adminapi.AssignRoleResponse
+	if _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/users/%d/roles", userID), nil, request, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Assignment, nil
+}
+
+// RevokeRole ถอนบทบาทจากผู้ใช้ พร้อมเหตุผลของการถอน (ว่างได้)
+func (c *Client) RevokeRole(ctx context.Context, userID uint, roleID uint, reason string) error {
+	var query url.Values
+	if reason != "" {
+		query = url.Values{"reason": {reason}}
+	}
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/users/%d/roles/%d", userID, roleID), query, nil, nil)
+	return err
+}
@@ -0,0 +1,47 @@
+package authclient
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware ตรวจ bearer token ของ Gin แนบ Claims ไปกับ context ของ request
+// และตั้งค่า claims, userID และ tenantID ใน gin.Context เช่นเดียวกับ middleware ของ auth-api
+func (v *Verifier) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, status, message := v.authenticate(c.Request)
+		if claims == nil {
+			c.AbortWithStatusJSON(status, gin.H{"error": message})
+			return
+		}
+
+		c.Request = c.Request.WithContext(ContextWithClaims(c.Request.Context(), claims))
+		c.Set("claims", claims)
+		c.Set("userID", claims.UserID)
+		c.Set("tenantID", claims.TenantID)
+		c.Next()
+	}
+}
+
+// GinRequirePermission ต้องใช้หลัง GinMiddleware ปฏิเสธ request ที่เจ้าของ token ไม่มีสิทธิ์ resource:action
+func (v *Verifier) GinRequirePermission(resource string, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, message := v.authorize(c.Request.Context(), resource, action); status != http.StatusOK {
+			c.AbortWithStatusJSON(status, gin.H{"error": message})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GinRequireRole ต้องใช้หลัง GinMiddleware ปฏิเสธ request ที่ token ไม่มีบทบาท role
+func (v *Verifier) GinRequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, message := requireRole(c.Request.Context(), role); status != http.StatusOK {
+			c.AbortWithStatusJSON(status, gin.H{"error": message})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+type claimsKey struct{}
+
+// ContextWithClaims แนบ Claims ไปกับ context
+func ContextWithClaims(ctx context.Context, claims *jwt.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext คืน Claims ที่ Middleware หรือ GinMiddleware แนบไว้
+func ClaimsFromContext(ctx context.Context) (*jwt.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*jwt.Claims)
+	return claims, ok
+}
+
+// Middleware ตรวจ bearer token ของ net/http แล้วแนบ Claims ไปกับ context ของ request
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, status, message := v.authenticate(r)
+		if claims == nil {
+			writeError(w, status, message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+	})
+}
+
+// RequirePermission ต้องใช้หลัง Middleware ปฏิเสธ request ที่เจ้าของ token ไม่มีสิทธิ์ resource:action
+func (v *Verifier) RequirePermission(resource string, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status, message := v.authorize(r.Context(), resource, action); status != http.StatusOK {
+				writeError(w, status, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole ต้องใช้หลัง Middleware ปฏิเสธ request ที่ token ไม่มีบทบาท role
+func (v *Verifier) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status, message := requireRole(r.Context(), role); status != http.StatusOK {
+				writeError(w, status, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticate อ่านและตรวจ token จาก header Authorization คืน Claims หรือ status และข้อความ error
+func (v *Verifier) authenticate(r *http.Request) (*jwt.Claims, int, string) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, http.StatusUnauthorized, "Authorization header is required"
+	}
+
+	// ตรวจสอบรูปแบบ "Bearer <token>"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, http.StatusUnauthorized, "Authorization header format must be Bearer <token>"
+	}
+
+	claims, err := v.Verify(r.Context(), parts[1])
+	if err != nil {
+		return nil, http.StatusUnauthorized, "Invalid or expired token"
+	}
+	return claims, http.StatusOK, ""
+}
+
+// authorize ตัดสินสิทธิ์ของ Claims ใน context
+func (v *Verifier) authorize(ctx context.Context, resource string, action string) (int, string) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, "Unauthorized"
+	}
+
+	allowed, err := v.Allowed(ctx, claims, resource, action)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check permissions"
+	}
+	if !allowed {
+		return http.StatusForbidden, "Permission denied"
+	}
+	return http.StatusOK, ""
+}
+
+// requireRole ตรวจบทบาทจาก claim roles ซึ่งมีเฉพาะ token ที่แนบสิทธิ์มา token ที่จำกัด scope ใช้ไม่ได้
+func requireRole(ctx context.Context, role string) (int, string) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return http.StatusUnauthorized, "Unauthorized"
+	}
+	if claims.IsScoped() {
+		return http.StatusForbidden, "Scoped tokens cannot use role-protected routes"
+	}
+	if !claims.HasEmbeddedPermissions() {
+		return http.StatusForbidden, "Token does not carry roles"
+	}
+	for _, name := range claims.Roles {
+		if name == role {
+			return http.StatusOK, ""
+		}
+	}
+	return http.StatusForbidden, "Role required: " + role
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
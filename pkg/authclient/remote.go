@@ -0,0 +1,59 @@
+package authclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// checkRequest และ checkResponse ตรงกับ POST /api/authz/check ของ auth-api
+type checkRequest struct {
+	Subject  checkSubject `json:"subject"`
+	Resource string       `json:"resource"`
+	Action   string       `json:"action"`
+}
+
+type checkSubject struct {
+	Type string `json:"type"`
+	ID   uint   `json:"id"`
+}
+
+type checkResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// remoteCheck ถามสิทธิ์ของผู้ใช้จาก auth-api ด้วยบัญชีบริการ (HTTP Basic)
+func (v *Verifier) remoteCheck(ctx context.Context, userID uint, resource string, action string) (bool, error) {
+	body, err := json.Marshal(checkRequest{
+		Subject:  checkSubject{Type: "user", ID: userID},
+		Resource: resource,
+		Action:   action,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.config.CheckURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(v.config.ClientID, v.config.ClientSecret)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("permission check: unexpected status %d", resp.StatusCode)
+	}
+
+	var decision checkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("decoding permission check: %w", err)
+	}
+	return decision.Allowed, nil
+}
@@ -0,0 +1,164 @@
+// Package authclient ตรวจ token ที่ auth-api ออกให้ภายในบริการอื่น โดยไม่ต้องเข้าถึงฐานข้อมูลของ auth-api
+// token ถูกตรวจแบบ offline ด้วย public key จาก JWKS ที่แคชไว้ และตรวจสิทธิ์จาก claim ที่แนบมากับ token
+// ถ้า token ไม่ได้แนบสิทธิ์มาจะถาม API ตรวจสิทธิ์ของ auth-api แทน (POST /api/authz/check)
+package authclient
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+const (
+	// DefaultRefreshInterval ระยะเวลาที่ใช้ JWKS ที่แคชไว้ก่อนโหลดใหม่
+	DefaultRefreshInterval = 10 * time.Minute
+	// minRefreshInterval ระยะห่างขั้นต่ำของการโหลด JWKS ใหม่เมื่อพบ kid ที่ไม่รู้จัก (กัน token ปลอมสั่งโหลดถี่ๆ)
+	minRefreshInterval = 10 * time.Second
+)
+
+var (
+	// ErrUnknownKey เกิดเมื่อ kid ของ token ไม่อยู่ใน JWKS แม้โหลดใหม่แล้ว
+	ErrUnknownKey = errors.New("token signing key is not in the JWKS")
+	// ErrNoPermissionSource เกิดเมื่อ token ไม่ได้แนบสิทธิ์และไม่ได้ตั้งค่า API ตรวจสิทธิ์ไว้
+	ErrNoPermissionSource = errors.New("token does not carry permissions and no check API is configured")
+)
+
+// Config การตั้งค่า Verifier
+type Config struct {
+	JWKSURL         string        // เช่น https://auth.example.com/.well-known/jwks.json
+	Issuer          string        // iss ที่ต้องตรงกัน (ว่าง = ไม่ตรวจ)
+	Audiences       []string      // aud ที่บริการนี้ยอมรับ (ว่าง = ไม่ตรวจ)
+	Leeway          time.Duration // ความคลาดเคลื่อนของนาฬิกาที่ยอมรับ
+	RefreshInterval time.Duration // 0 = DefaultRefreshInterval
+
+	// CheckURL, ClientID และ ClientSecret ใช้ถามสิทธิ์จาก auth-api ด้วยบัญชีบริการ เมื่อ token ไม่ได้แนบสิทธิ์มา
+	CheckURL     string // เช่น https://auth.example.com/api/authz/check (ว่าง = ไม่ถาม)
+	ClientID     string
+	ClientSecret string
+
+	HTTPClient *http.Client // nil = client ที่มี timeout 10 วินาที
+}
+
+// Verifier ตรวจ token และสิทธิ์ ใช้พร้อมกันจากหลาย goroutine ได้
+type Verifier struct {
+	config  Config
+	options jwt.ValidationOptions
+	client  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func NewVerifier(config Config) (*Verifier, error) {
+	if config.JWKSURL == "" {
+		return nil, errors.New("JWKSURL is required")
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Verifier{
+		config: config,
+		options: jwt.ValidationOptions{
+			StrictIssuer: config.Issuer != "",
+			Audiences:    config.Audiences,
+			Leeway:       config.Leeway,
+		},
+		client: client,
+	}, nil
+}
+
+// Verify ตรวจลายเซ็นและ claims ของ token แล้วคืน Claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*jwt.Claims, error) {
+	return jwt.VerifyRS256(token, func(keyID string) (*rsa.PublicKey, error) {
+		return v.key(ctx, keyID)
+	}, v.config.Issuer, v.options)
+}
+
+// Allowed ตรวจว่าเจ้าของ token ได้รับสิทธิ์ resource:action หรือไม่ ภายใน scope ของ token
+// ใช้สิทธิ์ที่แนบมากับ token ถ้ามี ไม่เช่นนั้นถาม API ตรวจสิทธิ์
+func (v *Verifier) Allowed(ctx context.Context, claims *jwt.Claims, resource string, action string) (bool, error) {
+	if !claims.ScopeCovers(resource, action) {
+		return false, nil
+	}
+	if claims.HasEmbeddedPermissions() {
+		return claims.HasPermission(resource, action), nil
+	}
+	if v.config.CheckURL == "" {
+		return false, ErrNoPermissionSource
+	}
+	return v.remoteCheck(ctx, claims.UserID, resource, action)
+}
+
+// key คืน public key ตาม kid โหลด JWKS ใหม่เมื่อแคชเก่าเกิน RefreshInterval หรือเมื่อพบ kid ใหม่ (เช่นหลังหมุน key)
+func (v *Verifier) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	key, ok := v.keys[keyID]
+	stale := now.Sub(v.fetchedAt) >= v.config.RefreshInterval
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(v.fetchedAt) < minRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		// ใช้ key เดิมต่อไประหว่างที่โหลด JWKS ไม่ได้
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	v.keys = keys
+	v.fetchedAt = now
+
+	if key, ok = keys[keyID]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.config.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwt.JSONWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// ข้าม key ชนิดที่ไม่รองรับ แทนที่จะทิ้งทั้งชุด
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,176 @@
+package authclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/auth-api/pkg/jwt"
+)
+
+// authServer จำลอง auth-api: เผยแพร่ JWKS และตอบ API ตรวจสิทธิ์ (อนุญาตเฉพาะ reports:read)
+type authServer struct {
+	*httptest.Server
+	jwtService *jwt.JWTService
+	jwksHits   atomic.Int32
+	checkHits  atomic.Int32
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+	server := &authServer{jwtService: newRSAService(t, "key-1")}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		server.jwksHits.Add(1)
+		json.NewEncoder(w).Encode(server.jwtService.JWKS())
+	})
+	mux.HandleFunc("/api/authz/check", func(w http.ResponseWriter, r *http.Request) {
+		server.checkHits.Add(1)
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "svc" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request checkRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		json.NewEncoder(w).Encode(checkResponse{Allowed: request.Resource == "reports" && request.Action == "read"})
+	})
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRSAService(t *testing.T, keyID string) *jwt.JWTService {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwtService := jwt.NewJWTService("unused-secret", "auth-api", time.Hour)
+	jwtService.UseRSAKey(key, keyID)
+	return jwtService
+}
+
+func newTestVerifier(t *testing.T, server *authServer) *Verifier {
+	t.Helper()
+	verifier, err := NewVerifier(Config{
+		JWKSURL:      server.URL + "/.well-known/jwks.json",
+		Issuer:       "auth-api",
+		Audiences:    []string{"reporting"},
+		CheckURL:     server.URL + "/api/authz/check",
+		ClientID:     "svc",
+		ClientSecret: "secret",
+	})
+	require.NoError(t, err)
+	return verifier
+}
+
+func serve(handler http.Handler, token string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestVerifier_NetHTTP(t *testing.T) {
+	server := newAuthServer(t)
+	verifier := newTestVerifier(t, server)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, found := ClaimsFromContext(r.Context())
+		assert.True(t, found)
+		assert.Equal(t, uint(7), claims.UserID)
+	})
+	readUsers := verifier.Middleware(verifier.RequirePermission("users", "read")(ok))
+	readReports := verifier.Middleware(verifier.RequirePermission("reports", "read")(ok))
+
+	embedded, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"),
+		jwt.WithPermissions([]string{"viewer"}, []string{"users:read"}, "v1"))
+	require.NoError(t, err)
+	plain, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+	otherAudience, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("billing"))
+	require.NoError(t, err)
+	forged, err := newRSAService(t, "key-1").GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+	hmac, err := jwt.NewJWTService("unused-secret", "auth-api", time.Hour).GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+
+	// สิทธิ์ที่แนบมากับ token ตรวจได้โดยไม่ถาม auth-api
+	assert.Equal(t, http.StatusOK, serve(readUsers, embedded))
+	assert.Equal(t, http.StatusForbidden, serve(readReports, embedded))
+	assert.Equal(t, int32(0), server.checkHits.Load())
+
+	// token ที่ไม่ได้แนบสิทธิ์ถาม API ตรวจสิทธิ์แทน
+	assert.Equal(t, http.StatusOK, serve(readReports, plain))
+	assert.Equal(t, http.StatusForbidden, serve(readUsers, plain))
+	assert.Equal(t, int32(2), server.checkHits.Load())
+
+	for _, token := range []string{"", otherAudience, forged, hmac} {
+		assert.Equal(t, http.StatusUnauthorized, serve(readUsers, token))
+	}
+
+	// JWKS ถูกโหลดครั้งเดียวแล้วใช้จากแคช
+	assert.Equal(t, int32(1), server.jwksHits.Load())
+}
+
+func TestVerifier_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := newAuthServer(t)
+	verifier := newTestVerifier(t, server)
+
+	r := gin.New()
+	r.Use(verifier.GinMiddleware())
+	r.GET("/test", verifier.GinRequireRole("admin"), func(c *gin.Context) {
+		assert.Equal(t, uint(7), c.GetUint("userID"))
+		c.Status(http.StatusOK)
+	})
+
+	admin, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"),
+		jwt.WithPermissions([]string{"admin"}, []string{"users:read"}, "v1"))
+	require.NoError(t, err)
+	scoped, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"),
+		jwt.WithPermissions([]string{"admin"}, []string{"users:read"}, "v1"), jwt.WithScope([]string{"users:read"}))
+	require.NoError(t, err)
+	withoutRoles, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, serve(r, admin))
+	assert.Equal(t, http.StatusForbidden, serve(r, scoped))
+	assert.Equal(t, http.StatusForbidden, serve(r, withoutRoles))
+}
+
+func TestVerifier_KeyRotation(t *testing.T) {
+	server := newAuthServer(t)
+	verifier := newTestVerifier(t, server)
+	ctx := context.Background()
+
+	token, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+	_, err = verifier.Verify(ctx, token)
+	require.NoError(t, err)
+
+	// auth-api เปลี่ยน key: kid ใหม่ทำให้โหลด JWKS ใหม่ แต่ไม่ถี่กว่า minRefreshInterval
+	server.jwtService = newRSAService(t, "key-2")
+	rotated, err := server.jwtService.GenerateToken(7, "user@example.com", jwt.WithAudience("reporting"))
+	require.NoError(t, err)
+	_, err = verifier.Verify(ctx, rotated)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(1), server.jwksHits.Load())
+
+	verifier.fetchedAt = time.Now().Add(-minRefreshInterval)
+	_, err = verifier.Verify(ctx, rotated)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), server.jwksHits.Load())
+}
@@ -1,10 +1,12 @@
 package database
 
 import (
+	_ "embed"
 	"fmt"
 	"log"
 
 	"github.com/yourusername/auth-api/internal/models"
+	"github.com/yourusername/auth-api/internal/policy"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -20,15 +22,20 @@ type Config struct {
 	SSLMode  string
 }
 
-// NewConnection สร้างการเชื่อมต่อฐานข้อมูลใหม่
-func NewConnection(config *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
+// DSN คืน connection string ของ PostgreSQL
+func (config *Config) DSN() string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
 	)
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+// NewConnection สร้างการเชื่อมต่อฐานข้อมูลใหม่
+func NewConnection(config *Config) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(config.DSN()), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// แปลง error ของ unique index ฯลฯ เป็น gorm.ErrDuplicatedKey เพื่อให้ handler ตรวจได้โดยไม่ผูกกับ driver
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, err
@@ -39,92 +46,195 @@ func NewConnection(config *Config) (*gorm.DB, error) {
 
 // MigrateDB สร้างหรืออัปเดตโครงสร้างฐานข้อมูล
 func MigrateDB(db *gorm.DB) error {
+	// user_roles เป็นตารางเชื่อมที่มีข้อมูลช่วงเวลาการกำหนดบทบาท
+	if err := db.SetupJoinTable(&models.User{}, "Roles", &models.UserRole{}); err != nil {
+		return err
+	}
+
+	// สิทธิ์ซ้ำที่สร้างไว้ก่อนมี unique index ต้องถูกรวมก่อน ไม่เช่นนั้นการสร้าง index จะล้มเหลว
+	if err := mergeDuplicatePermissions(db); err != nil {
+		return err
+	}
+
 	err := db.AutoMigrate(
+		&models.Organization{},
 		&models.User{},
 		&models.Role{},
 		&models.Permission{},
+		&models.Group{},
+		&models.UserRole{},
+		&models.AuditLog{},
+		&models.AccessRequest{},
+		&models.SoDRule{},
+		&models.ServiceAccount{},
+		&models.RelationTuple{},
+		&models.RelationRevision{},
+		&models.PermissionUsage{},
+		&models.AccessReviewCampaign{},
+		&models.AccessReviewItem{},
 	)
 	if err != nil {
 		return err
 	}
 
+	if err := createLookupIndexes(db); err != nil {
+		return err
+	}
+
+	if err := dropLegacyForeignKeys(db); err != nil {
+		return err
+	}
+
+	// ชื่อบทบาทไม่ต้องไม่ซ้ำทั้งระบบอีกต่อไป แต่ไม่ซ้ำภายใน tenant (idx_roles_org_name)
+	if db.Migrator().HasIndex(&models.Role{}, "idx_roles_name") {
+		if err := db.Migrator().DropIndex(&models.Role{}, "idx_roles_name"); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// SeedDefaultData สร้างข้อมูลเริ่มต้นในฐานข้อมูล
-func SeedDefaultData(db *gorm.DB) error {
-	// สร้าง permissions
-	permissions := []models.Permission{
-		{Resource: "users", Action: "read", Description: "อ่านข้อมูลผู้ใช้"},
-		{Resource: "users", Action: "write", Description: "แก้ไขข้อมูลผู้ใช้"},
-		{Resource: "roles", Action: "read", Description: "อ่านข้อมูลบทบาท"},
-		{Resource: "roles", Action: "write", Description: "แก้ไขข้อมูลบทบาท"},
-		{Resource: "permissions", Action: "read", Description: "อ่านข้อมูลสิทธิ์"},
-		{Resource: "permissions", Action: "write", Description: "แก้ไขข้อมูลสิทธิ์"},
-	}
-
-	for _, perm := range permissions {
-		var existingPerm models.Permission
-		result := db.Where("resource = ? AND action = ?", perm.Resource, perm.Action).First(&existingPerm)
-		if result.RowsAffected == 0 {
-			if err := db.Create(&perm).Error; err != nil {
-				log.Printf("Failed to create permission %s:%s: %v", perm.Resource, perm.Action, err)
-			}
+// createLookupIndexes สร้าง index ของตารางเชื่อมที่ gorm สร้างให้เพียง primary key
+// group_members มี primary key (group_id, user_id) จึงต้องมี index ของ user_id สำหรับการหากลุ่มของผู้ใช้ขณะตรวจสิทธิ์
+// role_permissions มี primary key (role_id, permission_id) จึงต้องมี index ของ permission_id สำหรับ query ที่เริ่มจากสิทธิ์
+// (การตรวจสิทธิ์แบบ EXISTS เมื่อปิดแคช การหาผู้ถือสิทธิ์ และการ invalidate แคชเมื่อแก้ไขสิทธิ์)
+func createLookupIndexes(db *gorm.DB) error {
+	statements := []string{
+		"CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members (user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions (permission_id)",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// dropLegacyForeignKeys ลบ foreign key ที่ AutoMigrate เคยสร้างจาก association ที่ถูกเอาออกแล้ว
+// ข้อมูลเชิงประวัติเหล่านี้ต้องไม่ขวางการลบผู้ใช้หรือบทบาท (AutoMigrate ไม่ลบ constraint ให้เอง)
+func dropLegacyForeignKeys(db *gorm.DB) error {
+	statements := []string{
+		"ALTER TABLE IF EXISTS access_review_items DROP CONSTRAINT IF EXISTS fk_access_review_items_user",
+		"ALTER TABLE IF EXISTS access_review_items DROP CONSTRAINT IF EXISTS fk_access_review_items_role",
+		"ALTER TABLE IF EXISTS access_requests DROP CONSTRAINT IF EXISTS fk_access_requests_role",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeDuplicatePermissions รวมสิทธิ์ที่มี resource และ action ซ้ำกันให้เหลือรายการที่ ID ต่ำสุด
+// บทบาทที่ถือสิทธิ์ซ้ำจะถือรายการที่เหลือแทน ทำเพียงครั้งเดียวก่อน idx_permissions_resource_action ถูกสร้าง
+func mergeDuplicatePermissions(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Permission{}) ||
+		migrator.HasIndex(&models.Permission{}, "idx_permissions_resource_action") {
+		return nil
+	}
 
-	// สร้าง roles พื้นฐาน
-	adminRole := models.Role{Name: "admin", Description: "ผู้ดูแลระบบ"}
-	supervisorRole := models.Role{Name: "supervisor", Description: "ผู้ควบคุม"}
-	editorRole := models.Role{Name: "editor", Description: "ผู้แก้ไข"}
-	viewerRole := models.Role{Name: "viewer", Description: "ผู้ดู"}
+	return db.Transaction(func(tx *gorm.DB) error {
+		duplicates := `SELECT p.id, keep.id AS keep_id FROM permissions p
+			JOIN (SELECT resource, action, MIN(id) AS id FROM permissions GROUP BY resource, action) keep
+			ON keep.resource = p.resource AND keep.action = p.action AND keep.id <> p.id`
 
-	// ตรวจสอบและสร้าง role ถ้ายังไม่มี
-	var existingRole models.Role
+		if migrator.HasTable("role_permissions") {
+			if err := tx.Exec(`INSERT INTO role_permissions (role_id, permission_id)
+				SELECT rp.role_id, d.keep_id FROM role_permissions rp JOIN (` + duplicates + `) d ON d.id = rp.permission_id
+				ON CONFLICT DO NOTHING`).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`DELETE FROM role_permissions WHERE permission_id IN (SELECT id FROM (` + duplicates + `) d)`).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`DELETE FROM permissions WHERE id IN (SELECT id FROM (` + duplicates + `) d)`).Error
+	})
+}
 
-	if db.Where("name = ?", adminRole.Name).First(&existingRole).RowsAffected == 0 {
-		if err := db.Create(&adminRole).Error; err != nil {
-			log.Printf("Failed to create admin role: %v", err)
-		} else {
-			// ให้สิทธิ์ทั้งหมดกับ admin
-			var allPermissions []models.Permission
-			db.Find(&allPermissions)
-			db.Model(&adminRole).Association("Permissions").Append(allPermissions)
+// defaultPolicyFile สิทธิ์และบทบาทเริ่มต้น
+//
+//go:embed default_policy.yaml
+var defaultPolicyFile []byte
+
+// grantNewPermissionsToAdmin ให้สิทธิ์ที่ seed ครั้งนี้สร้างขึ้น (สิทธิ์ที่เพิ่มในเวอร์ชันนี้) แก่บทบาท admin ที่มีอยู่แล้ว
+// สิทธิ์เดิมไม่ถูกให้ซ้ำ ผู้ดูแลที่ถอนสิทธิ์ใดออกจาก admin จึงไม่ได้สิทธิ์นั้นคืนเมื่อเริ่มระบบใหม่
+// บทบาท admin ที่ seed ครั้งนี้สร้างขึ้นได้ทุกสิทธิ์จาก "*" อยู่แล้ว
+func grantNewPermissionsToAdmin(db *gorm.DB, defaultPolicy *policy.Policy, plan *policy.Plan) error {
+	created := make(map[string]bool)
+	for _, change := range plan.Changes {
+		if change.Action != policy.ChangeCreate {
+			continue
+		}
+		switch {
+		case change.Kind == "role" && change.Name == "admin":
+			return nil
+		case change.Kind == "permission":
+			created[change.Name] = true
 		}
 	}
+	if len(created) == 0 {
+		return nil
+	}
 
-	if db.Where("name = ?", supervisorRole.Name).First(&existingRole).RowsAffected == 0 {
-		if err := db.Create(&supervisorRole).Error; err != nil {
-			log.Printf("Failed to create supervisor role: %v", err)
-		} else {
-			// ให้สิทธิ์อ่านทั้งหมดและแก้ไขบางส่วนกับ supervisor
-			var readPermissions []models.Permission
-			db.Where("action = ?", "read").Find(&readPermissions)
-			db.Model(&supervisorRole).Association("Permissions").Append(readPermissions)
-		}
+	var adminRole models.Role
+	result := db.Where("name = ? AND organization_id IS NULL", "admin").Limit(1).Find(&adminRole)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
 	}
 
-	if db.Where("name = ?", editorRole.Name).First(&existingRole).RowsAffected == 0 {
-		if err := db.Create(&editorRole).Error; err != nil {
-			log.Printf("Failed to create editor role: %v", err)
-		} else {
-			// ให้สิทธิ์อ่านและแก้ไขบางส่วนกับ editor
-			var userPermissions []models.Permission
-			db.Where("resource = ? AND action = ?", "users", "read").Or("resource = ? AND action = ?", "users", "write").Find(&userPermissions)
-			db.Model(&editorRole).Association("Permissions").Append(userPermissions)
+	var permissions []models.Permission
+	for _, perm := range defaultPolicy.Permissions {
+		if !created[perm.Key()] {
+			continue
 		}
+		var permission models.Permission
+		if err := db.Where("resource = ? AND action = ?", perm.Resource, perm.Action).First(&permission).Error; err != nil {
+			return err
+		}
+		permissions = append(permissions, permission)
+	}
+	return db.Model(&adminRole).Association("Permissions").Append(permissions)
+}
+
+// SeedDefaultData สร้างข้อมูลเริ่มต้นในฐานข้อมูล
+func SeedDefaultData(db *gorm.DB) error {
+	// สร้างสิทธิ์และบทบาทจาก policy เริ่มต้นเฉพาะที่ยังไม่มี ของเดิมที่แก้ไขผ่าน API จะไม่ถูกเขียนทับ
+	defaultPolicy, err := policy.Parse(defaultPolicyFile)
+	if err != nil {
+		return err
+	}
+	plan, err := policy.Apply(db, defaultPolicy, policy.ApplyOptions{CreateOnly: true})
+	if err != nil {
+		return err
+	}
+	if err := grantNewPermissionsToAdmin(db, defaultPolicy, plan); err != nil {
+		return err
 	}
 
-	if db.Where("name = ?", viewerRole.Name).First(&existingRole).RowsAffected == 0 {
-		if err := db.Create(&viewerRole).Error; err != nil {
-			log.Printf("Failed to create viewer role: %v", err)
-		} else {
-			// ให้สิทธิ์อ่านอย่างเดียวกับ viewer
-			var readPermissions []models.Permission
-			db.Where("action = ?", "read").Find(&readPermissions)
-			db.Model(&viewerRole).Association("Permissions").Append(readPermissions)
+	// สิทธิ์และบทบาทเริ่มต้นเป็น system (รวมถึงข้อมูลที่ seed ไว้ก่อนมี flag system)
+	for _, perm := range defaultPolicy.Permissions {
+		err := db.Model(&models.Permission{}).
+			Where("resource = ? AND action = ? AND system = ?", perm.Resource, perm.Action, false).
+			Update("system", true).Error
+		if err != nil {
+			return err
 		}
 	}
+	roleNames := make([]string, 0, len(defaultPolicy.Roles))
+	for _, role := range defaultPolicy.Roles {
+		roleNames = append(roleNames, role.Name)
+	}
+	err = db.Model(&models.Role{}).
+		Where("name IN ? AND organization_id IS NULL", roleNames).
+		Update("system", true).Error
+	if err != nil {
+		return err
+	}
 
 	// สร้าง admin user เริ่มต้น
 	adminUser := models.User{
@@ -132,6 +242,7 @@ func SeedDefaultData(db *gorm.DB) error {
 		Email:    "admin@example.com",
 		Password: "adminpassword", // จะถูกเข้ารหัสโดย BeforeCreate hook
 		FullName: "System Administrator",
+		System:   true,
 	}
 
 	var existingUser models.User
@@ -141,9 +252,11 @@ func SeedDefaultData(db *gorm.DB) error {
 		} else {
 			// กำหนด role admin ให้กับ user admin
 			var adminRoleModel models.Role
-			db.Where("name = ?", "admin").First(&adminRoleModel)
+			db.Where("name = ? AND organization_id IS NULL", "admin").First(&adminRoleModel)
 			db.Model(&adminUser).Association("Roles").Append(&adminRoleModel)
 		}
+	} else if !existingUser.System {
+		db.Model(&existingUser).Update("system", true)
 	}
 
 	return nil
@@ -0,0 +1,100 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JSONWebKey public key หนึ่งรายการใน JWKS (RFC 7517) รองรับเฉพาะ RSA
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JSONWebKeySet ชุด public key ที่ใช้ตรวจ token (เผยแพร่ที่ /.well-known/jwks.json)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// ParseRSAPrivateKey อ่าน RSA private key จาก PEM (PKCS#1 หรือ PKCS#8)
+func ParseRSAPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	return jwt.ParseRSAPrivateKeyFromPEM(pemData)
+}
+
+// UseRSAKey ให้ JWTService เซ็น token ด้วย RS256 พร้อม header kid และตรวจเฉพาะ token RS256
+// keyID ว่างจะใช้ thumbprint ของ public key (RFC 7638)
+func (j *JWTService) UseRSAKey(key *rsa.PrivateKey, keyID string) {
+	if keyID == "" {
+		keyID = Thumbprint(&key.PublicKey)
+	}
+	j.rsaKey = key
+	j.keyID = keyID
+}
+
+// JWKS คืน public key ของ service นี้ (ว่างเมื่อเซ็นด้วย secret key ซึ่งเผยแพร่ไม่ได้)
+func (j *JWTService) JWKS() JSONWebKeySet {
+	if j.rsaKey == nil {
+		return JSONWebKeySet{Keys: []JSONWebKey{}}
+	}
+	return JSONWebKeySet{Keys: []JSONWebKey{NewJSONWebKey(&j.rsaKey.PublicKey, j.keyID)}}
+}
+
+// NewJSONWebKey แปลง RSA public key เป็น JSONWebKey สำหรับ RS256
+func NewJSONWebKey(key *rsa.PublicKey, keyID string) JSONWebKey {
+	n, e := rsaComponents(key)
+	return JSONWebKey{Kty: "RSA", Kid: keyID, Use: "sig", Alg: "RS256", N: n, E: e}
+}
+
+// PublicKey แปลง JSONWebKey กลับเป็น RSA public key
+func (k JSONWebKey) PublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid RSA public key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// Thumbprint คำนวณ JWK thumbprint (RFC 7638) ของ RSA public key ใช้เป็น kid
+func Thumbprint(key *rsa.PublicKey) string {
+	n, e := rsaComponents(key)
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func rsaComponents(key *rsa.PublicKey) (string, string) {
+	return base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}
+
+// VerifyRS256 ตรวจ token ที่เซ็นด้วย RS256 โดยหา public key จาก kid ใน header แล้วตรวจ claims ตาม options
+// ใช้โดยบริการที่ตรวจ token ด้วย JWKS โดยไม่มี secret ของ service นี้
+func VerifyRS256(tokenString string, keyByID func(keyID string) (*rsa.PublicKey, error), issuer string, options ValidationOptions) (*Claims, error) {
+	return parseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		keyID, _ := token.Header["kid"].(string)
+		return keyByID(keyID)
+	}, issuer, options)
+}
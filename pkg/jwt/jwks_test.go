@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTService_RSAKeyAndJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwtService := NewJWTService("test-secret-key", "test-issuer", time.Hour)
+	assert.Empty(t, jwtService.JWKS().Keys)
+	jwtService.UseRSAKey(key, "")
+
+	token, err := jwtService.GenerateToken(1, "test@example.com")
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	// public key ใน JWKS ตรวจ token ได้โดยไม่ต้องมี private key
+	jwks := jwtService.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, Thumbprint(&key.PublicKey), jwks.Keys[0].Kid)
+	publicKey, err := jwks.Keys[0].PublicKey()
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(publicKey))
+
+	claims, err = VerifyRS256(token, func(keyID string) (*rsa.PublicKey, error) {
+		assert.Equal(t, jwks.Keys[0].Kid, keyID)
+		return publicKey, nil
+	}, "test-issuer", ValidationOptions{StrictIssuer: true})
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", claims.Email)
+
+	// เมื่อใช้ RSA key แล้ว token HS256 ที่เซ็นด้วย secret เดิมใช้ไม่ได้
+	hmacToken, err := NewJWTService("test-secret-key", "test-issuer", time.Hour).GenerateToken(1, "test@example.com")
+	require.NoError(t, err)
+	_, err = jwtService.ValidateToken(hmacToken)
+	assert.Error(t, err)
+}
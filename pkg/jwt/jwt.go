@@ -1,8 +1,10 @@
 package jwt
 
 import (
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -13,15 +15,123 @@ type JWTService struct {
 	secretKey     string
 	issuer        string
 	tokenDuration time.Duration
+	validation    ValidationOptions
+	// rsaKey และ keyID ใช้เซ็น token ด้วย RS256 แทน secretKey เพื่อให้บริการอื่นตรวจได้ด้วย public key (JWKS)
+	rsaKey *rsa.PrivateKey
+	keyID  string
+}
+
+// ValidationOptions กำหนดความเข้มงวดของ ValidateToken นอกเหนือจากลายเซ็นและวันหมดอายุ
+type ValidationOptions struct {
+	StrictIssuer     bool          // iss ต้องตรงกับ issuer ของ service นี้
+	Audiences        []string      // token ต้องมี aud อย่างน้อยหนึ่งค่าในรายการ (ว่าง = ไม่ตรวจ aud)
+	RequireNotBefore bool          // token ต้องมี nbf (token ที่ไม่มี nbf ถือว่าใช้ได้ทันทีถ้าไม่ได้ตั้งค่านี้)
+	Leeway           time.Duration // ยอมให้นาฬิกาของผู้ออกและผู้ตรวจคลาดกันได้ ใช้กับ exp, nbf และ iat
 }
 
 // Claims เก็บข้อมูลที่จะแนบไปกับ JWT token
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID   uint   `json:"user_id"`
+	Email    string `json:"email"`
+	TenantID *uint  `json:"tenant_id,omitempty"` // nil = ผู้ใช้ระดับ global
+	// Roles, Permissions และ PermissionsVersion มีเฉพาะ token ที่ออกในโหมดแนบสิทธิ์ (stateless authorization)
+	Roles              []string `json:"roles,omitempty"`
+	Permissions        []string `json:"perms,omitempty"` // ในรูปแบบ resource:action
+	PermissionsVersion string   `json:"pv,omitempty"`    // ค่าประจำชุดสิทธิ์ของผู้ใช้ ณ ตอนออก token ใช้ตรวจว่า token ล้าสมัยหรือไม่
+	// Scope สิทธิ์ (resource:action) ที่ token นี้ใช้ได้ คั่นด้วยช่องว่าง ว่าง = ใช้ได้ทุกสิทธิ์ที่ผู้ใช้มี
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Scopes คืนรายการ scope ของ token (nil = ไม่จำกัด)
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// IsScoped ตรวจสอบว่า token ถูกจำกัด scope หรือไม่
+func (c *Claims) IsScoped() bool {
+	return len(c.Scopes()) > 0
+}
+
+// ScopeCovers ตรวจสอบว่า scope ของ token ครอบคลุมสิทธิ์ resource:action หรือไม่ (token ที่ไม่จำกัด scope ครอบคลุมทุกสิทธิ์)
+func (c *Claims) ScopeCovers(resource string, action string) bool {
+	scopes := c.Scopes()
+	if len(scopes) == 0 {
+		return true
+	}
+	key := resource + ":" + action
+	for _, scope := range scopes {
+		if scope == key {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAudience ตรวจสอบว่า token ออกให้ audience ใดใน audiences หรือไม่
+func (c *Claims) HasAudience(audiences ...string) bool {
+	for _, audience := range audiences {
+		if c.VerifyAudience(audience, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEmbeddedPermissions ตรวจสอบว่า token แนบสิทธิ์มาด้วยหรือไม่
+func (c *Claims) HasEmbeddedPermissions() bool {
+	return c.PermissionsVersion != ""
+}
+
+// HasPermission ตรวจสอบสิทธิ์จากรายการที่แนบมากับ token โดยไม่ต้องเรียกฐานข้อมูล (ต้องอยู่ใน scope ของ token ด้วย)
+func (c *Claims) HasPermission(resource string, action string) bool {
+	if !c.ScopeCovers(resource, action) {
+		return false
+	}
+	key := resource + ":" + action
+	for _, perm := range c.Permissions {
+		if perm == key {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenOption ใช้กำหนดข้อมูลเพิ่มเติมใน Claims ตอนสร้าง token
+type TokenOption func(*Claims)
+
+// WithTenantID แนบ tenant ของผู้ใช้ไปกับ token
+func WithTenantID(tenantID *uint) TokenOption {
+	return func(c *Claims) {
+		c.TenantID = tenantID
+	}
+}
+
+// WithPermissions แนบบทบาท สิทธิ์ (resource:action) และเวอร์ชันของชุดสิทธิ์ไปกับ token
+func WithPermissions(roles []string, permissions []string, version string) TokenOption {
+	return func(c *Claims) {
+		c.Roles = roles
+		c.Permissions = permissions
+		c.PermissionsVersion = version
+	}
+}
+
+// WithScope จำกัดสิทธิ์ที่ token ใช้ได้ (scope คั่นด้วยช่องว่าง)
+func WithScope(scopes []string) TokenOption {
+	return func(c *Claims) {
+		c.Scope = strings.Join(scopes, " ")
+	}
+}
+
+// WithAudience กำหนด aud ของ token (บริการที่ token นี้ตั้งใจให้ใช้)
+func WithAudience(audiences ...string) TokenOption {
+	return func(c *Claims) {
+		if len(audiences) > 0 {
+			c.Audience = audiences
+		}
+	}
+}
+
 // NewJWTService สร้าง JWTService ใหม่
 func NewJWTService(secretKey string, issuer string, tokenDuration time.Duration) *JWTService {
 	return &JWTService{
@@ -31,17 +141,33 @@ func NewJWTService(secretKey string, issuer string, tokenDuration time.Duration)
 	}
 }
 
+// SetValidation กำหนดการตรวจ iss, aud, nbf และความคลาดเคลื่อนของเวลาใน ValidateToken
+func (j *JWTService) SetValidation(options ValidationOptions) {
+	j.validation = options
+}
+
 // GenerateToken สร้าง JWT token จากข้อมูลผู้ใช้
-func (j *JWTService) GenerateToken(userID uint, email string) (string, error) {
+func (j *JWTService) GenerateToken(userID uint, email string, opts ...TokenOption) (string, error) {
+	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.tokenDuration)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.tokenDuration)),
 			Issuer:    j.issuer,
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+	for _, opt := range opts {
+		opt(claims)
+	}
+
+	if j.rsaKey != nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = j.keyID
+		return token.SignedString(j.rsaKey)
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signedToken, err := token.SignedString([]byte(j.secretKey))
@@ -53,14 +179,27 @@ func (j *JWTService) GenerateToken(userID uint, email string) (string, error) {
 }
 
 // ValidateToken ตรวจสอบความถูกต้องของ token และคืนค่า Claims
+// claim ด้านเวลา issuer และ audience ตรวจตาม ValidationOptions แทนการตรวจค่าเริ่มต้นของไลบรารี
+// เมื่อใช้ RSA key (UseRSAKey) จะรับเฉพาะ token RS256 ไม่เช่นนั้นรับเฉพาะ HMAC ที่เซ็นด้วย secret key
 func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+	return parseToken(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if j.rsaKey != nil {
+			if token.Method != jwt.SigningMethodRS256 {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return &j.rsaKey.PublicKey, nil
+		}
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(j.secretKey), nil
-	})
+	}, j.issuer, j.validation)
+}
 
+// parseToken ตรวจลายเซ็นด้วย key จาก keyFunc แล้วตรวจ claims ตาม ValidationOptions
+func parseToken(tokenString string, keyFunc jwt.Keyfunc, issuer string, options ValidationOptions) (*Claims, error) {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -70,5 +209,29 @@ func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if err := options.Validate(claims, issuer, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
+
+// Validate ตรวจ exp, iat, nbf, iss (เทียบกับ issuer) และ aud ของ claims ณ เวลา now
+func (options ValidationOptions) Validate(claims *Claims, issuer string, now time.Time) error {
+	if !claims.VerifyExpiresAt(now.Add(-options.Leeway), false) {
+		return jwt.ErrTokenExpired
+	}
+	if !claims.VerifyIssuedAt(now.Add(options.Leeway), false) {
+		return jwt.ErrTokenUsedBeforeIssued
+	}
+	if !claims.VerifyNotBefore(now.Add(options.Leeway), options.RequireNotBefore) {
+		return jwt.ErrTokenNotValidYet
+	}
+	if options.StrictIssuer && !claims.VerifyIssuer(issuer, true) {
+		return jwt.ErrTokenInvalidIssuer
+	}
+	if len(options.Audiences) > 0 && !claims.HasAudience(options.Audiences...) {
+		return jwt.ErrTokenInvalidAudience
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -98,3 +99,137 @@ func TestJWTService_ValidateToken_DifferentSecret(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, claims)
 }
+
+func TestJWTService_GenerateToken_WithTenantID(t *testing.T) {
+	jwtService := NewJWTService("test-secret-key", "test-issuer", 1*time.Hour)
+
+	// token ของผู้ใช้ที่สังกัด tenant ต้องมี tenant_id
+	tenantID := uint(7)
+	token, err := jwtService.GenerateToken(1, "test@example.com", WithTenantID(&tenantID))
+	assert.NoError(t, err)
+
+	claims, err := jwtService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.NotNil(t, claims.TenantID)
+	assert.Equal(t, tenantID, *claims.TenantID)
+
+	// token ของผู้ใช้ระดับ global ต้องไม่มี tenant_id
+	token, err = jwtService.GenerateToken(1, "test@example.com")
+	assert.NoError(t, err)
+
+	claims, err = jwtService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.Nil(t, claims.TenantID)
+}
+
+func TestJWTService_WithPermissions(t *testing.T) {
+	jwtService := NewJWTService("test-secret-key", "test-issuer", time.Hour)
+
+	token, err := jwtService.GenerateToken(1, "test@example.com",
+		WithPermissions([]string{"editor"}, []string{"users:read", "users:write"}, "abc123"))
+	assert.NoError(t, err)
+
+	claims, err := jwtService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.True(t, claims.HasEmbeddedPermissions())
+	assert.Equal(t, []string{"editor"}, claims.Roles)
+	assert.Equal(t, "abc123", claims.PermissionsVersion)
+	assert.True(t, claims.HasPermission("users", "write"))
+	assert.False(t, claims.HasPermission("users", "delete"))
+
+	// token ปกติไม่แนบสิทธิ์
+	token, err = jwtService.GenerateToken(1, "test@example.com")
+	assert.NoError(t, err)
+	claims, err = jwtService.ValidateToken(token)
+	assert.NoError(t, err)
+	assert.False(t, claims.HasEmbeddedPermissions())
+}
+
+func TestClaims_ScopeCovers(t *testing.T) {
+	unscoped := &Claims{}
+	assert.False(t, unscoped.IsScoped())
+	assert.True(t, unscoped.ScopeCovers("users", "write"))
+
+	scoped := &Claims{
+		Scope:       "users:read reports:read",
+		Permissions: []string{"users:read", "users:write"},
+	}
+	assert.True(t, scoped.IsScoped())
+	assert.True(t, scoped.ScopeCovers("reports", "read"))
+	assert.False(t, scoped.ScopeCovers("users", "write"))
+
+	// สิทธิ์ที่แนบมาแต่อยู่นอก scope ใช้ไม่ได้
+	assert.True(t, scoped.HasPermission("users", "read"))
+	assert.False(t, scoped.HasPermission("users", "write"))
+}
+
+func TestJWTService_ValidationOptions(t *testing.T) {
+	secretKey := "shared-secret-key"
+	reporting := NewJWTService(secretKey, "reporting", time.Hour)
+	authAPI := NewJWTService(secretKey, "auth-api", time.Hour)
+
+	// token ที่อีกแอปออกด้วย key เดียวกันใช้ได้จนกว่าจะเปิด StrictIssuer
+	foreign, err := reporting.GenerateToken(1, "test@example.com")
+	assert.NoError(t, err)
+	_, err = authAPI.ValidateToken(foreign)
+	assert.NoError(t, err)
+	authAPI.SetValidation(ValidationOptions{StrictIssuer: true})
+	_, err = authAPI.ValidateToken(foreign)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// token ต้องออกให้ audience ที่ยอมรับ
+	authAPI.SetValidation(ValidationOptions{StrictIssuer: true, Audiences: []string{"auth-api"}})
+	forReporting, err := authAPI.GenerateToken(1, "test@example.com", WithAudience("reporting"))
+	assert.NoError(t, err)
+	_, err = authAPI.ValidateToken(forReporting)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	forBoth, err := authAPI.GenerateToken(1, "test@example.com", WithAudience("reporting", "auth-api"))
+	assert.NoError(t, err)
+	claims, err := authAPI.ValidateToken(forBoth)
+	assert.NoError(t, err)
+	assert.True(t, claims.HasAudience("auth-api"))
+}
+
+func TestJWTService_ValidationOptions_NotBeforeAndLeeway(t *testing.T) {
+	secretKey := "test-secret-key"
+	jwtService := NewJWTService(secretKey, "test-issuer", time.Hour)
+	sign := func(claims *Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+		assert.NoError(t, err)
+		return token
+	}
+	now := time.Now()
+
+	// token ที่ไม่มี nbf ถูกปฏิเสธเมื่อบังคับ nbf
+	withoutNotBefore := sign(&Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "test-issuer",
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}})
+	_, err := jwtService.ValidateToken(withoutNotBefore)
+	assert.NoError(t, err)
+	jwtService.SetValidation(ValidationOptions{RequireNotBefore: true})
+	_, err = jwtService.ValidateToken(withoutNotBefore)
+	assert.ErrorIs(t, err, jwt.ErrTokenNotValidYet)
+
+	// นาฬิกาของผู้ออกเร็วกว่า 10 วินาที และ token หมดอายุไปแล้ว 10 วินาที ใช้ได้เมื่อยอมให้คลาดได้ 30 วินาที
+	skewed := sign(&Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "test-issuer",
+		IssuedAt:  jwt.NewNumericDate(now.Add(10 * time.Second)),
+		NotBefore: jwt.NewNumericDate(now.Add(10 * time.Second)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}})
+	expired := sign(&Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "test-issuer",
+		NotBefore: jwt.NewNumericDate(now.Add(-time.Hour)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(-10 * time.Second)),
+	}})
+	for _, token := range []string{skewed, expired} {
+		jwtService.SetValidation(ValidationOptions{RequireNotBefore: true})
+		_, err = jwtService.ValidateToken(token)
+		assert.Error(t, err)
+
+		jwtService.SetValidation(ValidationOptions{RequireNotBefore: true, Leeway: 30 * time.Second})
+		_, err = jwtService.ValidateToken(token)
+		assert.NoError(t, err)
+	}
+}